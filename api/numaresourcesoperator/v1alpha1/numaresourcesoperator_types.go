@@ -32,23 +32,118 @@ type NUMAResourcesOperatorSpec struct {
 	// +optional
 	// +kubebuilder:default=Normal
 	LogLevel operatorv1.LogLevel `json:"logLevel,omitempty"`
+	// RemoveNodeResourceTopologyAPI makes the deletion of the object remove the NodeResourceTopology CRD too,
+	// and with it all the NodeResourceTopology objects. By default the CRD is kept, because other components
+	// may consume it.
+	// +optional
+	RemoveNodeResourceTopologyAPI bool `json:"removeNodeResourceTopologyAPI,omitempty"`
+	// Paused stops the reconciliation of the deployed objects, e.g. during a cluster maintenance.
+	// While paused, the operator only refreshes the status and reports the objects differing from the desired state.
+	// The deletion of the object goes ahead while paused, and cleans up the deployed objects as usual.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// DisableExporterMetrics stops the operator from exposing the resource topology exporter metrics.
+	// By default each node group gets a metrics Service, served over TLS on OpenShift, and a ServiceMonitor
+	// if the prometheus operator API is available.
+	// +optional
+	DisableExporterMetrics bool `json:"disableExporterMetrics,omitempty"`
 }
 
 // NodeGroup defines group of nodes that will run resource topology exporter daemon set
 // You can choose the group of node by MachineConfigPoolSelector or by NodeSelector
 type NodeGroup struct {
+	// Name identifies the node group. It is required when NodeSelector is used,
+	// because the generated objects are named after it.
+	// +optional
+	Name string `json:"name,omitempty"`
 	// MachineConfigPoolSelector defines label selector for the machine config pool
 	MachineConfigPoolSelector *metav1.LabelSelector `json:"machineConfigPoolSelector,omitempty"`
+	// NodeSelector defines label selector for the nodes, for platforms without machine config pools
+	// (e.g. vanilla kubernetes). It is mutually exclusive with MachineConfigPoolSelector.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Config defines the RTE behaviour for this node group, overriding the global settings
+	// +optional
+	Config *NodeGroupConfig `json:"config,omitempty"`
+}
+
+// NodeGroupConfig exposes the resource topology exporter settings per node group.
+// Unset fields fall back to the global values in NUMAResourcesOperatorSpec or to the built-in defaults.
+type NodeGroupConfig struct {
+	// ExporterImage overrides the exporter image used for this node group
+	// +optional
+	ExporterImage string `json:"imageSpec,omitempty"`
+	// LogLevel overrides the exporter log level for this node group.
+	// Valid values are: "Normal", "Debug", "Trace", "TraceAll".
+	// +optional
+	LogLevel operatorv1.LogLevel `json:"logLevel,omitempty"`
+	// InfoRefreshPeriod sets the interval between two podresources API polls
+	// +optional
+	InfoRefreshPeriod *metav1.Duration `json:"infoRefreshPeriod,omitempty"`
+	// PodReadiness toggles the custom readiness conditions reported by the exporter pods
+	// +optional
+	PodReadiness *bool `json:"podReadiness,omitempty"`
+	// NotifyFilePath sets the path of the file the exporter watches to trigger an update
+	// +optional
+	NotifyFilePath string `json:"notifyFilePath,omitempty"`
 }
 
 // NUMAResourcesOperatorStatus defines the observed state of NUMAResourcesOperator
 type NUMAResourcesOperatorStatus struct {
 	DaemonSets         []NamespacedName    `json:"daemonsets,omitempty"`
 	MachineConfigPools []MachineConfigPool `json:"machineconfigpools,omitempty"`
+	// NodeGroups reports the state of the objects deployed for each node group
+	// +optional
+	NodeGroups []NodeGroupStatus `json:"nodeGroups,omitempty"`
 	// Conditions show the current state of the NUMAResourcesOperator Operator
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// MachineConfigState describes the rollout of the generated machine config on the machine config pool
+type MachineConfigState string
+
+const (
+	// MachineConfigStatePending means the machine config pool did not render the machine config yet
+	MachineConfigStatePending MachineConfigState = "Pending"
+	// MachineConfigStateUpdating means the machine config pool is applying the machine config to the nodes
+	MachineConfigStateUpdating MachineConfigState = "Updating"
+	// MachineConfigStateUpdated means all the nodes of the machine config pool run with the machine config
+	MachineConfigStateUpdated MachineConfigState = "Updated"
+	// MachineConfigStateDegraded means the machine config pool failed to apply the machine config
+	MachineConfigStateDegraded MachineConfigState = "Degraded"
+)
+
+// NodeGroupStatus defines the observed state of the objects deployed for a node group
+type NodeGroupStatus struct {
+	// Name is the name the generated objects are named after:
+	// the selected machine config pool name, or the node group name when using a node selector
+	Name string `json:"name"`
+	// MachineConfigPool is the name of the machine config pool selected by the node group
+	// +optional
+	MachineConfigPool string `json:"machineConfigPool,omitempty"`
+	// MachineConfig is the name of the machine config generated for the machine config pool
+	// +optional
+	MachineConfig string `json:"machineConfig,omitempty"`
+	// MachineConfigState is the rollout state of the machine config on the machine config pool
+	// +optional
+	MachineConfigState MachineConfigState `json:"machineConfigState,omitempty"`
+	// DaemonSet is the resource topology exporter daemon set running on the node group
+	// +optional
+	DaemonSet NamespacedName `json:"daemonSet,omitempty"`
+	// DesiredNumberScheduled is the number of nodes that should be running the daemon set pod
+	// +optional
+	DesiredNumberScheduled int32 `json:"desiredNumberScheduled,omitempty"`
+	// NumberReady is the number of nodes running the daemon set pod and having it ready
+	// +optional
+	NumberReady int32 `json:"numberReady,omitempty"`
+	// UpdatedNumberScheduled is the number of nodes running the updated daemon set pod
+	// +optional
+	UpdatedNumberScheduled int32 `json:"updatedNumberScheduled,omitempty"`
+	// ObservedGeneration is the most recent daemon set generation observed by the daemon set controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
 // MachineConfigPool defines the observed state of each MachineConfigPool selected by node groups
 type MachineConfigPool struct {
 	// Name the name of the machine config pool
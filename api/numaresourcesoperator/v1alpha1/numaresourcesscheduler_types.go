@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
@@ -31,12 +32,108 @@ type NUMAResourcesSchedulerSpec struct {
 	// +optional
 	// +kubebuilder:default=Normal
 	LogLevel operatorv1.LogLevel `json:"logLevel,omitempty"`
+	// Replicas is the number of scheduler replicas. With more than one replica, the replicas
+	// elect a leader and are spread across the control-plane nodes.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources overrides the compute resources of the scheduler container.
+	// Defaults to 100m CPU and 500Mi memory requests.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector restricts the scheduler pods to the nodes matching the labels.
+	// When set, the default affinity to the control-plane nodes is dropped.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Affinity replaces the default affinity of the scheduler pods, which requires
+	// the control-plane nodes and spreads the replicas across them.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Tolerations replaces the default tolerations of the scheduler pods,
+	// which tolerate the control-plane nodes taint.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// PriorityClassName sets the priority class of the scheduler pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// ScoringStrategy sets how the NodeResourceTopologyMatch plugin scores the nodes.
+	// Defaults to the scheduler plugin built-in strategy.
+	// +optional
+	ScoringStrategy *ScoringStrategyParams `json:"scoringStrategy,omitempty"`
+	// CacheResyncPeriod sets the interval between two resyncs of the scheduler plugin NodeResourceTopology cache.
+	// The period is rounded to seconds, zero disables the cache.
+	// Defaults to the scheduler plugin built-in period.
+	// +optional
+	CacheResyncPeriod *metav1.Duration `json:"cacheResyncPeriod,omitempty"`
+	// Profiles defines additional NUMA-aware scheduler profiles, served by the same scheduler besides
+	// the profile defined by SchedulerName, ScoringStrategy and CacheResyncPeriod.
+	// The profile names must be unique.
+	// +optional
+	Profiles []SchedulerProfile `json:"profiles,omitempty"`
+	// Paused stops the reconciliation of the scheduler objects, e.g. during a cluster maintenance.
+	// While paused, the operator only refreshes the status and reports the objects differing from the desired state.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// SchedulerProfile defines a NUMA-aware scheduler profile, with its own NodeResourceTopologyMatch plugin settings
+type SchedulerProfile struct {
+	// SchedulerName is the name the pods set in their spec to be scheduled by this profile
+	SchedulerName string `json:"schedulerName"`
+	// ScoringStrategy sets how the NodeResourceTopologyMatch plugin scores the nodes.
+	// Defaults to the scheduler plugin built-in strategy.
+	// +optional
+	ScoringStrategy *ScoringStrategyParams `json:"scoringStrategy,omitempty"`
+	// CacheResyncPeriod sets the interval between two resyncs of the scheduler plugin NodeResourceTopology cache.
+	// The period is rounded to seconds, zero disables the cache.
+	// Defaults to the scheduler plugin built-in period.
+	// +optional
+	CacheResyncPeriod *metav1.Duration `json:"cacheResyncPeriod,omitempty"`
+}
+
+// ScoringStrategyType is the scoring strategy of the NodeResourceTopologyMatch scheduler plugin
+// +kubebuilder:validation:Enum=MostAllocated;BalancedAllocation;LeastAllocated
+type ScoringStrategyType string
+
+const (
+	// MostAllocated favors the nodes whose NUMA zones have the least resources left
+	MostAllocated ScoringStrategyType = "MostAllocated"
+	// BalancedAllocation favors the nodes whose NUMA zones have the most balanced resource usage
+	BalancedAllocation ScoringStrategyType = "BalancedAllocation"
+	// LeastAllocated favors the nodes whose NUMA zones have the most resources left
+	LeastAllocated ScoringStrategyType = "LeastAllocated"
+)
+
+// ScoringStrategyParams defines the scoring strategy of the NodeResourceTopologyMatch scheduler plugin
+type ScoringStrategyParams struct {
+	// Type is the scoring strategy
+	// +optional
+	Type ScoringStrategyType `json:"type,omitempty"`
+	// Resources are the resources the scoring accounts for, with their weights
+	// +optional
+	Resources []ResourceSpecParams `json:"resources,omitempty"`
+}
+
+// ResourceSpecParams defines the weight of a resource in the node scoring
+type ResourceSpecParams struct {
+	// Name is the name of the resource
+	Name string `json:"name"`
+	// Weight is the weight of the resource
+	// +kubebuilder:validation:Minimum=1
+	Weight int64 `json:"weight"`
 }
 
 // NUMAResourcesSchedulerStatus defines the observed state of NUMAResourcesScheduler
 type NUMAResourcesSchedulerStatus struct {
 	Deployment    NamespacedName `json:"deployment,omitempty"`
 	SchedulerName string         `json:"schedulerName,omitempty"`
+	// SchedulerNames lists the names of all the NUMA-aware scheduler profiles
+	// +optional
+	SchedulerNames []string `json:"schedulerNames,omitempty"`
+	// ReadyReplicas is the number of scheduler replicas ready to schedule the pods
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
 	// Conditions show the current state of the NUMAResourcesOperator Operator
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
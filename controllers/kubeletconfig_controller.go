@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -43,6 +44,7 @@ import (
 	"github.com/openshift-kni/numaresources-operator/pkg/apply"
 	"github.com/openshift-kni/numaresources-operator/pkg/machineconfigpools"
 	mcpfind "github.com/openshift-kni/numaresources-operator/pkg/machineconfigpools/find"
+	"github.com/openshift-kni/numaresources-operator/pkg/metrics"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
 	cfgstate "github.com/openshift-kni/numaresources-operator/pkg/objectstate/cfg"
 	rteconfig "github.com/openshift-kni/numaresources-operator/rte/pkg/config"
@@ -90,6 +92,7 @@ func (r *KubeletConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	cm, err := r.reconcileConfigMap(ctx, instance, req.NamespacedName)
 	if err != nil {
 		klog.ErrorS(err, "failed to reconcile configmap", "controller", "kubeletconfig")
+		metrics.KubeletConfigRenderFailed(req.NamespacedName.Name)
 
 		msg := fmt.Sprintf("Failed to update RTE config from kubelet config %s/%s", req.NamespacedName.Namespace, req.NamespacedName.Name)
 		r.Recorder.Event(instance, "Warning", "ProcessFailed", msg)
@@ -181,20 +184,27 @@ func renderRTEConfig(namespace, name string, klConfig *kubeletconfigv1beta1.Kube
 	return rtemanifests.CreateConfigMap(namespace, name, string(data)), nil
 }
 
-func findReservedMemoryFromKubelet(klMemRes []kubeletconfigv1beta1.MemoryReservation) map[int]int64 {
-	res := make(map[int]int64)
+// findReservedMemoryFromKubelet returns the memory reserved by kubelet by memory type. When only plain memory
+// is reserved the result renders in the legacy format, which the older exporters can read too.
+func findReservedMemoryFromKubelet(klMemRes []kubeletconfigv1beta1.MemoryReservation) sysinfo.ReservedMemory {
+	res := make(sysinfo.ReservedMemory)
 	for _, memRes := range klMemRes {
 		for resName, resQty := range memRes.Limits {
-			if resName != corev1.ResourceMemory {
-				// TODO we support only memory reservation atm
+			if resName != corev1.ResourceMemory && !strings.HasPrefix(string(resName), corev1.ResourceHugePagesPrefix) {
+				klog.Warningf("unsupported reserved memory type %q on NUMA node %d, skipped", resName, memRes.NumaNode)
 				continue
 			}
 			v, ok := resQty.AsInt64()
 			if !ok {
-				// TODO log?
+				klog.Warningf("cannot represent the reserved %q on NUMA node %d: %s, skipped", resName, memRes.NumaNode, resQty.String())
 				continue
 			}
-			res[int(memRes.NumaNode)] = v
+			counters, ok := res[string(resName)]
+			if !ok {
+				counters = make(sysinfo.PerNUMACounters)
+			}
+			counters[int(memRes.NumaNode)] = v
+			res[string(resName)] = counters
 		}
 	}
 	return res
@@ -19,10 +19,12 @@ package controllers
 import (
 	"context"
 
+	"github.com/ghodss/yaml"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	machineconfigv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -35,6 +37,8 @@ import (
 	nrov1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
 	"github.com/openshift-kni/numaresources-operator/pkg/testutils"
+	rteconfig "github.com/openshift-kni/numaresources-operator/rte/pkg/config"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
 )
 
 const (
@@ -51,6 +55,17 @@ func NewFakeKubeletConfigReconciler(initObjects ...runtime.Object) (*KubeletConf
 	}, nil
 }
 
+// getRenderedReservedMemory returns the reserved memory of the given exporter config as it was rendered
+func getRenderedReservedMemory(data string) interface{} {
+	var conf struct {
+		Resources struct {
+			ReservedMemory interface{} `json:"reservedMemory"`
+		} `json:"resources"`
+	}
+	Expect(yaml.Unmarshal([]byte(data), &conf)).To(Succeed())
+	return conf.Resources.ReservedMemory
+}
+
 var _ = Describe("Test KubeletConfig Reconcile", func() {
 	Context("with KubeletConfig objects already present in the cluster", func() {
 		var nro *nrov1alpha1.NUMAResourcesOperator
@@ -97,6 +112,111 @@ var _ = Describe("Test KubeletConfig Reconcile", func() {
 				Expect(reconciler.Client.Get(context.TODO(), key, cm)).ToNot(HaveOccurred())
 
 			})
+
+			It("should render the reserved memory and hugepages in the configmap", func() {
+				kubeletConfig := &kubeletconfigv1beta1.KubeletConfiguration{
+					ReservedMemory: []kubeletconfigv1beta1.MemoryReservation{
+						{
+							NumaNode: 0,
+							Limits: corev1.ResourceList{
+								corev1.ResourceMemory:                resource.MustParse("1Gi"),
+								corev1.ResourceName("hugepages-1Gi"): resource.MustParse("2Gi"),
+							},
+						},
+						{
+							NumaNode: 1,
+							Limits: corev1.ResourceList{
+								corev1.ResourceName("hugepages-2Mi"): resource.MustParse("512Mi"),
+							},
+						},
+					},
+				}
+				mcoKc := testutils.NewKubeletConfig("test1", label1, mcp1.Spec.MachineConfigSelector, kubeletConfig)
+				reconciler, err := NewFakeKubeletConfigReconciler(nro, mcp1, mcoKc)
+				Expect(err).ToNot(HaveOccurred())
+
+				key := client.ObjectKeyFromObject(mcoKc)
+				_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+				Expect(err).ToNot(HaveOccurred())
+
+				cm := &corev1.ConfigMap{}
+				key = client.ObjectKey{
+					Namespace: testNamespace,
+					Name:      objectnames.GetComponentName(nro.Name, mcp1.Name),
+				}
+				Expect(reconciler.Client.Get(context.TODO(), key, cm)).ToNot(HaveOccurred())
+				Expect(cm.Data).To(HaveLen(1))
+
+				for _, data := range cm.Data {
+					conf := rteconfig.Config{}
+					Expect(yaml.Unmarshal([]byte(data), &conf)).To(Succeed())
+					Expect(conf.Resources.ReservedMemory).To(Equal(sysinfo.ReservedMemory{
+						"memory": {
+							0: 1024 * 1024 * 1024,
+						},
+						"hugepages-1Gi": {
+							0: 2 * 1024 * 1024 * 1024,
+						},
+						"hugepages-2Mi": {
+							1: 512 * 1024 * 1024,
+						},
+					}))
+					Expect(getRenderedReservedMemory(data)).To(Equal(map[string]interface{}{
+						"memory": map[string]interface{}{
+							"0": float64(1024 * 1024 * 1024),
+						},
+						"hugepages-1Gi": map[string]interface{}{
+							"0": float64(2 * 1024 * 1024 * 1024),
+						},
+						"hugepages-2Mi": map[string]interface{}{
+							"1": float64(512 * 1024 * 1024),
+						},
+					}))
+				}
+			})
+
+			It("should render the reserved memory in the legacy format when no hugepages are reserved", func() {
+				kubeletConfig := &kubeletconfigv1beta1.KubeletConfiguration{
+					ReservedMemory: []kubeletconfigv1beta1.MemoryReservation{
+						{
+							NumaNode: 0,
+							Limits: corev1.ResourceList{
+								corev1.ResourceMemory: resource.MustParse("1Gi"),
+							},
+						},
+						{
+							NumaNode: 1,
+							Limits: corev1.ResourceList{
+								corev1.ResourceMemory: resource.MustParse("2Gi"),
+							},
+						},
+					},
+				}
+				mcoKc := testutils.NewKubeletConfig("test1", label1, mcp1.Spec.MachineConfigSelector, kubeletConfig)
+				reconciler, err := NewFakeKubeletConfigReconciler(nro, mcp1, mcoKc)
+				Expect(err).ToNot(HaveOccurred())
+
+				key := client.ObjectKeyFromObject(mcoKc)
+				_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+				Expect(err).ToNot(HaveOccurred())
+
+				cm := &corev1.ConfigMap{}
+				key = client.ObjectKey{
+					Namespace: testNamespace,
+					Name:      objectnames.GetComponentName(nro.Name, mcp1.Name),
+				}
+				Expect(reconciler.Client.Get(context.TODO(), key, cm)).ToNot(HaveOccurred())
+				Expect(cm.Data).To(HaveLen(1))
+
+				for _, data := range cm.Data {
+					// the exporters predating the hugepages reservation only read this format
+					Expect(getRenderedReservedMemory(data)).To(Equal(map[string]interface{}{
+						"0": float64(1024 * 1024 * 1024),
+						"1": float64(2 * 1024 * 1024 * 1024),
+					}))
+				}
+			})
+
 			It("should send events when NRO present and operation succesfull", func() {
 				reconciler, err := NewFakeKubeletConfigReconciler(nro, mcp1, mcoKc1)
 				Expect(err).ToNot(HaveOccurred())
@@ -35,7 +35,9 @@ import (
 	apiextensionv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -55,7 +57,10 @@ import (
 	"github.com/openshift-kni/numaresources-operator/pkg/apply"
 	"github.com/openshift-kni/numaresources-operator/pkg/loglevel"
 	"github.com/openshift-kni/numaresources-operator/pkg/machineconfigpools"
+	mcpfind "github.com/openshift-kni/numaresources-operator/pkg/machineconfigpools/find"
+	"github.com/openshift-kni/numaresources-operator/pkg/metrics"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectstate"
 	apistate "github.com/openshift-kni/numaresources-operator/pkg/objectstate/api"
 	rtestate "github.com/openshift-kni/numaresources-operator/pkg/objectstate/rte"
 	"github.com/openshift-kni/numaresources-operator/pkg/status"
@@ -63,7 +68,7 @@ import (
 )
 
 const (
-	defaultNUMAResourcesOperatorCrName = "numaresourcesoperator"
+	defaultNUMAResourcesOperatorCrName = objectnames.DefaultNUMAResourcesOperatorCrName
 	numaResourcesRetryPeriod           = 1 * time.Minute
 )
 
@@ -99,6 +104,9 @@ type NUMAResourcesOperatorReconciler struct {
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=*
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=*
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=*
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=*
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 //+kubebuilder:rbac:groups=nodetopology.openshift.io,resources=numaresourcesoperators,verbs=*
 //+kubebuilder:rbac:groups=nodetopology.openshift.io,resources=numaresourcesoperators/status,verbs=get;update;patch
@@ -121,12 +129,22 @@ func (r *NUMAResourcesOperatorReconciler) Reconcile(ctx context.Context, req ctr
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			deleteMetrics(req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, err
 	}
 
+	// the deletion goes through even if paused, or the finalizer would hold the object forever
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.reconcileDeletion(ctx, instance)
+	}
+
+	if instance.Spec.Paused {
+		return r.reconcilePaused(ctx, instance)
+	}
+
 	if req.Name != defaultNUMAResourcesOperatorCrName {
 		message := fmt.Sprintf("incorrect NUMAResourcesOperator resource name: %s", instance.Name)
 		return r.updateStatus(ctx, instance, status.ConditionDegraded, status.ConditionTypeIncorrectNUMAResourcesOperatorResourceName, message)
@@ -136,54 +154,73 @@ func (r *NUMAResourcesOperatorReconciler) Reconcile(ctx context.Context, req ctr
 		return r.updateStatus(ctx, instance, status.ConditionDegraded, validation.NodeGroupsError, err.Error())
 	}
 
-	mcps, err := machineconfigpools.GetNodeGroupsMCPs(ctx, r.Client, instance.Spec.NodeGroups)
-	if err != nil {
+	if err := validation.NodeGroupsForPlatform(instance.Spec.NodeGroups, r.Platform); err != nil {
 		return r.updateStatus(ctx, instance, status.ConditionDegraded, validation.NodeGroupsError, err.Error())
 	}
 
-	if err := validation.MachineConfigPoolDuplicates(mcps); err != nil {
-		return r.updateStatus(ctx, instance, status.ConditionDegraded, validation.NodeGroupsError, err.Error())
+	// machine config pools exist only on OpenShift, on other platforms the node groups select the nodes directly
+	var mcps []*machineconfigv1.MachineConfigPool
+	if r.Platform == platform.OpenShift {
+		var err error
+		mcps, err = machineconfigpools.GetNodeGroupsMCPs(ctx, r.Client, instance.Spec.NodeGroups)
+		if err != nil {
+			return r.updateStatus(ctx, instance, status.ConditionDegraded, validation.NodeGroupsError, err.Error())
+		}
+
+		if err := validation.MachineConfigPoolDuplicates(mcps); err != nil {
+			return r.updateStatus(ctx, instance, status.ConditionDegraded, validation.NodeGroupsError, err.Error())
+		}
 	}
 
-	result, condition, err := r.reconcileResource(ctx, instance, mcps)
+	if err := r.ensureFinalizer(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result, condition, reason, err := r.reconcileResource(ctx, instance, mcps)
 	if condition != "" {
-		// TODO: use proper reason
-		reason, message := condition, messageFromError(err)
-		_, _ = r.updateStatus(ctx, instance, condition, reason, message)
+		_, _ = r.updateStatus(ctx, instance, condition, reason, messageFromError(err))
 	}
-	return result, err
+	return result, reconcileError(err)
 }
 
 func (r *NUMAResourcesOperatorReconciler) updateStatus(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, condition string, reason string, message string) (ctrl.Result, error) {
-	klog.Error(message)
+	if condition == status.ConditionDegraded {
+		klog.Error(message)
+	}
 
 	if err := status.Update(ctx, r.Client, instance, condition, reason, message); err != nil {
 		klog.InfoS("Failed to update numaresourcesoperator status", "Desired condition", status.ConditionDegraded, "error", err)
 		return ctrl.Result{}, err
 	}
+	metrics.SetConditions("NUMAResourcesOperator", instance.Name, instance.Status.Conditions)
 
 	// we do not return an error here because to pass the validation error a user will need to update NRO CR
 	// that will anyway initiate to reconcile loop
 	return ctrl.Result{}, nil
 }
 
+// reconcileError filters out the errors reporting the resources are not ready yet, which are retried on the requeue
+func reconcileError(err error) error {
+	var notReady status.ErrResourcesNotReady
+	if errors.As(err, &notReady) {
+		return nil
+	}
+	return err
+}
+
 func messageFromError(err error) string {
 	if err == nil {
 		return ""
 	}
-	unwErr := errors.Unwrap(err)
-	if unwErr == nil {
-		return ""
-	}
-	return unwErr.Error()
+	return err.Error()
 }
 
-func (r *NUMAResourcesOperatorReconciler) reconcileResource(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) (ctrl.Result, string, error) {
+func (r *NUMAResourcesOperatorReconciler) reconcileResource(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) (ctrl.Result, string, string, error) {
 	var err error
 	err = r.syncNodeResourceTopologyAPI()
 	if err != nil {
 		r.Recorder.Eventf(instance, corev1.EventTypeWarning, "FailedCRDInstall", "Failed to install Node Resource Topology CRD: %v", err)
-		return ctrl.Result{}, status.ConditionDegraded, errors.Wrapf(err, "FailedAPISync")
+		return ctrl.Result{}, status.ConditionDegraded, status.ReasonCRDInstallFailed, errors.Wrapf(err, "failed to install the node resource topology CRD")
 	}
 	r.Recorder.Eventf(instance, corev1.EventTypeNormal, "SuccessfulCRDInstall", "Node Resource Topology CRD installed")
 
@@ -192,39 +229,47 @@ func (r *NUMAResourcesOperatorReconciler) reconcileResource(ctx context.Context,
 		// before creating additional components
 		if err := r.syncMachineConfigs(ctx, instance, mcps); err != nil {
 			r.Recorder.Eventf(instance, corev1.EventTypeWarning, "FailedMCSync", "Failed to set up machine configuration for worker nodes: %v", err)
-			return ctrl.Result{}, status.ConditionDegraded, errors.Wrapf(err, "failed to sync machine configs")
+			return ctrl.Result{}, status.ConditionDegraded, status.ReasonMachineConfigSyncFailed, errors.Wrapf(err, "failed to sync machine configs")
 		}
 		r.Recorder.Eventf(instance, corev1.EventTypeNormal, "SuccessfulMCSync", "Enabled machine configuration for worker nodes")
+		observeMachineConfigPoolsWait(instance.Name, mcps)
 
 		// MCO need to update SELinux context and other stuff, and need to trigger a reboot.
 		// It can take a while.
 		if allMCPsUpdated := r.syncMachineConfigPoolsStatuses(instance, mcps); !allMCPsUpdated {
+			if err := r.syncNodeGroupsStatus(ctx, instance, mcps); err != nil {
+				return ctrl.Result{}, status.ConditionDegraded, status.ReasonNodeGroupsStatusFailed, errors.Wrapf(err, "failed to sync node groups status")
+			}
 			// the Machine Config Pool still did not apply the machine config, wait for one minute
-			return ctrl.Result{RequeueAfter: numaResourcesRetryPeriod}, status.ConditionProgressing, nil
+			return ctrl.Result{RequeueAfter: numaResourcesRetryPeriod}, status.ConditionProgressing, status.ReasonMachineConfigPending, status.ErrResourcesNotReady{Message: "waiting for the machine config pools to apply the machine configs"}
 		}
 	}
 
 	daemonSetsInfo, err := r.syncNUMAResourcesOperatorResources(ctx, instance, mcps)
 	if err != nil {
 		r.Recorder.Eventf(instance, corev1.EventTypeWarning, "FailedRTECreate", "Failed to create Resource-Topology-Exporter DaemonSets: %v", err)
-		return ctrl.Result{}, status.ConditionDegraded, errors.Wrapf(err, "FailedRTESync")
+		return ctrl.Result{}, status.ConditionDegraded, status.ReasonRTESyncFailed, errors.Wrapf(err, "failed to sync the resource topology exporter")
 	}
 	r.Recorder.Eventf(instance, corev1.EventTypeNormal, "SuccessfulRTECreate", "Created Resource-Topology-Exporter DaemonSets")
 
+	if err := r.syncNodeGroupsStatus(ctx, instance, mcps); err != nil {
+		return ctrl.Result{}, status.ConditionDegraded, status.ReasonNodeGroupsStatusFailed, errors.Wrapf(err, "failed to sync node groups status")
+	}
+
 	instance.Status.DaemonSets = []nropv1alpha1.NamespacedName{}
 	for _, nname := range daemonSetsInfo {
 		ok, err := r.Helper.IsDaemonSetRunning(nname.Namespace, nname.Name)
 		if err != nil {
-			return ctrl.Result{}, status.ConditionDegraded, err
+			return ctrl.Result{}, status.ConditionDegraded, status.ReasonDaemonSetStatusFailed, err
 		}
 		if !ok {
-			return ctrl.Result{RequeueAfter: 5 * time.Second}, status.ConditionProgressing, nil
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, status.ConditionProgressing, status.ReasonDaemonSetNotReady, status.ErrResourcesNotReady{Message: fmt.Sprintf("waiting for the daemon set %s/%s to be ready", nname.Namespace, nname.Name)}
 		}
 
 		instance.Status.DaemonSets = append(instance.Status.DaemonSets, nname)
 	}
 
-	return ctrl.Result{}, status.ConditionAvailable, nil
+	return ctrl.Result{}, status.ConditionAvailable, status.ReasonAsExpected, nil
 }
 
 func (r *NUMAResourcesOperatorReconciler) syncNodeResourceTopologyAPI() error {
@@ -276,12 +321,112 @@ func (r *NUMAResourcesOperatorReconciler) syncMachineConfigPoolsStatuses(instanc
 
 		if !IsMachineConfigPoolUpdated(instance.Name, mcp) {
 			allMCPsUpdated = false
-			break
 		}
 	}
 	return allMCPsUpdated
 }
 
+// syncNodeGroupsStatus reports, for each node group, the rollout state of the machine config and of the daemon set
+func (r *NUMAResourcesOperatorReconciler) syncNodeGroupsStatus(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) error {
+	var nodeGroupsStatus []nropv1alpha1.NodeGroupStatus
+	for _, mcp := range mcps {
+		ngStatus := nropv1alpha1.NodeGroupStatus{
+			Name:               mcp.Name,
+			MachineConfigPool:  mcp.Name,
+			MachineConfig:      objectnames.GetMachineConfigName(instance.Name, mcp.Name),
+			MachineConfigState: machineConfigState(instance.Name, mcp),
+		}
+		if err := r.updateNodeGroupDaemonSetStatus(ctx, instance, &ngStatus); err != nil {
+			return err
+		}
+		nodeGroupsStatus = append(nodeGroupsStatus, ngStatus)
+	}
+
+	for _, nodeGroup := range instance.Spec.NodeGroups {
+		if nodeGroup.NodeSelector == nil {
+			continue
+		}
+		ngStatus := nropv1alpha1.NodeGroupStatus{
+			Name: nodeGroup.Name,
+		}
+		if err := r.updateNodeGroupDaemonSetStatus(ctx, instance, &ngStatus); err != nil {
+			return err
+		}
+		nodeGroupsStatus = append(nodeGroupsStatus, ngStatus)
+	}
+
+	instance.Status.NodeGroups = nodeGroupsStatus
+
+	var nodeGroupNames []string
+	for _, ngStatus := range nodeGroupsStatus {
+		if ngStatus.DaemonSet.Name != "" {
+			nodeGroupNames = append(nodeGroupNames, ngStatus.Name)
+		}
+	}
+	metrics.DeleteStaleNodeGroups(instance.Name, nodeGroupNames)
+	return nil
+}
+
+func (r *NUMAResourcesOperatorReconciler) updateNodeGroupDaemonSetStatus(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, ngStatus *nropv1alpha1.NodeGroupStatus) error {
+	key := client.ObjectKey{
+		Namespace: r.Namespace,
+		Name:      objectnames.GetComponentName(instance.Name, ngStatus.Name),
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := r.Get(ctx, key, ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			// not created yet, e.g. still waiting for the machine config
+			return nil
+		}
+		return err
+	}
+
+	ngStatus.DaemonSet = nropv1alpha1.NamespacedName{
+		Namespace: ds.Namespace,
+		Name:      ds.Name,
+	}
+	ngStatus.DesiredNumberScheduled = ds.Status.DesiredNumberScheduled
+	ngStatus.NumberReady = ds.Status.NumberReady
+	ngStatus.UpdatedNumberScheduled = ds.Status.UpdatedNumberScheduled
+	ngStatus.ObservedGeneration = ds.Status.ObservedGeneration
+	metrics.SetNodeGroupDaemonSetReadyRatio(instance.Name, ngStatus.Name, ds.Status.NumberReady, ds.Status.DesiredNumberScheduled)
+	return nil
+}
+
+// observeMachineConfigPoolsWait tracks the time the machine config pools take to apply the machine configs
+func observeMachineConfigPoolsWait(instanceName string, mcps []*machineconfigv1.MachineConfigPool) {
+	var mcpNames []string
+	for _, mcp := range mcps {
+		if IsMachineConfigPoolUpdated(instanceName, mcp) {
+			metrics.MachineConfigPoolWaitEnded(mcp.Name)
+		} else {
+			metrics.MachineConfigPoolWaitStarted(mcp.Name)
+		}
+		mcpNames = append(mcpNames, mcp.Name)
+	}
+	metrics.DeleteStaleMachineConfigPools(mcpNames)
+}
+
+// deleteMetrics deletes the series about the given NUMAResourcesOperator, once gone
+func deleteMetrics(instanceName string) {
+	metrics.DeleteConditions("NUMAResourcesOperator", instanceName)
+	metrics.DeleteStaleNodeGroups(instanceName, nil)
+	metrics.DeleteStaleMachineConfigPools(nil)
+}
+
+func machineConfigState(instanceName string, mcp *machineconfigv1.MachineConfigPool) nropv1alpha1.MachineConfigState {
+	if !isMachineConfigExists(instanceName, mcp) {
+		return nropv1alpha1.MachineConfigStatePending
+	}
+	if machineconfigv1.IsMachineConfigPoolConditionTrue(mcp.Status.Conditions, machineconfigv1.MachineConfigPoolDegraded) {
+		return nropv1alpha1.MachineConfigStateDegraded
+	}
+	if IsMachineConfigPoolUpdated(instanceName, mcp) {
+		return nropv1alpha1.MachineConfigStateUpdated
+	}
+	return nropv1alpha1.MachineConfigStateUpdating
+}
+
 func (r *NUMAResourcesOperatorReconciler) syncNUMAResourcesOperatorResources(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) ([]nropv1alpha1.NamespacedName, error) {
 	klog.Info("RTESync start")
 
@@ -290,23 +435,31 @@ func (r *NUMAResourcesOperatorReconciler) syncNUMAResourcesOperatorResources(ctx
 		klog.ErrorS(fmt.Errorf("failed to delete unused daemonsets"), "errors", errorList)
 	}
 
-	errorList = r.deleteUnusedMachineConfigs(ctx, instance, mcps)
+	errorList = r.deleteUnusedMetricsObjects(ctx, instance, mcps)
 	if len(errorList) > 0 {
-		klog.ErrorS(fmt.Errorf("failed to delete unused machineconfigs"), "errors", errorList)
+		klog.ErrorS(fmt.Errorf("failed to delete unused metrics objects"), "errors", errorList)
+	}
+
+	if r.Platform == platform.OpenShift {
+		errorList = r.deleteUnusedMachineConfigs(ctx, instance, mcps)
+		if len(errorList) > 0 {
+			klog.ErrorS(fmt.Errorf("failed to delete unused machineconfigs"), "errors", errorList)
+		}
 	}
 
 	var daemonSetsNName []nropv1alpha1.NamespacedName
 
-	err := rtestate.UpdateDaemonSetUserImageSettings(r.RTEManifests.DaemonSet, instance.Spec.ExporterImage, r.ImageSpec, r.ImagePullPolicy)
+	rteManifests, err := r.renderRTEManifests(instance)
 	if err != nil {
 		return daemonSetsNName, err
 	}
-	if err = loglevel.UpdatePodSpec(&r.RTEManifests.DaemonSet.Spec.Template.Spec, instance.Spec.LogLevel); err != nil {
-		return daemonSetsNName, err
-	}
 
-	existing := rtestate.FromClient(ctx, r.Client, r.Platform, r.RTEManifests, instance, mcps, r.Namespace)
-	for _, objState := range existing.State(r.RTEManifests, r.Platform, instance, mcps) {
+	existing := rtestate.FromClient(ctx, r.Client, r.Platform, rteManifests, instance, mcps, r.Namespace)
+	objStates, err := existing.State(rteManifests, r.Platform, instance, mcps, r.nodeGroupConfigUpdater(instance))
+	if err != nil {
+		return nil, err
+	}
+	for _, objState := range objStates {
 		if err := controllerutil.SetControllerReference(instance, objState.Desired, r.Scheme); err != nil {
 			return nil, errors.Wrapf(err, "Failed to set controller reference to %s %s", objState.Desired.GetNamespace(), objState.Desired.GetName())
 		}
@@ -322,6 +475,117 @@ func (r *NUMAResourcesOperatorReconciler) syncNUMAResourcesOperatorResources(ctx
 	return daemonSetsNName, nil
 }
 
+// renderRTEManifests returns the RTE manifests with the global settings applied,
+// which are the baseline on top of which each node group applies its own config.
+// The exporter image is not among them, because it may differ per node group.
+func (r *NUMAResourcesOperatorReconciler) renderRTEManifests(instance *nropv1alpha1.NUMAResourcesOperator) (rtemanifests.Manifests, error) {
+	rteManifests := r.RTEManifests.Clone()
+	if err := loglevel.UpdatePodSpec(&rteManifests.DaemonSet.Spec.Template.Spec, instance.Spec.LogLevel); err != nil {
+		return rteManifests, err
+	}
+	return rteManifests, nil
+}
+
+// reconcilePaused refreshes the status without touching the deployed objects,
+// and reports the objects the reconciliation would create or update once resumed
+func (r *NUMAResourcesOperatorReconciler) reconcilePaused(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) (ctrl.Result, error) {
+	klog.InfoS("Reconciliation paused", "object", instance.Name)
+
+	drifted, err := r.refreshPausedStatus(ctx, instance)
+	reason, message := status.PausedReasonAndMessage(drifted, err)
+	instance.Status.Conditions = status.UpdatePausedCondition(instance.Status.Conditions, true, reason, message, instance.Generation)
+	if err := r.Client.Status().Update(ctx, instance); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "could not update status for object %s", client.ObjectKeyFromObject(instance))
+	}
+	metrics.SetConditions("NUMAResourcesOperator", instance.Name, instance.Status.Conditions)
+	// nothing triggers a new reconcile on drift, so check again periodically
+	return ctrl.Result{RequeueAfter: numaResourcesRetryPeriod}, nil
+}
+
+func (r *NUMAResourcesOperatorReconciler) refreshPausedStatus(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) ([]string, error) {
+	var mcps []*machineconfigv1.MachineConfigPool
+	if r.Platform == platform.OpenShift {
+		var err error
+		mcps, err = machineconfigpools.GetNodeGroupsMCPs(ctx, r.Client, instance.Spec.NodeGroups)
+		if err != nil {
+			return nil, err
+		}
+		r.syncMachineConfigPoolsStatuses(instance, mcps)
+	}
+
+	if err := r.syncNodeGroupsStatus(ctx, instance, mcps); err != nil {
+		return nil, err
+	}
+
+	objStates := apistate.FromClient(ctx, r.Client, r.Platform, r.APIManifests).State(r.APIManifests)
+
+	var ownedStates []objectstate.ObjectState
+	if r.Platform == platform.OpenShift {
+		existing := rtestate.FromClient(ctx, r.Client, r.Platform, r.RTEManifests, instance, mcps, r.Namespace)
+		ownedStates = append(ownedStates, existing.MachineConfigsState(r.RTEManifests, instance, mcps)...)
+	}
+
+	rteManifests, err := r.renderRTEManifests(instance)
+	if err != nil {
+		return nil, err
+	}
+	existing := rtestate.FromClient(ctx, r.Client, r.Platform, rteManifests, instance, mcps, r.Namespace)
+	rteStates, err := existing.State(rteManifests, r.Platform, instance, mcps, r.nodeGroupConfigUpdater(instance))
+	if err != nil {
+		return nil, err
+	}
+	ownedStates = append(ownedStates, rteStates...)
+
+	// the owner reference is part of the desired state, like when the objects are applied
+	for _, objState := range ownedStates {
+		if err := controllerutil.SetControllerReference(instance, objState.Desired, r.Scheme); err != nil {
+			return nil, errors.Wrapf(err, "failed to set controller reference to %s %s", objState.Desired.GetNamespace(), objState.Desired.GetName())
+		}
+	}
+
+	return driftedObjects(append(objStates, ownedStates...))
+}
+
+// driftedObjects returns the kind and the key of the objects the reconciliation would create or update
+func driftedObjects(objStates []objectstate.ObjectState) ([]string, error) {
+	var drifted []string
+	for _, objState := range objStates {
+		ok, err := apply.IsObjectDrifted(objState)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		drifted = append(drifted, describeObject(objState.Desired))
+	}
+	return drifted, nil
+}
+
+// describeObject returns the kind and the name of the object, like "Kind namespace/name"
+func describeObject(obj client.Object) string {
+	name := obj.GetName()
+	if ns := obj.GetNamespace(); ns != "" {
+		name = ns + "/" + name
+	}
+	return fmt.Sprintf("%s %s", obj.GetObjectKind().GroupVersionKind().Kind, name)
+}
+
+// nodeGroupConfigUpdater returns the updater applying the exporter image of each node group, and the settings
+// which only the built-in image supports, before the node group config
+func (r *NUMAResourcesOperatorReconciler) nodeGroupConfigUpdater(instance *nropv1alpha1.NUMAResourcesOperator) rtestate.GenerateDesiredManifestUpdater {
+	return func(nodeGroup *nropv1alpha1.NodeGroup, ds *appsv1.DaemonSet) error {
+		userImage := rtestate.UserExporterImage(instance, nodeGroup)
+		if err := rtestate.UpdateDaemonSetUserImageSettings(ds, userImage, r.ImageSpec, r.ImagePullPolicy); err != nil {
+			return err
+		}
+		if nodeGroup == nil {
+			return nil
+		}
+		return rtestate.UpdateDaemonSetNodeGroupConfig(ds, nodeGroup.Config)
+	}
+}
+
 func (r *NUMAResourcesOperatorReconciler) deleteUnusedDaemonSets(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) []error {
 	klog.V(3).Info("Delete Daemonsets start")
 	var errors []error
@@ -331,13 +595,7 @@ func (r *NUMAResourcesOperatorReconciler) deleteUnusedDaemonSets(ctx context.Con
 		return append(errors, err)
 	}
 
-	// Generate the names of the DaemonSets that should be running,
-	// one for each MachineConfigPool
-	expectedDaemonSetNames := sets.NewString()
-	for _, mcp := range mcps {
-		expectedDaemonSetNames = expectedDaemonSetNames.Insert(objectnames.GetComponentName(instance.Name, mcp.Name))
-	}
-
+	expectedDaemonSetNames := getExpectedDaemonSetNames(instance, mcps)
 	for _, ds := range daemonSetList.Items {
 		if !expectedDaemonSetNames.Has(ds.Name) {
 			if isOwnedBy(ds.GetObjectMeta(), instance) {
@@ -354,6 +612,104 @@ func (r *NUMAResourcesOperatorReconciler) deleteUnusedDaemonSets(ctx context.Con
 	return errors
 }
 
+// getExpectedDaemonSetNames generates the names of the DaemonSets that should be running,
+// one for each MachineConfigPool and one for each node group selecting the nodes directly
+func getExpectedDaemonSetNames(instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) sets.String {
+	expectedDaemonSetNames := sets.NewString()
+	for _, mcp := range mcps {
+		expectedDaemonSetNames = expectedDaemonSetNames.Insert(objectnames.GetComponentName(instance.Name, mcp.Name))
+	}
+	for _, nodeGroup := range instance.Spec.NodeGroups {
+		if nodeGroup.NodeSelector == nil {
+			continue
+		}
+		expectedDaemonSetNames = expectedDaemonSetNames.Insert(objectnames.GetComponentName(instance.Name, nodeGroup.Name))
+	}
+	return expectedDaemonSetNames
+}
+
+// getExpectedMetricsNames generates the names of the metrics services and service monitors that should exist,
+// one for each DaemonSet running the built-in exporter image, the only one serving the metrics we expose
+func getExpectedMetricsNames(instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) sets.String {
+	expectedNames := sets.NewString()
+	for _, mcp := range mcps {
+		nodeGroup, _ := mcpfind.NodeGroupByMCP(instance.Spec.NodeGroups, mcp)
+		if rtestate.UserExporterImage(instance, nodeGroup) != "" {
+			continue
+		}
+		expectedNames = expectedNames.Insert(objectnames.GetComponentName(instance.Name, mcp.Name))
+	}
+	for i := range instance.Spec.NodeGroups {
+		nodeGroup := &instance.Spec.NodeGroups[i]
+		if nodeGroup.NodeSelector == nil || rtestate.UserExporterImage(instance, nodeGroup) != "" {
+			continue
+		}
+		expectedNames = expectedNames.Insert(objectnames.GetComponentName(instance.Name, nodeGroup.Name))
+	}
+	return expectedNames
+}
+
+// deleteUnusedMetricsObjects deletes the metrics services and service monitors of the removed node groups
+// and of the ones running a user-provided exporter image, or all of them, and the RBAC of the cluster monitoring, if the exporter metrics are disabled.
+// The services and service monitors are named after the daemonsets.
+func (r *NUMAResourcesOperatorReconciler) deleteUnusedMetricsObjects(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) []error {
+	klog.V(3).Info("Delete metrics objects start")
+	expectedNames := sets.NewString()
+	if !instance.Spec.DisableExporterMetrics {
+		expectedNames = getExpectedMetricsNames(instance, mcps)
+	}
+
+	var objs []client.Object
+	var serviceList corev1.ServiceList
+	if err := r.List(ctx, &serviceList, &client.ListOptions{Namespace: instance.Namespace}); err != nil {
+		klog.ErrorS(err, "error while getting Service list")
+		return []error{err}
+	}
+	for i := range serviceList.Items {
+		objs = append(objs, &serviceList.Items[i])
+	}
+
+	serviceMonitorList := &unstructured.UnstructuredList{}
+	serviceMonitorList.SetGroupVersionKind(rtestate.ServiceMonitorGVK.GroupVersion().WithKind(rtestate.ServiceMonitorGVK.Kind + "List"))
+	if err := r.List(ctx, serviceMonitorList, &client.ListOptions{Namespace: instance.Namespace}); err == nil {
+		for i := range serviceMonitorList.Items {
+			objs = append(objs, &serviceMonitorList.Items[i])
+		}
+	} else if !meta.IsNoMatchError(err) {
+		klog.ErrorS(err, "error while getting ServiceMonitor list")
+		return []error{err}
+	}
+
+	if r.Platform == platform.OpenShift && instance.Spec.DisableExporterMetrics {
+		key := client.ObjectKey{
+			Name:      rtestate.MetricsPrometheusRBACName,
+			Namespace: r.Namespace,
+		}
+		for _, obj := range []client.Object{&rbacv1.Role{}, &rbacv1.RoleBinding{}} {
+			if err := r.Get(ctx, key, obj); err == nil {
+				objs = append(objs, obj)
+			} else if !apierrors.IsNotFound(err) {
+				klog.ErrorS(err, "error while getting the prometheus RBAC", "name", key.Name)
+				return []error{err}
+			}
+		}
+	}
+
+	var errors []error
+	for _, obj := range objs {
+		if expectedNames.Has(obj.GetName()) || !isOwnedBy(obj, instance) {
+			continue
+		}
+		if err := r.Client.Delete(ctx, obj); err != nil {
+			klog.ErrorS(err, "error while deleting metrics object", "object", describeObject(obj))
+			errors = append(errors, err)
+		} else {
+			klog.V(3).Infof("%s deleted", describeObject(obj))
+		}
+	}
+	return errors
+}
+
 func (r *NUMAResourcesOperatorReconciler) deleteUnusedMachineConfigs(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) []error {
 	klog.V(3).Info("Delete Machineconfigs start")
 	var errors []error
@@ -428,17 +784,18 @@ func (r *NUMAResourcesOperatorReconciler) SetupWithManager(mgr ctrl.Manager) err
 	b := ctrl.NewControllerManagedBy(mgr).For(&nropv1alpha1.NUMAResourcesOperator{})
 	if r.Platform == platform.OpenShift {
 		b = b.Owns(&securityv1.SecurityContextConstraints{}).
-			Owns(&machineconfigv1.MachineConfig{}, builder.WithPredicates(p))
+			Owns(&machineconfigv1.MachineConfig{}, builder.WithPredicates(p)).
+			Watches(
+				&source.Kind{Type: &machineconfigv1.MachineConfigPool{}},
+				handler.EnqueueRequestsFromMapFunc(r.mcpToNUMAResourceOperator),
+				builder.WithPredicates(mcpPredicates))
 	}
 	return b.Owns(&apiextensionv1.CustomResourceDefinition{}).
 		Owns(&corev1.ServiceAccount{}, builder.WithPredicates(p)).
 		Owns(&rbacv1.RoleBinding{}, builder.WithPredicates(p)).
 		Owns(&rbacv1.Role{}, builder.WithPredicates(p)).
 		Owns(&appsv1.DaemonSet{}, builder.WithPredicates(p)).
-		Watches(
-			&source.Kind{Type: &machineconfigv1.MachineConfigPool{}},
-			handler.EnqueueRequestsFromMapFunc(r.mcpToNUMAResourceOperator),
-			builder.WithPredicates(mcpPredicates)).
+		Owns(&corev1.Service{}, builder.WithPredicates(p)).
 		Complete(r)
 }
 
@@ -39,6 +39,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
@@ -297,6 +298,492 @@ var _ = Describe("Test NUMAResourcesOperator Reconcile", func() {
 			})
 		})
 	})
+	Context("with per-NodeGroup config", func() {
+		It("should apply the node group settings only to the matching DaemonSet", func() {
+			label1 := map[string]string{"test1": "test1"}
+			label2 := map[string]string{"test2": "test2"}
+
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+				{MatchLabels: label2},
+			})
+			nro.Spec.ExporterImage = "quay.io/openshift-kni/rte:global"
+			podReadiness := false
+			nro.Spec.NodeGroups[0].Config = &nrov1alpha1.NodeGroupConfig{
+				ExporterImage:     "quay.io/openshift-kni/rte:group",
+				InfoRefreshPeriod: &metav1.Duration{Duration: 30 * time.Second},
+				PodReadiness:      &podReadiness,
+				NotifyFilePath:    "/run/rte/notify",
+			}
+
+			mcp1 := testutils.NewMachineConfigPool("test1", label1, &metav1.LabelSelector{MatchLabels: label1}, &metav1.LabelSelector{MatchLabels: label1})
+			mcp2 := testutils.NewMachineConfigPool("test2", label2, &metav1.LabelSelector{MatchLabels: label2}, &metav1.LabelSelector{MatchLabels: label2})
+			for _, mcp := range []*machineconfigv1.MachineConfigPool{mcp1, mcp2} {
+				mcp.Status.Configuration.Source = []corev1.ObjectReference{
+					{
+						Name: objectnames.GetMachineConfigName(nro.Name, mcp.Name),
+					},
+				}
+				mcp.Status.Conditions = []machineconfigv1.MachineConfigPoolCondition{
+					{
+						Type:   machineconfigv1.MachineConfigPoolUpdated,
+						Status: corev1.ConditionTrue,
+					},
+				}
+			}
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.OpenShift, nro, mcp1, mcp2)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+
+			ds1 := &appsv1.DaemonSet{}
+			ds1Key := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, mcp1.Name),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), ds1Key, ds1)).ToNot(HaveOccurred())
+			cnt1 := ds1.Spec.Template.Spec.Containers[0]
+			Expect(cnt1.Image).To(Equal("quay.io/openshift-kni/rte:group"))
+			Expect(cnt1.Args).To(ContainElements("--sleep-interval=30s", "--podreadiness=false", "--notify-file=/run/rte/notify"))
+			Expect(ds1.Spec.Template.Spec.ReadinessGates).To(BeEmpty())
+
+			ds2 := &appsv1.DaemonSet{}
+			ds2Key := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, mcp2.Name),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), ds2Key, ds2)).ToNot(HaveOccurred())
+			cnt2 := ds2.Spec.Template.Spec.Containers[0]
+			Expect(cnt2.Image).To(Equal("quay.io/openshift-kni/rte:global"))
+			Expect(cnt2.Args).ToNot(ContainElement("--podreadiness=false"))
+			Expect(ds2.Spec.Template.Spec.ReadinessGates).ToNot(BeEmpty())
+
+			By("checking the shared manifests are not modified")
+			Expect(reconciler.RTEManifests.DaemonSet.Spec.Template.Spec.Containers[0].Image).ToNot(Equal("quay.io/openshift-kni/rte:global"))
+		})
+
+		It("should apply the built-in image settings only to the DaemonSets running the built-in image", func() {
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, nil)
+			nro.Spec.NodeGroups = []nrov1alpha1.NodeGroup{
+				{
+					Name: "group1",
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"test1": "test1"},
+					},
+					Config: &nrov1alpha1.NodeGroupConfig{
+						ExporterImage: "quay.io/openshift-kni/rte:group",
+					},
+				},
+				{
+					Name: "group2",
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"test2": "test2"},
+					},
+				},
+			}
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.Kubernetes, nro)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			ds1 := &appsv1.DaemonSet{}
+			ds1Key := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, "group1"),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), ds1Key, ds1)).ToNot(HaveOccurred())
+			cnt1 := ds1.Spec.Template.Spec.Containers[0]
+			Expect(cnt1.Image).To(Equal("quay.io/openshift-kni/rte:group"))
+			Expect(cnt1.Args).ToNot(ContainElement("--reload-on-conf-change"))
+			for _, arg := range cnt1.Args {
+				Expect(arg).ToNot(HavePrefix("--debug-server-address"))
+				Expect(arg).ToNot(HavePrefix("--health-server-address"))
+			}
+			Expect(cnt1.LivenessProbe).To(BeNil())
+			Expect(cnt1.ReadinessProbe).To(BeNil())
+			if cnt1.SecurityContext != nil {
+				Expect(cnt1.SecurityContext.RunAsUser).To(BeNil())
+				Expect(cnt1.SecurityContext.RunAsGroup).To(BeNil())
+			}
+
+			ds2 := &appsv1.DaemonSet{}
+			ds2Key := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, "group2"),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), ds2Key, ds2)).ToNot(HaveOccurred())
+			cnt2 := ds2.Spec.Template.Spec.Containers[0]
+			Expect(cnt2.Image).To(Equal(testImageSpec))
+			Expect(cnt2.Args).To(ContainElements("--reload-on-conf-change", "--debug-server-address=127.0.0.1:2114", "--health-server-address=:2115"))
+			Expect(cnt2.LivenessProbe).ToNot(BeNil())
+			Expect(cnt2.ReadinessProbe).ToNot(BeNil())
+			Expect(cnt2.SecurityContext).ToNot(BeNil())
+			Expect(cnt2.SecurityContext.RunAsUser).ToNot(BeNil())
+			Expect(*cnt2.SecurityContext.RunAsUser).To(BeZero())
+		})
+	})
+
+	Context("with node selector NodeGroups on kubernetes", func() {
+		It("should create and remove the daemonsets without machine config pools", func() {
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, nil)
+			nro.Spec.NodeGroups = []nrov1alpha1.NodeGroup{
+				{
+					Name: "group1",
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"test1": "test1"},
+					},
+				},
+				{
+					Name: "group2",
+					NodeSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{
+								Key:      "test2",
+								Operator: metav1.LabelSelectorOpExists,
+							},
+						},
+					},
+				},
+			}
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.Kubernetes, nro)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Second}))
+
+			ds1 := &appsv1.DaemonSet{}
+			ds1Key := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, "group1"),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), ds1Key, ds1)).ToNot(HaveOccurred())
+			Expect(ds1.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"test1": "test1"}))
+			// the built-in exporter image reloads the config in place
+			Expect(ds1.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--reload-on-conf-change"))
+			// and serves the health checks the probes use
+			Expect(ds1.Spec.Template.Spec.Containers[0].Args).To(ContainElements("--debug-server-address=127.0.0.1:2114", "--health-server-address=:2115"))
+			Expect(ds1.Spec.Template.Spec.Containers[0].LivenessProbe).ToNot(BeNil())
+			Expect(ds1.Spec.Template.Spec.Containers[0].LivenessProbe.HTTPGet.Path).To(Equal("/healthz"))
+			Expect(ds1.Spec.Template.Spec.Containers[0].ReadinessProbe).ToNot(BeNil())
+			Expect(ds1.Spec.Template.Spec.Containers[0].ReadinessProbe.HTTPGet.Path).To(Equal("/readyz"))
+			// the probes target a port the container exposes, next to the metrics one
+			var portNames []string
+			for _, port := range ds1.Spec.Template.Spec.Containers[0].Ports {
+				portNames = append(portNames, port.Name)
+			}
+			Expect(portNames).To(ContainElement(ds1.Spec.Template.Spec.Containers[0].LivenessProbe.HTTPGet.Port.String()))
+			Expect(portNames).To(ContainElement(ds1.Spec.Template.Spec.Containers[0].ReadinessProbe.HTTPGet.Port.String()))
+			Expect(portNames).To(ContainElement("metrics-port"))
+
+			ds2 := &appsv1.DaemonSet{}
+			ds2Key := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, "group2"),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), ds2Key, ds2)).ToNot(HaveOccurred())
+			Expect(ds2.Spec.Template.Spec.Affinity).ToNot(BeNil())
+			terms := ds2.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+			Expect(terms).To(HaveLen(1))
+			Expect(terms[0].MatchExpressions).To(Equal([]corev1.NodeSelectorRequirement{
+				{
+					Key:      "test2",
+					Operator: corev1.NodeSelectorOpExists,
+				},
+			}))
+
+			By("removing the second node group")
+			Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+			nro.Spec.NodeGroups = nro.Spec.NodeGroups[:1]
+			Expect(reconciler.Client.Update(context.TODO(), nro)).ToNot(HaveOccurred())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Client.Get(context.TODO(), ds1Key, ds1)).ToNot(HaveOccurred())
+			err = reconciler.Client.Get(context.TODO(), ds2Key, ds2)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "DaemonSet %s should be deleted", ds2Key)
+		})
+
+		It("should reject machine config pool selectors", func() {
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: map[string]string{"test": "test"}},
+			})
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.Kubernetes, nro)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+			degradedCondition := getConditionByType(nro.Status.Conditions, status.ConditionDegraded)
+			Expect(degradedCondition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degradedCondition.Reason).To(Equal(validation.NodeGroupsError))
+		})
+	})
+
+	Context("with the exporter metrics", func() {
+		getServiceMonitor := func(reconciler *NUMAResourcesOperatorReconciler, key client.ObjectKey) (*unstructured.Unstructured, error) {
+			sm := rte.NewServiceMonitor()
+			err := reconciler.Client.Get(context.TODO(), key, sm)
+			return sm, err
+		}
+
+		It("should expose the metrics over TLS on OpenShift until disabled", func() {
+			label1 := map[string]string{"test1": "test1"}
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			})
+			mcp1 := testutils.NewMachineConfigPool("test1", label1, &metav1.LabelSelector{MatchLabels: label1}, &metav1.LabelSelector{MatchLabels: label1})
+			mcp1.Status.Configuration.Source = []corev1.ObjectReference{
+				{
+					Name: objectnames.GetMachineConfigName(nro.Name, mcp1.Name),
+				},
+			}
+			mcp1.Status.Conditions = []machineconfigv1.MachineConfigPoolCondition{
+				{
+					Type:   machineconfigv1.MachineConfigPoolUpdated,
+					Status: corev1.ConditionTrue,
+				},
+			}
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.OpenShift, nro, mcp1)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			dsKey := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, mcp1.Name),
+				Namespace: testNamespace,
+			}
+			ds := &appsv1.DaemonSet{}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, ds)).ToNot(HaveOccurred())
+			Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(rte.DaemonSetNameLabelKey, dsKey.Name))
+			cnt := ds.Spec.Template.Spec.Containers[0]
+			Expect(cnt.Args).To(ContainElements(
+				"--metrics-tls-port=2113",
+				"--metrics-tls-cert=/etc/secrets/rte-metrics/tls.crt",
+				"--metrics-tls-key=/etc/secrets/rte-metrics/tls.key",
+			))
+			Expect(cnt.Env).To(ContainElement(corev1.EnvVar{Name: "METRICS_PORT", Value: "2112"}))
+			Expect(ds.Spec.Template.Spec.Volumes).To(ContainElement(corev1.Volume{
+				Name: "metrics-tls",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: rte.GetMetricsTLSSecretName(dsKey.Name),
+					},
+				},
+			}))
+
+			svc := &corev1.Service{}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, svc)).ToNot(HaveOccurred())
+			Expect(svc.Annotations).To(HaveKeyWithValue("service.beta.openshift.io/serving-cert-secret-name", rte.GetMetricsTLSSecretName(dsKey.Name)))
+			Expect(svc.Spec.Selector).To(HaveKeyWithValue(rte.DaemonSetNameLabelKey, dsKey.Name))
+			Expect(svc.Spec.Ports).To(HaveLen(1))
+			Expect(svc.Spec.Ports[0].Port).To(BeEquivalentTo(rte.MetricsTLSPort))
+
+			sm, err := getServiceMonitor(reconciler, dsKey)
+			Expect(err).ToNot(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoints).To(HaveLen(1))
+			Expect(endpoints[0]).To(HaveKeyWithValue("scheme", "https"))
+			Expect(metav1.IsControlledBy(sm, nro)).To(BeTrue())
+
+			rbacKey := client.ObjectKey{
+				Name:      rte.MetricsPrometheusRBACName,
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), rbacKey, &rbacv1.Role{})).ToNot(HaveOccurred())
+			rb := &rbacv1.RoleBinding{}
+			Expect(reconciler.Client.Get(context.TODO(), rbacKey, rb)).ToNot(HaveOccurred())
+			Expect(rb.Subjects).To(ContainElement(rbacv1.Subject{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      "prometheus-k8s",
+				Namespace: "openshift-monitoring",
+			}))
+
+			By("disabling the exporter metrics")
+			Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+			nro.Spec.DisableExporterMetrics = true
+			Expect(reconciler.Client.Update(context.TODO(), nro)).ToNot(HaveOccurred())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = reconciler.Client.Get(context.TODO(), dsKey, &corev1.Service{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "Service %s should be deleted", dsKey)
+			_, err = getServiceMonitor(reconciler, dsKey)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "ServiceMonitor %s should be deleted", dsKey)
+			err = reconciler.Client.Get(context.TODO(), rbacKey, &rbacv1.Role{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "Role %s should be deleted", rbacKey)
+			err = reconciler.Client.Get(context.TODO(), rbacKey, &rbacv1.RoleBinding{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "RoleBinding %s should be deleted", rbacKey)
+
+			ds = &appsv1.DaemonSet{}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, ds)).ToNot(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].Args).ToNot(ContainElement("--metrics-tls-port=2113"))
+			for _, vol := range ds.Spec.Template.Spec.Volumes {
+				Expect(vol.Name).ToNot(Equal("metrics-tls"))
+			}
+		})
+
+		It("should expose the metrics over TLS on OpenShift only with the built-in image", func() {
+			label1 := map[string]string{"test1": "test1"}
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			})
+			mcp1 := testutils.NewMachineConfigPool("test1", label1, &metav1.LabelSelector{MatchLabels: label1}, &metav1.LabelSelector{MatchLabels: label1})
+			mcp1.Status.Configuration.Source = []corev1.ObjectReference{
+				{
+					Name: objectnames.GetMachineConfigName(nro.Name, mcp1.Name),
+				},
+			}
+			mcp1.Status.Conditions = []machineconfigv1.MachineConfigPoolCondition{
+				{
+					Type:   machineconfigv1.MachineConfigPoolUpdated,
+					Status: corev1.ConditionTrue,
+				},
+			}
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.OpenShift, nro, mcp1)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			dsKey := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, mcp1.Name),
+				Namespace: testNamespace,
+			}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, &corev1.Service{})).ToNot(HaveOccurred())
+
+			By("setting a user-provided exporter image")
+			Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+			nro.Spec.ExporterImage = "quay.io/openshift-kni/rte:user"
+			Expect(reconciler.Client.Update(context.TODO(), nro)).ToNot(HaveOccurred())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = reconciler.Client.Get(context.TODO(), dsKey, &corev1.Service{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "Service %s should be deleted", dsKey)
+			_, err = getServiceMonitor(reconciler, dsKey)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "ServiceMonitor %s should be deleted", dsKey)
+
+			ds := &appsv1.DaemonSet{}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, ds)).ToNot(HaveOccurred())
+			cnt := ds.Spec.Template.Spec.Containers[0]
+			Expect(cnt.Image).To(Equal("quay.io/openshift-kni/rte:user"))
+			for _, arg := range cnt.Args {
+				Expect(arg).ToNot(HavePrefix("--metrics-tls"))
+			}
+			Expect(cnt.Env).To(ContainElement(corev1.EnvVar{Name: "METRICS_PORT", Value: "2112"}))
+			for _, port := range cnt.Ports {
+				Expect(port.ContainerPort).ToNot(BeEquivalentTo(rte.MetricsTLSPort))
+			}
+			for _, vol := range ds.Spec.Template.Spec.Volumes {
+				Expect(vol.Name).ToNot(Equal("metrics-tls"))
+			}
+		})
+
+		It("should expose the plain metrics on kubernetes", func() {
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, nil)
+			nro.Spec.NodeGroups = []nrov1alpha1.NodeGroup{
+				{
+					Name: "group1",
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"test1": "test1"},
+					},
+				},
+			}
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.Kubernetes, nro)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+
+			dsKey := client.ObjectKey{
+				Name:      objectnames.GetComponentName(nro.Name, "group1"),
+				Namespace: testNamespace,
+			}
+			ds := &appsv1.DaemonSet{}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, ds)).ToNot(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].Args).ToNot(ContainElement("--metrics-tls-port=2113"))
+
+			svc := &corev1.Service{}
+			Expect(reconciler.Client.Get(context.TODO(), dsKey, svc)).ToNot(HaveOccurred())
+			Expect(svc.Annotations).To(BeEmpty())
+			Expect(svc.Spec.Ports).To(HaveLen(1))
+			Expect(svc.Spec.Ports[0].Port).To(BeEquivalentTo(rte.MetricsPort))
+
+			sm, err := getServiceMonitor(reconciler, dsKey)
+			Expect(err).ToNot(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoints).To(HaveLen(1))
+			Expect(endpoints[0]).To(HaveKeyWithValue("scheme", "http"))
+		})
+	})
+
+	Context("with paused NRO", func() {
+		It("should only report the drift until resumed", func() {
+			label1 := map[string]string{"test1": "test1"}
+			nro := testutils.NewNUMAResourcesOperator(defaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			})
+			nro.Spec.Paused = true
+			mcp1 := testutils.NewMachineConfigPool("test1", label1, &metav1.LabelSelector{MatchLabels: label1}, &metav1.LabelSelector{MatchLabels: label1})
+
+			reconciler, err := NewFakeNUMAResourcesOperatorReconciler(platform.OpenShift, nro, mcp1)
+			Expect(err).ToNot(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nro)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: numaResourcesRetryPeriod}))
+
+			mcName := objectnames.GetMachineConfigName(nro.Name, mcp1.Name)
+			err = reconciler.Client.Get(context.TODO(), client.ObjectKey{Name: mcName}, &machineconfigv1.MachineConfig{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+			Expect(nro.Finalizers).To(BeEmpty())
+			Expect(nro.Status.MachineConfigPools).To(HaveLen(1))
+			pausedCondition := getConditionByType(nro.Status.Conditions, status.ConditionPaused)
+			Expect(pausedCondition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(pausedCondition.Reason).To(Equal(status.ReasonPausedByUser))
+			Expect(pausedCondition.Message).To(ContainSubstring("MachineConfig " + mcName))
+
+			By("resuming the reconciliation")
+			nro.Spec.Paused = false
+			Expect(reconciler.Client.Update(context.TODO(), nro)).ToNot(HaveOccurred())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reconciler.Client.Get(context.TODO(), client.ObjectKey{Name: mcName}, &machineconfigv1.MachineConfig{})).ToNot(HaveOccurred())
+
+			Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+			pausedCondition = getConditionByType(nro.Status.Conditions, status.ConditionPaused)
+			Expect(pausedCondition.Status).To(Equal(metav1.ConditionFalse))
+		})
+	})
+
 	Context("with correct NRO CR", func() {
 		var nro *nrov1alpha1.NUMAResourcesOperator
 		var mcp1 *machineconfigv1.MachineConfigPool
@@ -379,8 +866,23 @@ var _ = Describe("Test NUMAResourcesOperator Reconcile", func() {
 
 						key := client.ObjectKeyFromObject(nro)
 						Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
-						Expect(len(nro.Status.MachineConfigPools)).To(Equal(1))
+						progressingCondition := getConditionByType(nro.Status.Conditions, status.ConditionProgressing)
+						Expect(progressingCondition.Status).To(Equal(metav1.ConditionTrue))
+						Expect(progressingCondition.Reason).To(Equal(status.ReasonMachineConfigPending))
+
+						Expect(len(nro.Status.MachineConfigPools)).To(Equal(2))
 						Expect(nro.Status.MachineConfigPools[0].Name).To(Equal("test1"))
+						Expect(nro.Status.MachineConfigPools[1].Name).To(Equal("test2"))
+
+						Expect(len(nro.Status.NodeGroups)).To(Equal(2))
+						for i, mcp := range []*machineconfigv1.MachineConfigPool{mcp1, mcp2} {
+							ngStatus := nro.Status.NodeGroups[i]
+							Expect(ngStatus.Name).To(Equal(mcp.Name))
+							Expect(ngStatus.MachineConfigPool).To(Equal(mcp.Name))
+							Expect(ngStatus.MachineConfig).To(Equal(objectnames.GetMachineConfigName(nro.Name, mcp.Name)))
+							Expect(ngStatus.MachineConfigState).To(Equal(nrov1alpha1.MachineConfigStatePending))
+							Expect(ngStatus.DaemonSet.Name).To(BeEmpty())
+						}
 					})
 				})
 
@@ -468,10 +970,127 @@ var _ = Describe("Test NUMAResourcesOperator Reconcile", func() {
 							Namespace: testNamespace,
 						}
 						Expect(reconciler.Client.Get(context.TODO(), mcp2DSKey, ds)).ToNot(HaveOccurred())
+
+						By("Check the node groups status reports the daemon sets")
+						Expect(reconciler.Client.Get(context.TODO(), client.ObjectKeyFromObject(nro), nro)).ToNot(HaveOccurred())
+						Expect(len(nro.Status.NodeGroups)).To(Equal(2))
+						for i, dsKey := range []client.ObjectKey{mcp1DSKey, mcp2DSKey} {
+							ngStatus := nro.Status.NodeGroups[i]
+							Expect(ngStatus.MachineConfigState).To(Equal(nrov1alpha1.MachineConfigStateUpdated))
+							Expect(ngStatus.DaemonSet).To(Equal(nrov1alpha1.NamespacedName{Namespace: dsKey.Namespace, Name: dsKey.Name}))
+						}
 					})
 
 				})
 			})
+			Context("on deletion", func() {
+				setMachineConfigPoolSources := func(mcp *machineconfigv1.MachineConfigPool, sources []corev1.ObjectReference) {
+					Expect(reconciler.Client.Get(context.TODO(), client.ObjectKeyFromObject(mcp), mcp)).ToNot(HaveOccurred())
+					mcp.Status.Configuration.Source = sources
+					mcp.Status.Conditions = []machineconfigv1.MachineConfigPoolCondition{
+						{
+							Type:   machineconfigv1.MachineConfigPoolUpdated,
+							Status: corev1.ConditionTrue,
+						},
+					}
+					Expect(reconciler.Client.Status().Update(context.TODO(), mcp)).ToNot(HaveOccurred())
+				}
+
+				BeforeEach(func() {
+					key := client.ObjectKeyFromObject(nro)
+					_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+					Expect(err).ToNot(HaveOccurred())
+
+					for _, mcp := range []*machineconfigv1.MachineConfigPool{mcp1, mcp2} {
+						setMachineConfigPoolSources(mcp, []corev1.ObjectReference{
+							{
+								Name: objectnames.GetMachineConfigName(nro.Name, mcp.Name),
+							},
+						})
+					}
+					_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+					Expect(nro.Finalizers).To(ContainElement(numaResourcesOperatorFinalizer))
+					Expect(reconciler.Client.Delete(context.TODO(), nro)).ToNot(HaveOccurred())
+				})
+
+				It("should remove the machine configs and wait for the machine config pools before removing the other objects", func() {
+					key := client.ObjectKeyFromObject(nro)
+					result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: numaResourcesRetryPeriod}))
+
+					for _, mcp := range []*machineconfigv1.MachineConfigPool{mcp1, mcp2} {
+						mcKey := client.ObjectKey{
+							Name: objectnames.GetMachineConfigName(nro.Name, mcp.Name),
+						}
+						err := reconciler.Client.Get(context.TODO(), mcKey, &machineconfigv1.MachineConfig{})
+						Expect(apierrors.IsNotFound(err)).To(BeTrue())
+					}
+
+					Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+					progressingCondition := getConditionByType(nro.Status.Conditions, status.ConditionProgressing)
+					Expect(progressingCondition.Status).To(Equal(metav1.ConditionTrue))
+					Expect(progressingCondition.Reason).To(Equal(status.ReasonMachineConfigRemovalPending))
+
+					dsKey := client.ObjectKey{
+						Name:      objectnames.GetComponentName(nro.Name, mcp1.Name),
+						Namespace: testNamespace,
+					}
+					Expect(reconciler.Client.Get(context.TODO(), dsKey, &appsv1.DaemonSet{})).ToNot(HaveOccurred())
+
+					By("Rolling back the machine configs")
+					for _, mcp := range []*machineconfigv1.MachineConfigPool{mcp1, mcp2} {
+						setMachineConfigPoolSources(mcp, nil)
+					}
+					result, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{}))
+
+					err = reconciler.Client.Get(context.TODO(), dsKey, &appsv1.DaemonSet{})
+					Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+					err = reconciler.Client.Get(context.TODO(), client.ObjectKey{Name: "rte"}, &rbacv1.ClusterRole{})
+					Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+					err = reconciler.Client.Get(context.TODO(), client.ObjectKey{Name: "resource-topology-exporter"}, &securityv1.SecurityContextConstraints{})
+					Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+					// the CRD is kept by default
+					crdKey := client.ObjectKey{
+						Name: "noderesourcetopologies.topology.node.k8s.io",
+					}
+					Expect(reconciler.Client.Get(context.TODO(), crdKey, &apiextensionsv1.CustomResourceDefinition{})).ToNot(HaveOccurred())
+
+					err = reconciler.Client.Get(context.TODO(), key, nro)
+					Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				})
+
+				It("should tear down even if paused", func() {
+					key := client.ObjectKeyFromObject(nro)
+					Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+					nro.Spec.Paused = true
+					Expect(reconciler.Client.Update(context.TODO(), nro)).ToNot(HaveOccurred())
+
+					result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(result).To(Equal(reconcile.Result{RequeueAfter: numaResourcesRetryPeriod}))
+
+					for _, mcp := range []*machineconfigv1.MachineConfigPool{mcp1, mcp2} {
+						mcKey := client.ObjectKey{
+							Name: objectnames.GetMachineConfigName(nro.Name, mcp.Name),
+						}
+						err := reconciler.Client.Get(context.TODO(), mcKey, &machineconfigv1.MachineConfig{})
+						Expect(apierrors.IsNotFound(err)).To(BeTrue())
+					}
+
+					Expect(reconciler.Client.Get(context.TODO(), key, nro)).ToNot(HaveOccurred())
+					progressingCondition := getConditionByType(nro.Status.Conditions, status.ConditionProgressing)
+					Expect(progressingCondition.Reason).To(Equal(status.ReasonMachineConfigRemovalPending))
+				})
+			})
 		})
 
 		Context("with machine config pool with complex machine config selector", func() {
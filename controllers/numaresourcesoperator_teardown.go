@@ -0,0 +1,189 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	machineconfigv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
+	"github.com/openshift-kni/numaresources-operator/pkg/status"
+)
+
+// numaResourcesOperatorFinalizer holds the deletion of the NUMAResourcesOperator until
+// the objects the garbage collector does not handle properly are removed
+const numaResourcesOperatorFinalizer = "nodetopology.openshift.io/teardown"
+
+// ensureFinalizer adds the finalizer to the object, so the deletion goes through reconcileDeletion
+func (r *NUMAResourcesOperatorReconciler) ensureFinalizer(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) error {
+	if controllerutil.ContainsFinalizer(instance, numaResourcesOperatorFinalizer) {
+		return nil
+	}
+	controllerutil.AddFinalizer(instance, numaResourcesOperatorFinalizer)
+	return r.Update(ctx, instance)
+}
+
+// reconcileDeletion tears down the deployed objects in reverse order: the machine configs first, waiting for
+// the machine config pools to roll them back, then the RTE daemon sets and the cluster-scoped objects.
+// The owner references are not enough: the garbage collector does not wait for the machine config pools,
+// and does not touch the objects created before the NUMAResourcesOperator, like the shared CRD.
+func (r *NUMAResourcesOperatorReconciler) reconcileDeletion(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(instance, numaResourcesOperatorFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	klog.InfoS("Teardown start", "object", instance.Name)
+
+	if r.Platform == platform.OpenShift {
+		if err := r.deleteMachineConfigs(ctx, instance); err != nil {
+			return r.teardownFailed(ctx, instance, errors.Wrapf(err, "failed to delete the machine configs"))
+		}
+
+		pending, err := r.machineConfigPoolsPendingDeletion(ctx, instance)
+		if err != nil {
+			return r.teardownFailed(ctx, instance, errors.Wrapf(err, "failed to get the machine config pools"))
+		}
+		if len(pending) > 0 {
+			message := fmt.Sprintf("waiting for the machine config pools to remove the machine configs: %s", strings.Join(pending, ", "))
+			if _, err := r.updateStatus(ctx, instance, status.ConditionProgressing, status.ReasonMachineConfigRemovalPending, message); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: numaResourcesRetryPeriod}, nil
+		}
+	}
+
+	if err := r.deleteDaemonSets(ctx, instance); err != nil {
+		return r.teardownFailed(ctx, instance, errors.Wrapf(err, "failed to delete the daemon sets"))
+	}
+
+	objs := []client.Object{
+		r.RTEManifests.ServiceAccount,
+		r.RTEManifests.Role,
+		r.RTEManifests.RoleBinding,
+		r.RTEManifests.ClusterRole,
+		r.RTEManifests.ClusterRoleBinding,
+	}
+	if r.RTEManifests.SecurityContextConstraint != nil {
+		objs = append(objs, r.RTEManifests.SecurityContextConstraint)
+	}
+	if instance.Spec.RemoveNodeResourceTopologyAPI {
+		// the NodeResourceTopology objects go away with their CRD
+		objs = append(objs, r.APIManifests.Crd)
+	}
+	for _, obj := range objs {
+		if err := deleteIfExists(ctx, r.Client, obj); err != nil {
+			return r.teardownFailed(ctx, instance, errors.Wrapf(err, "failed to delete %s %q", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName()))
+		}
+	}
+
+	controllerutil.RemoveFinalizer(instance, numaResourcesOperatorFinalizer)
+	if err := r.Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	klog.InfoS("Teardown done", "object", instance.Name)
+	return ctrl.Result{}, nil
+}
+
+func (r *NUMAResourcesOperatorReconciler) teardownFailed(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator, err error) (ctrl.Result, error) {
+	if _, updateErr := r.updateStatus(ctx, instance, status.ConditionDegraded, status.ReasonTeardownFailed, err.Error()); updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+	return ctrl.Result{}, err
+}
+
+func (r *NUMAResourcesOperatorReconciler) deleteMachineConfigs(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) error {
+	var machineConfigList machineconfigv1.MachineConfigList
+	if err := r.List(ctx, &machineConfigList); err != nil {
+		return err
+	}
+
+	for i := range machineConfigList.Items {
+		mc := &machineConfigList.Items[i]
+		if !isOwnedBy(mc.GetObjectMeta(), instance) {
+			continue
+		}
+		if err := deleteIfExists(ctx, r.Client, mc); err != nil {
+			return err
+		}
+		klog.V(3).Infof("Machineconfig [%s] deleted", mc.Name)
+	}
+	return nil
+}
+
+// machineConfigPoolsPendingDeletion returns the names of the machine config pools still rolling back
+// the machine configs. The pools are taken from the status too, because the node groups may no longer
+// select all the pools the machine configs were rolled out on.
+func (r *NUMAResourcesOperatorReconciler) machineConfigPoolsPendingDeletion(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) ([]string, error) {
+	var mcpList machineconfigv1.MachineConfigPoolList
+	if err := r.List(ctx, &mcpList); err != nil {
+		return nil, err
+	}
+
+	rolledOut := sets.NewString()
+	for _, mcpStatus := range instance.Status.MachineConfigPools {
+		rolledOut.Insert(mcpStatus.Name)
+	}
+
+	var pending []string
+	for i := range mcpList.Items {
+		mcp := &mcpList.Items[i]
+		if !rolledOut.Has(mcp.Name) && !isMachineConfigExists(instance.Name, mcp) {
+			continue
+		}
+		if !IsMachineConfigPoolUpdatedAfterDeletion(instance.Name, mcp) {
+			pending = append(pending, mcp.Name)
+		}
+	}
+	return pending, nil
+}
+
+func (r *NUMAResourcesOperatorReconciler) deleteDaemonSets(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) error {
+	var daemonSetList appsv1.DaemonSetList
+	if err := r.List(ctx, &daemonSetList, &client.ListOptions{Namespace: r.Namespace}); err != nil {
+		return err
+	}
+
+	for i := range daemonSetList.Items {
+		ds := &daemonSetList.Items[i]
+		if !isOwnedBy(ds.GetObjectMeta(), instance) {
+			continue
+		}
+		if err := deleteIfExists(ctx, r.Client, ds); err != nil {
+			return err
+		}
+		klog.V(3).Infof("Daemonset [%s] deleted", ds.Name)
+	}
+	return nil
+}
+
+func deleteIfExists(ctx context.Context, cli client.Client, obj client.Object) error {
+	if err := cli.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
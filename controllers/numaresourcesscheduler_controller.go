@@ -19,17 +19,26 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/pkg/errors"
@@ -37,13 +46,15 @@ import (
 	nrsv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
 	"github.com/openshift-kni/numaresources-operator/pkg/apply"
 	"github.com/openshift-kni/numaresources-operator/pkg/loglevel"
+	"github.com/openshift-kni/numaresources-operator/pkg/metrics"
 	schedmanifests "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/manifests/sched"
 	schedstate "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/objectstate/sched"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
 	"github.com/openshift-kni/numaresources-operator/pkg/status"
 )
 
 const (
-	defaultNUMAResourcesSchedulerCrName                      = "numaresourcesscheduler"
+	defaultNUMAResourcesSchedulerCrName                      = objectnames.DefaultNUMAResourcesSchedulerCrName
 	conditionTypeIncorrectNUMAResourcesSchedulerResourceName = "IncorrectNUMAResourcesSchedulerResourceName"
 )
 
@@ -53,13 +64,16 @@ type NUMAResourcesSchedulerReconciler struct {
 	Scheme             *runtime.Scheme
 	SchedulerManifests schedmanifests.Manifests
 	Namespace          string
+	Recorder           record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=*
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=*
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=*
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=*
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=*
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=*
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=nodetopology.openshift.io,resources=numaresourcesschedulers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=nodetopology.openshift.io,resources=numaresourcesschedulers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=nodetopology.openshift.io,resources=numaresourcesschedulers/finalizers,verbs=update
@@ -84,6 +98,7 @@ func (r *NUMAResourcesSchedulerReconciler) Reconcile(ctx context.Context, req ct
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			metrics.DeleteConditions("NUMAResourcesScheduler", req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -95,108 +110,234 @@ func (r *NUMAResourcesSchedulerReconciler) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{}, r.updateStatus(ctx, instance, status.ConditionDegraded, conditionTypeIncorrectNUMAResourcesSchedulerResourceName, message)
 	}
 
-	result, condition, err := r.reconcileResource(ctx, instance)
+	if instance.Spec.Paused {
+		return r.reconcilePaused(ctx, instance)
+	}
+
+	result, condition, reason, err := r.reconcileResource(ctx, instance)
 	if condition != "" {
-		// TODO: use proper reason
-		reason, message := condition, messageFromError(err)
-		if err := r.updateStatus(ctx, instance, condition, reason, message); err != nil {
+		if err := r.updateStatus(ctx, instance, condition, reason, messageFromError(err)); err != nil {
 			klog.InfoS("Failed to update numaresourcesscheduler status", "Desired condition", condition, "error", err)
 		}
 	}
-	return result, err
+	return result, reconcileError(err)
 
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NUMAResourcesSchedulerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// we want to initate reconcile loop only on change under labels or spec of the object.
+	// The objects without a spec, like ConfigMaps and RBAC objects, don't track the generation, so any update counts.
+	p := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !validateUpdateEvent(&e) {
+				return false
+			}
+
+			return e.ObjectNew.GetGeneration() == 0 ||
+				e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration() ||
+				!apiequality.Semantic.DeepEqual(e.ObjectNew.GetLabels(), e.ObjectOld.GetLabels())
+		},
+	}
+
+	// the deployment status feeds the NUMAResourcesScheduler status, so we want to react to the availability changes too
+	dpPredicates := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !validateUpdateEvent(&e) {
+				return false
+			}
+
+			dpOld := e.ObjectOld.(*appsv1.Deployment)
+			dpNew := e.ObjectNew.(*appsv1.Deployment)
+			return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration() ||
+				!apiequality.Semantic.DeepEqual(e.ObjectNew.GetLabels(), e.ObjectOld.GetLabels()) ||
+				isDeploymentStatusChanged(dpOld, dpNew)
+		},
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&nrsv1alpha1.NUMAResourcesScheduler{}).
+		Owns(&corev1.ServiceAccount{}, builder.WithPredicates(p)).
+		Owns(&corev1.ConfigMap{}, builder.WithPredicates(p)).
+		Owns(&rbacv1.ClusterRole{}, builder.WithPredicates(p)).
+		Owns(&rbacv1.ClusterRoleBinding{}, builder.WithPredicates(p)).
+		Owns(&appsv1.Deployment{}, builder.WithPredicates(dpPredicates)).
+		Owns(&policyv1.PodDisruptionBudget{}, builder.WithPredicates(p)).
 		Complete(r)
 }
 
-func (r *NUMAResourcesSchedulerReconciler) reconcileResource(ctx context.Context, instance *nrsv1alpha1.NUMAResourcesScheduler) (reconcile.Result, string, error) {
+// isDeploymentStatusChanged tells if the deployment status changed in the fields reported in the NUMAResourcesScheduler status
+func isDeploymentStatusChanged(dpOld, dpNew *appsv1.Deployment) bool {
+	return isDeploymentAvailable(dpOld) != isDeploymentAvailable(dpNew) ||
+		dpOld.Status.ReadyReplicas != dpNew.Status.ReadyReplicas
+}
+
+func (r *NUMAResourcesSchedulerReconciler) reconcileResource(ctx context.Context, instance *nrsv1alpha1.NUMAResourcesScheduler) (reconcile.Result, string, string, error) {
 	klog.Info("SchedulerSync start")
 
-	deploymentInfo, schedulerName, err := r.syncNUMASchedulerResources(ctx, instance)
+	// the scheduler plugin can't work without the NodeResourceTopology objects, and the operator controller installs their CRD
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := r.Get(ctx, client.ObjectKey{Name: objectnames.NodeResourceTopologyCRDName}, crd); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, "MissingNRTAPI", "NodeResourceTopology CRD %s not found, is the NUMAResourcesOperator deployed?", objectnames.NodeResourceTopologyCRDName)
+			return ctrl.Result{}, status.ConditionDegraded, status.ReasonNRTAPIMissing, errors.Wrapf(err, "missing the node resource topology CRD")
+		}
+		return ctrl.Result{}, status.ConditionDegraded, status.ReasonNRTAPICheckFailed, errors.Wrapf(err, "failed to check the node resource topology CRD")
+	}
+
+	deploymentInfo, schedulerNames, err := r.syncNUMASchedulerResources(ctx, instance)
 	if err != nil {
-		return ctrl.Result{}, status.ConditionDegraded, errors.Wrapf(err, "FailedSchedulerSync")
+		return ctrl.Result{}, status.ConditionDegraded, status.ReasonSchedulerSyncFailed, errors.Wrapf(err, "failed to sync the scheduler")
+	}
+	if len(instance.Status.SchedulerNames) > 0 && !reflect.DeepEqual(instance.Status.SchedulerNames, schedulerNames) {
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, "SchedulerRenamed", "Scheduler profiles renamed from %v to %v", instance.Status.SchedulerNames, schedulerNames)
 	}
 
 	instance.Status.Deployment = nrsv1alpha1.NamespacedName{}
-	ok, err := isDeploymentRunning(ctx, r.Client, deploymentInfo)
-	if err != nil {
-		return ctrl.Result{}, status.ConditionDegraded, err
+	dp := &appsv1.Deployment{}
+	if err := r.Get(ctx, client.ObjectKey(deploymentInfo), dp); err != nil {
+		return ctrl.Result{}, status.ConditionDegraded, status.ReasonDeploymentStatusFailed, err
 	}
-	if !ok {
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, status.ConditionProgressing, nil
+	instance.Status.ReadyReplicas = dp.Status.ReadyReplicas
+	wasAvailable := meta.IsStatusConditionTrue(instance.Status.Conditions, status.ConditionAvailable)
+	if !isDeploymentAvailable(dp) {
+		if wasAvailable {
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, "DeploymentUnavailable", "Scheduler deployment %s/%s is no longer available", deploymentInfo.Namespace, deploymentInfo.Name)
+		}
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, status.ConditionProgressing, status.ReasonDeploymentNotReady, status.ErrResourcesNotReady{Message: fmt.Sprintf("waiting for the deployment %s/%s to be available", deploymentInfo.Namespace, deploymentInfo.Name)}
+	}
+
+	if !wasAvailable {
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, "DeploymentAvailable", "Scheduler deployment %s/%s is available", deploymentInfo.Namespace, deploymentInfo.Name)
 	}
 
 	instance.Status.Deployment = deploymentInfo
-	instance.Status.SchedulerName = schedulerName
+	instance.Status.SchedulerName = ""
+	if len(schedulerNames) > 0 {
+		instance.Status.SchedulerName = schedulerNames[0]
+	}
+	instance.Status.SchedulerNames = schedulerNames
 
-	return ctrl.Result{}, status.ConditionAvailable, nil
+	return ctrl.Result{}, status.ConditionAvailable, status.ReasonAsExpected, nil
 
 }
 
-func isDeploymentRunning(ctx context.Context, c client.Client, key nrsv1alpha1.NamespacedName) (bool, error) {
-	dp := &appsv1.Deployment{}
-	if err := c.Get(ctx, client.ObjectKey(key), dp); err != nil {
-		return false, err
-	}
-
+func isDeploymentAvailable(dp *appsv1.Deployment) bool {
 	for _, cond := range dp.Status.Conditions {
 		if cond.Type == appsv1.DeploymentAvailable {
-			return cond.Status == corev1.ConditionTrue, nil
+			return cond.Status == corev1.ConditionTrue
 		}
 	}
-	return false, nil
+	return false
 }
 
-func (r *NUMAResourcesSchedulerReconciler) syncNUMASchedulerResources(ctx context.Context, instance *nrsv1alpha1.NUMAResourcesScheduler) (nrsv1alpha1.NamespacedName, string, error) {
+func (r *NUMAResourcesSchedulerReconciler) syncNUMASchedulerResources(ctx context.Context, instance *nrsv1alpha1.NUMAResourcesScheduler) (nrsv1alpha1.NamespacedName, []string, error) {
 	var deploymentNName nrsv1alpha1.NamespacedName
-	schedulerName := instance.Spec.SchedulerName
+	var schedulerNames []string
 
-	schedstate.UpdateDeploymentImageSettings(r.SchedulerManifests.Deployment, instance.Spec.SchedulerImage)
-	schedstate.UpdateDeploymentConfigMapSettings(r.SchedulerManifests.Deployment, r.SchedulerManifests.ConfigMap.Name)
-	if schedulerName != "" {
-		err := schedstate.UpdateSchedulerName(r.SchedulerManifests.ConfigMap, instance.Spec.SchedulerName)
-		if err != nil {
-			return nrsv1alpha1.NamespacedName{}, schedulerName, err
-		}
-	}
-	if err := loglevel.UpdatePodSpec(&r.SchedulerManifests.Deployment.Spec.Template.Spec, instance.Spec.LogLevel); err != nil {
-		return deploymentNName, schedulerName, err
+	schedMf, err := r.renderSchedulerManifests(instance)
+	if err != nil {
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, "FailedSchedulerRender", "Failed to render the scheduler configuration: %v", err)
+		return deploymentNName, schedulerNames, err
 	}
 
-	existing := schedstate.FromClient(ctx, r.Client, r.SchedulerManifests)
-	for _, objState := range existing.State(r.SchedulerManifests) {
+	existing := schedstate.FromClient(ctx, r.Client, schedMf)
+	for _, objState := range existing.State(schedMf) {
 		if err := controllerutil.SetControllerReference(instance, objState.Desired, r.Scheme); err != nil {
-			return deploymentNName, schedulerName, errors.Wrapf(err, "Failed to set controller reference to %s %s", objState.Desired.GetNamespace(), objState.Desired.GetName())
+			return deploymentNName, schedulerNames, errors.Wrapf(err, "Failed to set controller reference to %s %s", objState.Desired.GetNamespace(), objState.Desired.GetName())
+		}
+		// the objects are applied on every reconcile, so we report only the actual changes.
+		// The drift is told comparing the objects like ApplyObject does, so it matches what is applied.
+		changed, err := apply.IsObjectDrifted(objState)
+		if err != nil {
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, "FailedSchedulerApply", "Failed to check %s: %v", describeObject(objState.Desired), err)
+			return deploymentNName, schedulerNames, errors.Wrapf(err, "could not check (%s) %s/%s", objState.Desired.GetObjectKind().GroupVersionKind(), objState.Desired.GetNamespace(), objState.Desired.GetName())
 		}
 		obj, err := apply.ApplyObject(ctx, r.Client, objState)
 		if err != nil {
-			return deploymentNName, schedulerName, errors.Wrapf(err, "could not apply (%s) %s/%s", objState.Desired.GetObjectKind().GroupVersionKind(), objState.Desired.GetNamespace(), objState.Desired.GetName())
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, "FailedSchedulerApply", "Failed to apply %s: %v", describeObject(objState.Desired), err)
+			return deploymentNName, schedulerNames, errors.Wrapf(err, "could not apply (%s) %s/%s", objState.Desired.GetObjectKind().GroupVersionKind(), objState.Desired.GetNamespace(), objState.Desired.GetName())
+		}
+
+		if changed {
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, "SuccessfulSchedulerApply", "Applied %s", describeObject(objState.Desired))
 		}
 
 		if nname, ok := schedstate.DeploymentNamespacedNameFromObject(obj); ok {
 			deploymentNName = nname
 		}
-		if schedName, ok := schedstate.SchedulerNameFromObject(obj); ok {
-			schedulerName = schedName
+		if schedNames, ok := schedstate.SchedulerNamesFromObject(obj); ok {
+			schedulerNames = schedNames
 		}
 	}
-	return deploymentNName, schedulerName, nil
+	return deploymentNName, schedulerNames, nil
 }
 
-func (r *NUMAResourcesSchedulerReconciler) updateStatus(ctx context.Context, sched *nrsv1alpha1.NUMAResourcesScheduler, condition string, reason string, message string) error {
-	conditions := status.NewConditions(condition, reason, message)
-	if apiequality.Semantic.DeepEqual(conditions, sched.Status.Conditions) {
-		return nil
+// renderSchedulerManifests applies the settings of the given object to a copy of the scheduler manifests,
+// so the settings the user drops fall back to the defaults
+func (r *NUMAResourcesSchedulerReconciler) renderSchedulerManifests(instance *nrsv1alpha1.NUMAResourcesScheduler) (schedmanifests.Manifests, error) {
+	schedMf := r.SchedulerManifests.Clone()
+	dp := schedMf.Deployment // shortcut
+
+	schedstate.UpdateDeploymentImageSettings(dp, instance.Spec.SchedulerImage)
+	schedstate.UpdateDeploymentConfigMapSettings(dp, schedMf.ConfigMap.Name)
+	schedstate.UpdateDeploymentResources(dp, instance.Spec.Resources)
+	schedstate.UpdateDeploymentPlacementSettings(dp, instance.Spec.NodeSelector, instance.Spec.Affinity, instance.Spec.Tolerations)
+	schedstate.UpdateDeploymentPriorityClassName(dp, instance.Spec.PriorityClassName)
+
+	replicas := int32(1)
+	if instance.Spec.Replicas != nil {
+		replicas = *instance.Spec.Replicas
+	}
+	schedstate.UpdateDeploymentReplicas(dp, replicas)
+	// the leader election is needed only to coordinate multiple replicas
+	if err := schedstate.UpdateSchedulerLeaderElection(schedMf.ConfigMap, replicas > 1, dp.Namespace, dp.Name); err != nil {
+		return schedMf, err
+	}
+
+	if err := schedstate.UpdateSchedulerProfiles(schedMf.ConfigMap, schedstate.ProfilesFromSpec(instance.Spec)); err != nil {
+		return schedMf, err
+	}
+	return schedMf, loglevel.UpdatePodSpec(&dp.Spec.Template.Spec, instance.Spec.LogLevel)
+}
+
+// reconcilePaused reports the objects the reconciliation would create or update once resumed, without touching them
+func (r *NUMAResourcesSchedulerReconciler) reconcilePaused(ctx context.Context, instance *nrsv1alpha1.NUMAResourcesScheduler) (ctrl.Result, error) {
+	klog.InfoS("Reconciliation paused", "object", instance.Name)
+
+	drifted, err := r.schedulerDriftedObjects(ctx, instance)
+	reason, message := status.PausedReasonAndMessage(drifted, err)
+	instance.Status.Conditions = status.UpdatePausedCondition(instance.Status.Conditions, true, reason, message, instance.Generation)
+	if err := r.Client.Status().Update(ctx, instance); err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "could not update status for object %s", client.ObjectKeyFromObject(instance))
 	}
-	sched.Status.Conditions = status.NewConditions(condition, reason, message)
+	metrics.SetConditions("NUMAResourcesScheduler", instance.Name, instance.Status.Conditions)
+	// nothing triggers a new reconcile on drift, so check again periodically
+	return ctrl.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (r *NUMAResourcesSchedulerReconciler) schedulerDriftedObjects(ctx context.Context, instance *nrsv1alpha1.NUMAResourcesScheduler) ([]string, error) {
+	schedMf, err := r.renderSchedulerManifests(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := schedstate.FromClient(ctx, r.Client, schedMf)
+	objStates := existing.State(schedMf)
+	for _, objState := range objStates {
+		if err := controllerutil.SetControllerReference(instance, objState.Desired, r.Scheme); err != nil {
+			return nil, errors.Wrapf(err, "failed to set controller reference to %s %s", objState.Desired.GetNamespace(), objState.Desired.GetName())
+		}
+	}
+	return driftedObjects(objStates)
+}
+
+func (r *NUMAResourcesSchedulerReconciler) updateStatus(ctx context.Context, sched *nrsv1alpha1.NUMAResourcesScheduler, condition string, reason string, message string) error {
+	sched.Status.Conditions = status.UpdateConditions(sched.Status.Conditions, condition, reason, message, sched.Generation)
 
 	if err := r.Client.Status().Update(ctx, sched); err != nil {
 		return errors.Wrapf(err, "could not update status for object %s", client.ObjectKeyFromObject(sched))
 	}
+	metrics.SetConditions("NUMAResourcesScheduler", sched.Name, sched.Status.Conditions)
 	return nil
 }
@@ -24,17 +24,24 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+
 	nrsv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
 	schedmanifests "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/manifests/sched"
 	"github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/objectstate/sched"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
 	"github.com/openshift-kni/numaresources-operator/pkg/status"
 	"github.com/openshift-kni/numaresources-operator/pkg/testutils"
 )
@@ -42,6 +49,15 @@ import (
 const testSchedulerName = "testSchedulerName"
 
 func NewFakeNUMAResourcesSchedulerReconciler(initObjects ...runtime.Object) (*NUMAResourcesSchedulerReconciler, error) {
+	nrtCRD := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: objectnames.NodeResourceTopologyCRDName,
+		},
+	}
+	return newFakeNUMAResourcesSchedulerReconciler(append(initObjects, nrtCRD)...)
+}
+
+func newFakeNUMAResourcesSchedulerReconciler(initObjects ...runtime.Object) (*NUMAResourcesSchedulerReconciler, error) {
 	fakeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(initObjects...).Build()
 	schedMf, err := schedmanifests.GetManifests(testNamespace)
 	if err != nil {
@@ -53,6 +69,7 @@ func NewFakeNUMAResourcesSchedulerReconciler(initObjects ...runtime.Object) (*NU
 		Scheme:             scheme.Scheme,
 		SchedulerManifests: schedMf,
 		Namespace:          testNamespace,
+		Recorder:           record.NewFakeRecorder(bufferSize),
 	}, nil
 }
 
@@ -72,6 +89,29 @@ var _ = ginkgo.Describe("Test NUMAResourcesScheduler Reconcile", func() {
 		gomega.Expect(degradedCondition.Reason).To(gomega.Equal(reason))
 	}
 
+	ginkgo.Context("with deployment status updates", func() {
+		ginkgo.It("should detect the availability and ready replicas changes", func() {
+			dpOld := &appsv1.Deployment{}
+			dpNew := dpOld.DeepCopy()
+			gomega.Expect(isDeploymentStatusChanged(dpOld, dpNew)).To(gomega.BeFalse())
+
+			dpNew.Status.ReadyReplicas = 1
+			gomega.Expect(isDeploymentStatusChanged(dpOld, dpNew)).To(gomega.BeTrue())
+
+			dpNew = dpOld.DeepCopy()
+			dpNew.Status.Conditions = []appsv1.DeploymentCondition{
+				{
+					Type:   appsv1.DeploymentAvailable,
+					Status: corev1.ConditionTrue,
+				},
+			}
+			gomega.Expect(isDeploymentStatusChanged(dpOld, dpNew)).To(gomega.BeTrue())
+
+			dpNew.Status.ObservedGeneration = 2
+			gomega.Expect(isDeploymentStatusChanged(dpNew.DeepCopy(), dpNew)).To(gomega.BeFalse())
+		})
+	})
+
 	ginkgo.Context("with unexpected NRS CR name", func() {
 		ginkgo.It("should updated the CR condition to degraded", func() {
 			nrs := testutils.NewNUMAResourcesScheduler("test", "some/url:latest", testSchedulerName)
@@ -79,6 +119,26 @@ var _ = ginkgo.Describe("Test NUMAResourcesScheduler Reconcile", func() {
 		})
 	})
 
+	ginkgo.Context("without the NRT API", func() {
+		ginkgo.It("should updated the CR condition to degraded", func() {
+			nrs := testutils.NewNUMAResourcesScheduler("numaresourcesscheduler", "some/url:latest", testSchedulerName)
+			reconciler, err := newFakeNUMAResourcesSchedulerReconciler(nrs)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nrs)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).To(gomega.HaveOccurred())
+
+			gomega.Expect(reconciler.Client.Get(context.TODO(), key, nrs)).ToNot(gomega.HaveOccurred())
+			degradedCondition := getConditionByType(nrs.Status.Conditions, status.ConditionDegraded)
+			gomega.Expect(degradedCondition.Status).To(gomega.Equal(metav1.ConditionTrue))
+			gomega.Expect(degradedCondition.Reason).To(gomega.Equal(status.ReasonNRTAPIMissing))
+
+			recorder := reconciler.Recorder.(*record.FakeRecorder)
+			gomega.Expect(recorder.Events).To(gomega.Receive(gomega.ContainSubstring("MissingNRTAPI")))
+		})
+	})
+
 	ginkgo.Context("with correct NRS CR", func() {
 		var nrs *nrsv1alpha1.NUMAResourcesScheduler
 		var reconciler *NUMAResourcesSchedulerReconciler
@@ -119,6 +179,53 @@ var _ = ginkgo.Describe("Test NUMAResourcesScheduler Reconcile", func() {
 			key.Name = "secondary-scheduler"
 			dp := &appsv1.Deployment{}
 			gomega.Expect(reconciler.Client.Get(context.TODO(), key, dp)).ToNot(gomega.HaveOccurred())
+
+			pdb := &policyv1.PodDisruptionBudget{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), key, pdb)).ToNot(gomega.HaveOccurred())
+
+			recorder := reconciler.Recorder.(*record.FakeRecorder)
+			gomega.Expect(recorder.Events).To(gomega.Receive(gomega.ContainSubstring("SuccessfulSchedulerApply")))
+
+			ginkgo.By("reconciling again without changes")
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(nrs)})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			for len(recorder.Events) > 0 {
+				gomega.Expect(<-recorder.Events).ToNot(gomega.ContainSubstring("SuccessfulSchedulerApply"))
+			}
+		})
+
+		ginkgo.It("should enable the leader election with multiple replicas", func() {
+			replicas := int32(3)
+			nrs.Spec.Replicas = &replicas
+			gomega.Expect(reconciler.Client.Update(context.TODO(), nrs)).ToNot(gomega.HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nrs)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			dpKey := client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      "secondary-scheduler",
+			}
+			dp := &appsv1.Deployment{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), dpKey, dp)).ToNot(gomega.HaveOccurred())
+			gomega.Expect(*dp.Spec.Replicas).To(gomega.Equal(replicas))
+
+			cmKey := client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      "topo-aware-scheduler-config",
+			}
+			cm := &corev1.ConfigMap{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), cmKey, cm)).ToNot(gomega.HaveOccurred())
+			schedCfg, err := manifests.KubeSchedulerConfigurationFromData([]byte(cm.Data[sched.SchedulerConfigFileName]))
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(schedCfg.LeaderElection.LeaderElect).ToNot(gomega.BeNil())
+			gomega.Expect(*schedCfg.LeaderElection.LeaderElect).To(gomega.BeTrue())
+			gomega.Expect(schedCfg.LeaderElection.ResourceNamespace).To(gomega.Equal(dpKey.Namespace))
+			gomega.Expect(schedCfg.LeaderElection.ResourceName).To(gomega.Equal(dpKey.Name))
 		})
 
 		ginkgo.It("should have the correct schedulerName", func() {
@@ -138,5 +245,129 @@ var _ = ginkgo.Describe("Test NUMAResourcesScheduler Reconcile", func() {
 			gomega.Expect(found).To(gomega.BeTrue())
 			gomega.Expect(name).To(gomega.BeEquivalentTo(testSchedulerName))
 		})
+
+		ginkgo.It("should apply the placement settings and restore the defaults once dropped", func() {
+			nrs.Spec.Resources = &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			}
+			nrs.Spec.NodeSelector = map[string]string{"node-role.kubernetes.io/infra": ""}
+			nrs.Spec.Tolerations = []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: corev1.TolerationOpExists,
+				},
+			}
+			nrs.Spec.PriorityClassName = "system-cluster-critical"
+			gomega.Expect(reconciler.Client.Update(context.TODO(), nrs)).ToNot(gomega.HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nrs)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			dpKey := client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      "secondary-scheduler",
+			}
+			dp := &appsv1.Deployment{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), dpKey, dp)).ToNot(gomega.HaveOccurred())
+			podSpec := &dp.Spec.Template.Spec
+			gomega.Expect(podSpec.Containers[0].Resources).To(gomega.Equal(*nrs.Spec.Resources))
+			gomega.Expect(podSpec.NodeSelector).To(gomega.Equal(nrs.Spec.NodeSelector))
+			gomega.Expect(podSpec.Affinity.NodeAffinity).To(gomega.BeNil())
+			gomega.Expect(podSpec.Tolerations).To(gomega.Equal(nrs.Spec.Tolerations))
+			gomega.Expect(podSpec.PriorityClassName).To(gomega.Equal(nrs.Spec.PriorityClassName))
+
+			gomega.Expect(reconciler.Client.Get(context.TODO(), key, nrs)).ToNot(gomega.HaveOccurred())
+			nrs.Spec.Resources = nil
+			nrs.Spec.NodeSelector = nil
+			nrs.Spec.Tolerations = nil
+			nrs.Spec.PriorityClassName = ""
+			gomega.Expect(reconciler.Client.Update(context.TODO(), nrs)).ToNot(gomega.HaveOccurred())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			dp = &appsv1.Deployment{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), dpKey, dp)).ToNot(gomega.HaveOccurred())
+			expectedSpec := &reconciler.SchedulerManifests.Deployment.Spec.Template.Spec
+			podSpec = &dp.Spec.Template.Spec
+			gomega.Expect(podSpec.Containers[0].Resources).To(gomega.Equal(expectedSpec.Containers[0].Resources))
+			gomega.Expect(podSpec.NodeSelector).To(gomega.BeEmpty())
+			gomega.Expect(podSpec.Affinity).To(gomega.Equal(expectedSpec.Affinity))
+			gomega.Expect(podSpec.Tolerations).To(gomega.Equal(expectedSpec.Tolerations))
+			gomega.Expect(podSpec.PriorityClassName).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("should render all the scheduler profiles", func() {
+			nrs.Spec.Profiles = []nrsv1alpha1.SchedulerProfile{
+				{
+					SchedulerName: "packing-scheduler",
+					ScoringStrategy: &nrsv1alpha1.ScoringStrategyParams{
+						Type: nrsv1alpha1.MostAllocated,
+					},
+				},
+			}
+			gomega.Expect(reconciler.Client.Update(context.TODO(), nrs)).ToNot(gomega.HaveOccurred())
+
+			key := client.ObjectKeyFromObject(nrs)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			cmKey := client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      "topo-aware-scheduler-config",
+			}
+			cm := &corev1.ConfigMap{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), cmKey, cm)).ToNot(gomega.HaveOccurred())
+
+			names, found := sched.SchedulerNamesFromObject(cm)
+			gomega.Expect(found).To(gomega.BeTrue())
+			gomega.Expect(names).To(gomega.Equal([]string{testSchedulerName, "packing-scheduler"}))
+		})
+
+		ginkgo.It("should only report the drift while paused", func() {
+			key := client.ObjectKeyFromObject(nrs)
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			gomega.Expect(reconciler.Client.Get(context.TODO(), key, nrs)).ToNot(gomega.HaveOccurred())
+			nrs.Spec.Paused = true
+			nrs.Spec.SchedulerName = "other-scheduler"
+			gomega.Expect(reconciler.Client.Update(context.TODO(), nrs)).ToNot(gomega.HaveOccurred())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			cmKey := client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      "topo-aware-scheduler-config",
+			}
+			cm := &corev1.ConfigMap{}
+			gomega.Expect(reconciler.Client.Get(context.TODO(), cmKey, cm)).ToNot(gomega.HaveOccurred())
+			name, found := sched.SchedulerNameFromObject(cm)
+			gomega.Expect(found).To(gomega.BeTrue())
+			gomega.Expect(name).To(gomega.BeEquivalentTo(testSchedulerName))
+
+			gomega.Expect(reconciler.Client.Get(context.TODO(), key, nrs)).ToNot(gomega.HaveOccurred())
+			pausedCondition := getConditionByType(nrs.Status.Conditions, status.ConditionPaused)
+			gomega.Expect(pausedCondition.Status).To(gomega.Equal(metav1.ConditionTrue))
+			gomega.Expect(pausedCondition.Reason).To(gomega.Equal(status.ReasonPausedByUser))
+			gomega.Expect(pausedCondition.Message).To(gomega.ContainSubstring("ConfigMap " + cmKey.String()))
+
+			nrs.Spec.Paused = false
+			gomega.Expect(reconciler.Client.Update(context.TODO(), nrs)).ToNot(gomega.HaveOccurred())
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			gomega.Expect(reconciler.Client.Get(context.TODO(), cmKey, cm)).ToNot(gomega.HaveOccurred())
+			name, _ = sched.SchedulerNameFromObject(cm)
+			gomega.Expect(name).To(gomega.BeEquivalentTo("other-scheduler"))
+
+			gomega.Expect(reconciler.Client.Get(context.TODO(), key, nrs)).ToNot(gomega.HaveOccurred())
+			pausedCondition = getConditionByType(nrs.Status.Conditions, status.ConditionPaused)
+			gomega.Expect(pausedCondition.Status).To(gomega.Equal(metav1.ConditionFalse))
+		})
 	})
 })
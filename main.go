@@ -21,6 +21,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -37,6 +38,7 @@ import (
 	schedmanifests "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/manifests/sched"
 	securityv1 "github.com/openshift/api/security/v1"
 	machineconfigv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -53,6 +55,7 @@ import (
 	schedstate "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/objectstate/sched"
 	rtestate "github.com/openshift-kni/numaresources-operator/pkg/objectstate/rte"
 	"github.com/openshift-kni/numaresources-operator/pkg/version"
+	"github.com/openshift-kni/numaresources-operator/pkg/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -61,6 +64,12 @@ var (
 
 	defaultImage     = ""
 	defaultNamespace = "numaresources-operator"
+
+	defaultWebhookCertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+)
+
+const (
+	webhookPort = 9443
 )
 
 func init() {
@@ -85,6 +94,8 @@ func main() {
 	var renderImageScheduler string
 	var showVersion bool
 	var enableScheduler bool
+	var enableWebhooks bool
+	var webhookCertDir string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -98,6 +109,8 @@ func main() {
 	flag.StringVar(&renderImageScheduler, "render-image-scheduler", "", "outputs the manifests rendered using the given image for the scheduler")
 	flag.BoolVar(&showVersion, "version", false, "outputs the version and exit")
 	flag.BoolVar(&enableScheduler, "enable-scheduler", false, "enable support for the NUMAResourcesScheduler object")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", true, "enable the validating webhooks, managing their certificates and registration")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", defaultWebhookCertDir, "directory to store the webhook serving certificates into")
 
 	opts := zap.Options{
 		Development: true,
@@ -187,7 +200,8 @@ func main() {
 		Namespace:               namespace,
 		Scheme:                  scheme,
 		MetricsBindAddress:      metricsAddr,
-		Port:                    9443,
+		Port:                    webhookPort,
+		CertDir:                 webhookCertDir,
 		HealthProbeBindAddress:  probeAddr,
 		LeaderElection:          enableLeaderElection,
 		LeaderElectionNamespace: namespace,
@@ -230,6 +244,8 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the scheduler webhooks need to know the scheduler config the reconciler deploys
+	var schedConfigMap *corev1.ConfigMap
 	if enableScheduler {
 		schedMf, err := schedmanifests.GetManifests(namespace)
 		if err != nil {
@@ -241,14 +257,23 @@ func main() {
 		if err = (&controllers.NUMAResourcesSchedulerReconciler{
 			Client:             mgr.GetClient(),
 			Scheme:             mgr.GetScheme(),
+			Recorder:           mgr.GetEventRecorderFor("numaresourcesscheduler-controller"),
 			SchedulerManifests: schedMf,
 		}).SetupWithManager(mgr); err != nil {
 			klog.ErrorS(err, "unable to create controller", "controller", "NUMAResourcesScheduler")
 			os.Exit(1)
 		}
+		schedConfigMap = schedMf.ConfigMap
 	}
 	//+kubebuilder:scaffold:builder
 
+	if enableWebhooks {
+		if err := setupWebhooks(mgr, clusterPlatform, namespace, webhookCertDir, schedConfigMap); err != nil {
+			klog.ErrorS(err, "unable to set up the webhooks")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		klog.ErrorS(err, "unable to set up health check")
 		os.Exit(1)
@@ -265,6 +290,43 @@ func main() {
 	}
 }
 
+func setupWebhooks(mgr ctrl.Manager, plat platform.Platform, namespace, certDir string, schedConfigMap *corev1.ConfigMap) error {
+	podName, ok := os.LookupEnv("PODNAME")
+	if !ok {
+		return fmt.Errorf("environment variable not set: %q", "PODNAME")
+	}
+
+	// the manager cache is not started yet, and we need the certificates before the webhook server starts
+	cli, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	opts := webhook.Options{
+		Namespace:       namespace,
+		PodName:         podName,
+		Port:            webhookPort,
+		CertDir:         certDir,
+		EnableScheduler: schedConfigMap != nil,
+	}
+	if err := webhook.Setup(context.Background(), cli, opts); err != nil {
+		return err
+	}
+	// the webhooks fail closed, so the certificates must be renewed before they expire
+	if err := mgr.Add(webhook.NewCertificatesRotator(cli, opts)); err != nil {
+		return err
+	}
+
+	webhook.Register(mgr, plat)
+	if schedConfigMap != nil {
+		if err := webhook.RegisterScheduler(mgr, schedConfigMap); err != nil {
+			return err
+		}
+	}
+	klog.InfoS("webhooks registered", "namespace", namespace, "service", webhook.ServiceName)
+	return nil
+}
+
 type detectionOutput struct {
 	AutoDetected platform.Platform `json:"autoDetected"`
 	UserSupplied platform.Platform `json:"userSupplied"`
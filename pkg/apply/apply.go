@@ -24,6 +24,7 @@ import (
 	"k8s.io/klog/v2"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/openshift-kni/numaresources-operator/pkg/metrics"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectstate"
 )
 
@@ -40,6 +41,7 @@ func describeObject(obj k8sclient.Object) (string, error) {
 
 func ApplyObject(ctx context.Context, cli k8sclient.Client, objState objectstate.ObjectState) (k8sclient.Object, error) {
 	objDesc, _ := describeObject(objState.Desired)
+	kind := objState.Desired.GetObjectKind().GroupVersionKind().Kind
 
 	if objState.IsNotFoundError() {
 		klog.InfoS("creating", "object", objDesc)
@@ -48,6 +50,7 @@ func ApplyObject(ctx context.Context, cli k8sclient.Client, objState objectstate
 			return nil, err
 		}
 		klog.InfoS("created", "object", objDesc)
+		metrics.ObjectApplied(kind, metrics.ApplyCreated)
 		return objState.Desired, nil
 	}
 
@@ -66,6 +69,36 @@ func ApplyObject(ctx context.Context, cli k8sclient.Client, objState objectstate
 			return nil, errors.Wrapf(err, "could not update object %s", objDesc)
 		}
 		klog.InfoS("updated", "object", objDesc)
+		metrics.ObjectApplied(kind, metrics.ApplyUpdated)
+		return updated, nil
 	}
+	metrics.ObjectApplied(kind, metrics.ApplyUnchanged)
 	return updated, nil
 }
+
+// IsObjectDrifted tells if ApplyObject would create or update the object, without changing anything.
+// The object is merged and compared exactly like ApplyObject does, so the per-object Compare decides.
+func IsObjectDrifted(objState objectstate.ObjectState) (bool, error) {
+	objDesc, _ := describeObject(objState.Desired)
+
+	if objState.IsNotFoundError() {
+		return true, nil
+	}
+	if objState.Error != nil {
+		return false, errors.Wrapf(objState.Error, "could not get object %s", objDesc)
+	}
+
+	desired, ok := objState.Desired.DeepCopyObject().(k8sclient.Object)
+	if !ok {
+		return false, fmt.Errorf("could not copy object %s", objDesc)
+	}
+	updated, err := objState.Merge(objState.Existing, desired)
+	if err != nil {
+		return false, errors.Wrapf(err, "could not merge object %s with existing", objDesc)
+	}
+	ok, err = objState.Compare(objState.Existing, updated)
+	if err != nil {
+		return false, errors.Wrapf(err, "could not compare object %s with existing", objDesc)
+	}
+	return !ok, nil
+}
@@ -22,8 +22,10 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/google/go-cmp/cmp"
@@ -107,3 +109,90 @@ func TestApplyObject(t *testing.T) {
 		}
 	}
 }
+
+func TestIsObjectDrifted(t *testing.T) {
+	dsExist := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            "test-daemonset",
+			ResourceVersion: "42",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "test-container",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	dsDesired := dsExist.DeepCopy()
+	dsDesired.ResourceVersion = ""
+
+	dsChanged := dsDesired.DeepCopy()
+	dsChanged.Spec.Template.Spec.Containers[0].Name = "new-container-name"
+
+	testCases := []struct {
+		name            string
+		objectState     objectstate.ObjectState
+		expectedDrifted bool
+	}{
+		{
+			name: "missing object",
+			objectState: objectstate.ObjectState{
+				Desired: dsDesired,
+				Error:   apierrors.NewNotFound(appsv1.Resource("daemonsets"), dsDesired.Name),
+			},
+			expectedDrifted: true,
+		},
+		{
+			name: "object differing only in server-set metadata",
+			objectState: objectstate.ObjectState{
+				Existing: dsExist,
+				Desired:  dsDesired,
+				Compare:  compare.Object,
+				Merge:    merge.ObjectForUpdate,
+			},
+		},
+		{
+			name: "object differing as the compare function tells",
+			objectState: objectstate.ObjectState{
+				Existing: dsExist,
+				Desired:  dsChanged,
+				Compare: func(existing, obj client.Object) (bool, error) {
+					// ignores the container names
+					return true, nil
+				},
+				Merge: merge.ObjectForUpdate,
+			},
+		},
+		{
+			name: "changed object",
+			objectState: objectstate.ObjectState{
+				Existing: dsExist,
+				Desired:  dsChanged,
+				Compare:  compare.Object,
+				Merge:    merge.ObjectForUpdate,
+			},
+			expectedDrifted: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		drifted, err := IsObjectDrifted(tc.objectState)
+		if err != nil {
+			t.Errorf("%q failed to check the object with error: %v", tc.name, err)
+		}
+		if drifted != tc.expectedDrifted {
+			t.Errorf("%q expected drifted=%v got %v", tc.name, tc.expectedDrifted, drifted)
+		}
+	}
+
+	if dsDesired.ResourceVersion != "" {
+		t.Errorf("IsObjectDrifted modified the desired object")
+	}
+}
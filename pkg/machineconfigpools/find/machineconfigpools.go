@@ -80,3 +80,26 @@ func MCPBySelector(mcps []*mcov1.MachineConfigPool, sel *metav1.LabelSelector) (
 	}
 	return nil, fmt.Errorf("cannot find MCP related to the selector %v", sel)
 }
+
+// NodeGroupByMCP returns the first node group whose selector matches the given machine config pool
+func NodeGroupByMCP(nodeGroups []nropv1alpha1.NodeGroup, mcp *mcov1.MachineConfigPool) (*nropv1alpha1.NodeGroup, bool) {
+	for idx := range nodeGroups {
+		nodeGroup := &nodeGroups[idx]
+		// handled by validation
+		if nodeGroup.MachineConfigPoolSelector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(nodeGroup.MachineConfigPoolSelector)
+		// handled by validation
+		if err != nil {
+			klog.Errorf("bad node group machine config pool selector %q", nodeGroup.MachineConfigPoolSelector.String())
+			continue
+		}
+
+		if selector.Matches(labels.Set(mcp.Labels)) {
+			return nodeGroup, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "numaresources_operator"
+
+// the outcomes of the object apply
+const (
+	ApplyCreated   = "created"
+	ApplyUpdated   = "updated"
+	ApplyUnchanged = "unchanged"
+)
+
+var (
+	machineConfigPoolWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "machineconfigpool_wait_seconds",
+			Help:      "Time spent waiting for the machine config pools to apply the generated machine configs.",
+			// the rollout reboots the nodes one by one, so it takes from minutes to hours
+			Buckets: prometheus.ExponentialBuckets(60, 2, 10),
+		},
+		[]string{"machineconfigpool"},
+	)
+
+	appliedObjects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "applied_objects_total",
+			Help:      "Number of objects the reconcilers applied, by kind and outcome.",
+		},
+		[]string{"kind", "result"},
+	)
+
+	conditions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "condition",
+			Help:      "Status of the conditions of the reconciled objects, 1 if true, 0 otherwise.",
+		},
+		[]string{"kind", "name", "condition"},
+	)
+
+	nodeGroupDaemonSetReadyRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "nodegroup_daemonset_ready_ratio",
+			Help:      "Ratio of the nodes running a ready resource topology exporter pod over the nodes which should run it, per node group.",
+		},
+		[]string{"name", "nodegroup"},
+	)
+
+	kubeletConfigRenderFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kubeletconfig_render_failures_total",
+			Help:      "Number of failures rendering the resource topology exporter config from a kubelet config.",
+		},
+		[]string{"kubeletconfig"},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		machineConfigPoolWaitSeconds,
+		appliedObjects,
+		conditions,
+		nodeGroupDaemonSetReadyRatio,
+		kubeletConfigRenderFailures,
+	)
+}
+
+// mcpWaits tracks when the reconciler started waiting for each machine config pool,
+// and the machine config pools with observed waits, to delete their series once gone
+var mcpWaits = struct {
+	sync.Mutex
+	started  map[string]time.Time
+	observed sets.String
+}{
+	started:  make(map[string]time.Time),
+	observed: sets.NewString(),
+}
+
+// exported tracks the label values of the series about the objects which can go away, by object:
+// the condition types for the conditions, the node group names for the node groups.
+// The vendored client cannot delete the series by partial labels match.
+var exported = struct {
+	sync.Mutex
+	conditions map[string]sets.String
+	nodeGroups map[string]sets.String
+}{
+	conditions: make(map[string]sets.String),
+	nodeGroups: make(map[string]sets.String),
+}
+
+func trackLabelValue(tracked map[string]sets.String, key, value string) {
+	if _, ok := tracked[key]; !ok {
+		tracked[key] = sets.NewString()
+	}
+	tracked[key].Insert(value)
+}
+
+// MachineConfigPoolWaitStarted records the machine config pool is applying the machine config.
+// The calls after the first one, until the wait ends, are ignored.
+func MachineConfigPoolWaitStarted(mcpName string) {
+	mcpWaits.Lock()
+	defer mcpWaits.Unlock()
+	if _, ok := mcpWaits.started[mcpName]; !ok {
+		mcpWaits.started[mcpName] = time.Now()
+	}
+}
+
+// MachineConfigPoolWaitEnded records the machine config pool applied the machine config,
+// observing the time spent waiting since the wait started, if any.
+func MachineConfigPoolWaitEnded(mcpName string) {
+	mcpWaits.Lock()
+	defer mcpWaits.Unlock()
+	started, ok := mcpWaits.started[mcpName]
+	if !ok {
+		return
+	}
+	delete(mcpWaits.started, mcpName)
+	mcpWaits.observed.Insert(mcpName)
+	machineConfigPoolWaitSeconds.WithLabelValues(mcpName).Observe(time.Since(started).Seconds())
+}
+
+// DeleteStaleMachineConfigPools forgets the machine config pools not in the given ones:
+// their ongoing waits are dropped, and their series deleted.
+func DeleteStaleMachineConfigPools(mcpNames []string) {
+	current := sets.NewString(mcpNames...)
+	mcpWaits.Lock()
+	defer mcpWaits.Unlock()
+	for mcpName := range mcpWaits.started {
+		if !current.Has(mcpName) {
+			delete(mcpWaits.started, mcpName)
+		}
+	}
+	for _, mcpName := range mcpWaits.observed.Difference(current).UnsortedList() {
+		mcpWaits.observed.Delete(mcpName)
+		machineConfigPoolWaitSeconds.DeleteLabelValues(mcpName)
+	}
+}
+
+// ObjectApplied counts the object applied by a reconciler
+func ObjectApplied(kind, result string) {
+	appliedObjects.WithLabelValues(kind, result).Inc()
+}
+
+// SetConditions exports the current conditions of the given object
+func SetConditions(kind, name string, conds []metav1.Condition) {
+	exported.Lock()
+	defer exported.Unlock()
+	for _, cond := range conds {
+		value := 0.0
+		if cond.Status == metav1.ConditionTrue {
+			value = 1.0
+		}
+		conditions.WithLabelValues(kind, name, cond.Type).Set(value)
+		trackLabelValue(exported.conditions, kind+"/"+name, cond.Type)
+	}
+}
+
+// DeleteConditions deletes the series of the conditions of the given object, once the object is gone
+func DeleteConditions(kind, name string) {
+	exported.Lock()
+	defer exported.Unlock()
+	key := kind + "/" + name
+	for condType := range exported.conditions[key] {
+		conditions.DeleteLabelValues(kind, name, condType)
+	}
+	delete(exported.conditions, key)
+}
+
+// SetNodeGroupDaemonSetReadyRatio exports the ready ratio of the daemon set of the node group
+func SetNodeGroupDaemonSetReadyRatio(name, nodeGroup string, numberReady, desiredNumberScheduled int32) {
+	ratio := 0.0
+	if desiredNumberScheduled > 0 {
+		ratio = float64(numberReady) / float64(desiredNumberScheduled)
+	}
+	exported.Lock()
+	defer exported.Unlock()
+	nodeGroupDaemonSetReadyRatio.WithLabelValues(name, nodeGroup).Set(ratio)
+	trackLabelValue(exported.nodeGroups, name, nodeGroup)
+}
+
+// DeleteStaleNodeGroups deletes the series of the node groups of the given object not in the given ones.
+// Once the object is gone, no node groups are given.
+func DeleteStaleNodeGroups(name string, nodeGroups []string) {
+	exported.Lock()
+	defer exported.Unlock()
+	tracked, ok := exported.nodeGroups[name]
+	if !ok {
+		return
+	}
+	for _, nodeGroup := range tracked.Difference(sets.NewString(nodeGroups...)).UnsortedList() {
+		tracked.Delete(nodeGroup)
+		nodeGroupDaemonSetReadyRatio.DeleteLabelValues(name, nodeGroup)
+	}
+	if tracked.Len() == 0 {
+		delete(exported.nodeGroups, name)
+	}
+}
+
+// KubeletConfigRenderFailed counts a failure rendering the config from the given kubelet config
+func KubeletConfigRenderFailed(kubeletConfigName string) {
+	kubeletConfigRenderFailures.WithLabelValues(kubeletConfigName).Inc()
+}
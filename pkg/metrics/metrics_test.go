@@ -0,0 +1,165 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMachineConfigPoolWait(t *testing.T) {
+	// start from no machine config pools, regardless of the other tests
+	DeleteStaleMachineConfigPools(nil)
+
+	// the wait was never started, nothing to observe
+	MachineConfigPoolWaitEnded("test-mcp")
+	if got := testutil.CollectAndCount(machineConfigPoolWaitSeconds); got != 0 {
+		t.Fatalf("expected no observation, got %d", got)
+	}
+
+	MachineConfigPoolWaitStarted("test-mcp")
+	MachineConfigPoolWaitStarted("test-mcp")
+	MachineConfigPoolWaitEnded("test-mcp")
+	MachineConfigPoolWaitEnded("test-mcp")
+	if got := testutil.CollectAndCount(machineConfigPoolWaitSeconds); got != 1 {
+		t.Errorf("expected one observed series, got %d", got)
+	}
+}
+
+func TestSetConditions(t *testing.T) {
+	SetConditions("TestKind", "test", []metav1.Condition{
+		{
+			Type:   "Available",
+			Status: metav1.ConditionTrue,
+		},
+		{
+			Type:   "Degraded",
+			Status: metav1.ConditionFalse,
+		},
+	})
+
+	if got := testutil.ToFloat64(conditions.WithLabelValues("TestKind", "test", "Available")); got != 1 {
+		t.Errorf("unexpected Available value: %v", got)
+	}
+	if got := testutil.ToFloat64(conditions.WithLabelValues("TestKind", "test", "Degraded")); got != 0 {
+		t.Errorf("unexpected Degraded value: %v", got)
+	}
+}
+
+func TestSetNodeGroupDaemonSetReadyRatio(t *testing.T) {
+	type testCase struct {
+		name                   string
+		numberReady            int32
+		desiredNumberScheduled int32
+		expected               float64
+	}
+
+	testCases := []testCase{
+		{
+			name:     "no nodes",
+			expected: 0,
+		},
+		{
+			name:                   "partially ready",
+			numberReady:            1,
+			desiredNumberScheduled: 4,
+			expected:               0.25,
+		},
+		{
+			name:                   "ready",
+			numberReady:            3,
+			desiredNumberScheduled: 3,
+			expected:               1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetNodeGroupDaemonSetReadyRatio("test", "worker", tc.numberReady, tc.desiredNumberScheduled)
+			if got := testutil.ToFloat64(nodeGroupDaemonSetReadyRatio.WithLabelValues("test", "worker")); got != tc.expected {
+				t.Errorf("expected ratio %v got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeleteConditions(t *testing.T) {
+	SetConditions("TestKind", "gone", []metav1.Condition{
+		{
+			Type:   "Available",
+			Status: metav1.ConditionTrue,
+		},
+	})
+	SetConditions("TestKind", "kept", []metav1.Condition{
+		{
+			Type:   "Available",
+			Status: metav1.ConditionTrue,
+		},
+	})
+	before := testutil.CollectAndCount(conditions)
+
+	DeleteConditions("TestKind", "gone")
+	if got := testutil.CollectAndCount(conditions); got != before-1 {
+		t.Errorf("expected %d series, got %d", before-1, got)
+	}
+	if got := testutil.ToFloat64(conditions.WithLabelValues("TestKind", "kept", "Available")); got != 1 {
+		t.Errorf("unexpected value of the series kept: %v", got)
+	}
+}
+
+func TestDeleteStaleNodeGroups(t *testing.T) {
+	SetNodeGroupDaemonSetReadyRatio("stale-test", "worker", 1, 1)
+	SetNodeGroupDaemonSetReadyRatio("stale-test", "gone", 1, 1)
+	before := testutil.CollectAndCount(nodeGroupDaemonSetReadyRatio)
+
+	DeleteStaleNodeGroups("stale-test", []string{"worker"})
+	if got := testutil.CollectAndCount(nodeGroupDaemonSetReadyRatio); got != before-1 {
+		t.Errorf("expected %d series after deleting the stale node group, got %d", before-1, got)
+	}
+
+	DeleteStaleNodeGroups("stale-test", nil)
+	if got := testutil.CollectAndCount(nodeGroupDaemonSetReadyRatio); got != before-2 {
+		t.Errorf("expected %d series after deleting the object, got %d", before-2, got)
+	}
+}
+
+func TestDeleteStaleMachineConfigPools(t *testing.T) {
+	// start from no machine config pools, regardless of the other tests
+	DeleteStaleMachineConfigPools(nil)
+
+	MachineConfigPoolWaitStarted("kept-mcp")
+	MachineConfigPoolWaitEnded("kept-mcp")
+	MachineConfigPoolWaitStarted("stale-mcp")
+	MachineConfigPoolWaitEnded("stale-mcp")
+	MachineConfigPoolWaitStarted("pending-mcp")
+	if got := testutil.CollectAndCount(machineConfigPoolWaitSeconds); got != 2 {
+		t.Fatalf("expected 2 series, got %d", got)
+	}
+
+	DeleteStaleMachineConfigPools([]string{"kept-mcp"})
+	if got := testutil.CollectAndCount(machineConfigPoolWaitSeconds); got != 1 {
+		t.Errorf("expected 1 series, got %d", got)
+	}
+
+	// the pending wait is forgotten too, so ending it observes nothing
+	MachineConfigPoolWaitEnded("pending-mcp")
+	if got := testutil.CollectAndCount(machineConfigPoolWaitSeconds); got != 1 {
+		t.Errorf("expected no new observation, got %d series", got)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -117,6 +118,22 @@ func Deployment(namespace string) (*appsv1.Deployment, error) {
 	return dp, nil
 }
 
+func PodDisruptionBudget(namespace string) (*policyv1.PodDisruptionBudget, error) {
+	obj, err := loadObject(filepath.Join("yaml", "poddisruptionbudget.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	pdb, ok := obj.(*policyv1.PodDisruptionBudget)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type, got %t", obj)
+	}
+	if namespace != "" {
+		pdb.Namespace = namespace
+	}
+	return pdb, nil
+}
+
 func deserializeObjectFromData(data []byte) (runtime.Object, error) {
 	decode := scheme.Codecs.UniversalDeserializer().Decode
 	obj, _, err := decode(data, nil, nil)
@@ -39,4 +39,7 @@ func TestLoad(t *testing.T) {
 	if obj, err := Deployment(""); obj == nil || err != nil {
 		t.Errorf("Deployment() failed: err=%v", err)
 	}
+	if obj, err := PodDisruptionBudget(""); obj == nil || err != nil {
+		t.Errorf("PodDisruptionBudget() failed: err=%v", err)
+	}
 }
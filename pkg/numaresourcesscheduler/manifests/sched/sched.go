@@ -19,6 +19,7 @@ package sched
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -32,6 +33,7 @@ type Manifests struct {
 	ClusterRoleBindingK8S *rbacv1.ClusterRoleBinding
 	ClusterRoleBindingNRT *rbacv1.ClusterRoleBinding
 	Deployment            *appsv1.Deployment
+	PodDisruptionBudget   *policyv1.PodDisruptionBudget
 }
 
 func (mf Manifests) ToObjects() []client.Object {
@@ -42,6 +44,7 @@ func (mf Manifests) ToObjects() []client.Object {
 		mf.ClusterRoleBindingK8S,
 		mf.ClusterRoleBindingNRT,
 		mf.Deployment,
+		mf.PodDisruptionBudget,
 	}
 }
 
@@ -53,6 +56,7 @@ func (mf Manifests) Clone() Manifests {
 		ClusterRoleBindingK8S: mf.ClusterRoleBindingK8S.DeepCopy(),
 		ClusterRoleBindingNRT: mf.ClusterRoleBindingNRT.DeepCopy(),
 		Deployment:            mf.Deployment.DeepCopy(),
+		PodDisruptionBudget:   mf.PodDisruptionBudget.DeepCopy(),
 	}
 }
 
@@ -90,5 +94,10 @@ func GetManifests(namespace string) (Manifests, error) {
 		return mf, err
 	}
 
+	mf.PodDisruptionBudget, err = manifests.PodDisruptionBudget(namespace)
+	if err != nil {
+		return mf, err
+	}
+
 	return mf, nil
 }
@@ -18,12 +18,19 @@ package sched
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
+	kubeschedulerconfigv1beta1 "k8s.io/kube-scheduler/config/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
@@ -41,6 +48,12 @@ const (
 	SchedulerPluginName          = "NodeResourceTopologyMatch"
 )
 
+// the NodeResourceTopologyMatch plugin args set from the NUMAResourcesScheduler
+const (
+	pluginArgScoringStrategy          = "scoringStrategy"
+	pluginArgCacheResyncPeriodSeconds = "cacheResyncPeriodSeconds"
+)
+
 type ExistingManifests struct {
 	Existing                   schedmanifests.Manifests
 	serviceAccountError        error
@@ -49,6 +62,7 @@ type ExistingManifests struct {
 	clusterRoleBindingK8SError error
 	clusterRoleBindingNRTError error
 	deploymentError            error
+	podDisruptionBudgetError   error
 }
 
 func (em ExistingManifests) State(mf schedmanifests.Manifests) []objectstate.ObjectState {
@@ -95,6 +109,13 @@ func (em ExistingManifests) State(mf schedmanifests.Manifests) []objectstate.Obj
 			Compare:  compare.Object,
 			Merge:    merge.MetadataForUpdate,
 		},
+		{
+			Existing: em.Existing.PodDisruptionBudget,
+			Error:    em.podDisruptionBudgetError,
+			Desired:  mf.PodDisruptionBudget.DeepCopy(),
+			Compare:  compare.Object,
+			Merge:    merge.MetadataForUpdate,
+		},
 	}
 }
 
@@ -131,6 +152,11 @@ func FromClient(ctx context.Context, cli client.Client, mf schedmanifests.Manife
 	if ret.deploymentError = cli.Get(ctx, client.ObjectKeyFromObject(mf.Deployment), dp); ret.deploymentError == nil {
 		ret.Existing.Deployment = dp
 	}
+
+	pdb := &policyv1.PodDisruptionBudget{}
+	if ret.podDisruptionBudgetError = cli.Get(ctx, client.ObjectKeyFromObject(mf.PodDisruptionBudget), pdb); ret.podDisruptionBudgetError == nil {
+		ret.Existing.PodDisruptionBudget = pdb
+	}
 	return ret
 }
 
@@ -141,6 +167,51 @@ func UpdateDeploymentImageSettings(dp *appsv1.Deployment, userImageSpec string)
 	klog.V(3).InfoS("Exporter image", "reason", "user-provided", "pullSpec", userImageSpec)
 }
 
+// UpdateDeploymentReplicas sets the number of scheduler replicas
+func UpdateDeploymentReplicas(dp *appsv1.Deployment, replicas int32) {
+	dp.Spec.Replicas = &replicas
+}
+
+// UpdateDeploymentResources overrides the compute resources of the scheduler container, if given
+func UpdateDeploymentResources(dp *appsv1.Deployment, resources *corev1.ResourceRequirements) {
+	if resources == nil {
+		return
+	}
+	dp.Spec.Template.Spec.Containers[0].Resources = *resources.DeepCopy()
+}
+
+// UpdateDeploymentPlacementSettings overrides where the scheduler pods run. A node selector replaces
+// the default node affinity, while an explicit affinity replaces the default affinity altogether.
+func UpdateDeploymentPlacementSettings(dp *appsv1.Deployment, nodeSelector map[string]string, affinity *corev1.Affinity, tolerations []corev1.Toleration) {
+	spec := &dp.Spec.Template.Spec // shortcut
+	if len(nodeSelector) > 0 {
+		spec.NodeSelector = make(map[string]string, len(nodeSelector))
+		for key, value := range nodeSelector {
+			spec.NodeSelector[key] = value
+		}
+		if spec.Affinity != nil {
+			spec.Affinity.NodeAffinity = nil
+		}
+	}
+	if affinity != nil {
+		spec.Affinity = affinity.DeepCopy()
+	}
+	if tolerations != nil {
+		spec.Tolerations = make([]corev1.Toleration, len(tolerations))
+		for idx := range tolerations {
+			tolerations[idx].DeepCopyInto(&spec.Tolerations[idx])
+		}
+	}
+}
+
+// UpdateDeploymentPriorityClassName sets the priority class of the scheduler pods, if given
+func UpdateDeploymentPriorityClassName(dp *appsv1.Deployment, priorityClassName string) {
+	if priorityClassName == "" {
+		return
+	}
+	dp.Spec.Template.Spec.PriorityClassName = priorityClassName
+}
+
 func UpdateDeploymentConfigMapSettings(dp *appsv1.Deployment, cmName string) {
 	spec := &dp.Spec.Template.Spec // shortcut
 	spec.Volumes[0] = newSchedConfigVolume(SchedulerConfigMapVolumeName, cmName)
@@ -156,29 +227,50 @@ func DeploymentNamespacedNameFromObject(obj client.Object) (nrsv1alpha1.Namespac
 }
 
 func SchedulerNameFromObject(obj client.Object) (string, bool) {
-	cfg, ok := obj.(*corev1.ConfigMap)
+	names, ok := SchedulerNamesFromObject(obj)
 	if !ok {
 		return "", false
 	}
+	return names[0], true
+}
+
+// SchedulerNamesFromObject returns the names of all the NUMA-aware profiles of the scheduler config
+func SchedulerNamesFromObject(obj client.Object) ([]string, bool) {
+	cfg, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil, false
+	}
 	if cfg.Data == nil {
 		// can this ever happen?
-		return "", false
+		return nil, false
 	}
 	data, ok := cfg.Data[SchedulerConfigFileName]
 	if !ok {
-		return "", false
+		return nil, false
 	}
 	schedCfg, err := manifests.KubeSchedulerConfigurationFromData([]byte(data))
 	if err != nil {
-		return "", false
+		return nil, false
 	}
+	var names []string
 	for _, schedProf := range schedCfg.Profiles {
-		// TODO: actually check this profile refers to a NodeResourceTopologyMatch
-		if schedProf.SchedulerName != nil {
-			return *schedProf.SchedulerName, true
+		if isNUMAAwareProfile(schedProf) && schedProf.SchedulerName != nil {
+			names = append(names, *schedProf.SchedulerName)
 		}
 	}
-	return "", false
+	return names, len(names) > 0
+}
+
+// ProfilesFromSpec returns all the NUMA-aware profiles of the given spec, the one defined by the top-level fields first
+func ProfilesFromSpec(spec nrsv1alpha1.NUMAResourcesSchedulerSpec) []nrsv1alpha1.SchedulerProfile {
+	profiles := []nrsv1alpha1.SchedulerProfile{
+		{
+			SchedulerName:     spec.SchedulerName,
+			ScoringStrategy:   spec.ScoringStrategy,
+			CacheResyncPeriod: spec.CacheResyncPeriod,
+		},
+	}
+	return append(profiles, spec.Profiles...)
 }
 
 func UpdateSchedulerName(cm *corev1.ConfigMap, name string) error {
@@ -186,6 +278,129 @@ func UpdateSchedulerName(cm *corev1.ConfigMap, name string) error {
 		return fmt.Errorf("not allow to set an empty name for scheduler in ConfigMap: %s/%s", cm.Namespace, cm.Name)
 	}
 
+	return updateSchedulerConfig(cm, func(schedCfg *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) error {
+		for i, schedProf := range schedCfg.Profiles {
+			// if we have a configuration for the NodeResourceTopologyMatch
+			// this is a valid profile
+			for _, plugin := range schedProf.PluginConfig {
+				if plugin.Name == SchedulerPluginName {
+					schedCfg.Profiles[i].SchedulerName = &name
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateSchedulerProfiles renders one NUMA-aware profile for each of the given profiles, using the first
+// NUMA-aware profile of the scheduler config as template. The other profiles are left untouched.
+// A profile with an empty name keeps the name of the template profile.
+func UpdateSchedulerProfiles(cm *corev1.ConfigMap, profiles []nrsv1alpha1.SchedulerProfile) error {
+	return updateSchedulerConfig(cm, func(schedCfg *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) error {
+		var tmpl *kubeschedulerconfigv1beta1.KubeSchedulerProfile
+		var others []kubeschedulerconfigv1beta1.KubeSchedulerProfile
+		names := sets.NewString()
+		for i := range schedCfg.Profiles {
+			schedProf := &schedCfg.Profiles[i]
+			if !isNUMAAwareProfile(*schedProf) {
+				others = append(others, *schedProf)
+				if schedProf.SchedulerName != nil {
+					names.Insert(*schedProf.SchedulerName)
+				}
+				continue
+			}
+			if tmpl == nil {
+				tmpl = schedProf
+			}
+		}
+		if tmpl == nil {
+			return fmt.Errorf("no profile using %s found in ConfigMap: %s/%s", SchedulerPluginName, cm.Namespace, cm.Name)
+		}
+
+		var rendered []kubeschedulerconfigv1beta1.KubeSchedulerProfile
+		for _, profile := range profiles {
+			schedProf := tmpl.DeepCopy()
+			if profile.SchedulerName != "" {
+				name := profile.SchedulerName
+				schedProf.SchedulerName = &name
+			}
+			if schedProf.SchedulerName == nil {
+				return fmt.Errorf("not allow to set an empty name for scheduler in ConfigMap: %s/%s", cm.Namespace, cm.Name)
+			}
+			if names.Has(*schedProf.SchedulerName) {
+				return fmt.Errorf("duplicate scheduler profile name %q", *schedProf.SchedulerName)
+			}
+			names.Insert(*schedProf.SchedulerName)
+
+			for j := range schedProf.PluginConfig {
+				pluginConf := &schedProf.PluginConfig[j]
+				if pluginConf.Name != SchedulerPluginName {
+					continue
+				}
+				if err := updatePluginArgs(pluginConf, profile.ScoringStrategy, profile.CacheResyncPeriod); err != nil {
+					return fmt.Errorf("profile %q: %w", *schedProf.SchedulerName, err)
+				}
+			}
+			rendered = append(rendered, *schedProf)
+		}
+
+		schedCfg.Profiles = append(rendered, others...)
+		return nil
+	})
+}
+
+// UpdateSchedulerLeaderElection toggles the leader election among the scheduler replicas.
+// The lease is named after the given object, so it never clashes with the lease of the default scheduler.
+func UpdateSchedulerLeaderElection(cm *corev1.ConfigMap, leaderElect bool, namespace, name string) error {
+	return updateSchedulerConfig(cm, func(schedCfg *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) error {
+		schedCfg.LeaderElection.LeaderElect = &leaderElect
+		schedCfg.LeaderElection.ResourceNamespace = namespace
+		schedCfg.LeaderElection.ResourceName = name
+		return nil
+	})
+}
+
+// isNUMAAwareProfile tells if the profile configures the NodeResourceTopologyMatch plugin
+func isNUMAAwareProfile(schedProf kubeschedulerconfigv1beta1.KubeSchedulerProfile) bool {
+	for _, pluginConf := range schedProf.PluginConfig {
+		if pluginConf.Name == SchedulerPluginName {
+			return true
+		}
+	}
+	return false
+}
+
+func updatePluginArgs(pluginConf *kubeschedulerconfigv1beta1.PluginConfig, scoringStrategy *nrsv1alpha1.ScoringStrategyParams, cacheResyncPeriod *metav1.Duration) error {
+	// the vendored plugin args type lags behind the plugin, so we handle the args as plain data
+	args := map[string]interface{}{}
+	if len(pluginConf.Args.Raw) > 0 {
+		if err := json.Unmarshal(pluginConf.Args.Raw, &args); err != nil {
+			return fmt.Errorf("cannot decode the %s args: %w", pluginConf.Name, err)
+		}
+	}
+
+	delete(args, pluginArgScoringStrategy)
+	if scoringStrategy != nil {
+		args[pluginArgScoringStrategy] = scoringStrategy
+	}
+
+	delete(args, pluginArgCacheResyncPeriodSeconds)
+	if cacheResyncPeriod != nil {
+		if cacheResyncPeriod.Duration < 0 {
+			return fmt.Errorf("invalid negative cache resync period %v", cacheResyncPeriod.Duration)
+		}
+		args[pluginArgCacheResyncPeriodSeconds] = int64(cacheResyncPeriod.Round(time.Second).Seconds())
+	}
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("cannot encode the %s args: %w", pluginConf.Name, err)
+	}
+	pluginConf.Args = runtime.RawExtension{Raw: data}
+	return nil
+}
+
+func updateSchedulerConfig(cm *corev1.ConfigMap, update func(schedCfg *kubeschedulerconfigv1beta1.KubeSchedulerConfiguration) error) error {
 	if cm.Data == nil {
 		return fmt.Errorf("no data found in ConfigMap: %s/%s", cm.Namespace, cm.Name)
 	}
@@ -200,14 +415,8 @@ func UpdateSchedulerName(cm *corev1.ConfigMap, name string) error {
 		return err
 	}
 
-	for i, schedProf := range schedCfg.Profiles {
-		// if we have a configuration for the NodeResourceTopologyMatch
-		// this is a valid profile
-		for _, plugin := range schedProf.PluginConfig {
-			if plugin.Name == SchedulerPluginName {
-				schedCfg.Profiles[i].SchedulerName = &name
-			}
-		}
+	if err := update(schedCfg); err != nil {
+		return err
 	}
 
 	byteData, err := manifests.KubeSchedulerConfigurationToData(schedCfg)
@@ -217,7 +426,6 @@ func UpdateSchedulerName(cm *corev1.ConfigMap, name string) error {
 
 	cm.Data[SchedulerConfigFileName] = string(byteData)
 	return nil
-
 }
 
 func newSchedConfigVolume(schedVolumeConfigName, configMapName string) corev1.Volume {
@@ -17,14 +17,18 @@ limitations under the License.
 package sched
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+
 	nrsv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
 )
 
@@ -277,3 +281,284 @@ func TestUpdateSchedulerName(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateSchedulerProfilesPluginArgs(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cm",
+			Namespace: "test-ns",
+		},
+		Data: map[string]string{
+			"config.yaml": schedConfigOK,
+		},
+	}
+
+	getArgs := func(t *testing.T) map[string]interface{} {
+		schedCfg, err := manifests.KubeSchedulerConfigurationFromData([]byte(cm.Data[SchedulerConfigFileName]))
+		if err != nil {
+			t.Fatalf("failed to decode the scheduler config: %v", err)
+		}
+		args := map[string]interface{}{}
+		if err := json.Unmarshal(schedCfg.Profiles[0].PluginConfig[0].Args.Raw, &args); err != nil {
+			t.Fatalf("failed to decode the plugin args: %v", err)
+		}
+		return args
+	}
+
+	scoringStrategy := &nrsv1alpha1.ScoringStrategyParams{
+		Type: nrsv1alpha1.MostAllocated,
+		Resources: []nrsv1alpha1.ResourceSpecParams{
+			{
+				Name:   "cpu",
+				Weight: 2,
+			},
+		},
+	}
+	cacheResyncPeriod := &metav1.Duration{Duration: 5 * time.Second}
+	profile := nrsv1alpha1.SchedulerProfile{
+		ScoringStrategy:   scoringStrategy,
+		CacheResyncPeriod: cacheResyncPeriod,
+	}
+	if err := UpdateSchedulerProfiles(cm, []nrsv1alpha1.SchedulerProfile{profile}); err != nil {
+		t.Fatalf("failed to update the plugin args: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"kubeconfigpath": "",
+		"scoringStrategy": map[string]interface{}{
+			"type": "MostAllocated",
+			"resources": []interface{}{
+				map[string]interface{}{
+					"name":   "cpu",
+					"weight": float64(2),
+				},
+			},
+		},
+		"cacheResyncPeriodSeconds": float64(5),
+	}
+	if args := getArgs(t); !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected plugin args: %v", args)
+	}
+
+	// the scheduler name update must preserve the args
+	if err := UpdateSchedulerName(cm, "foo"); err != nil {
+		t.Fatalf("failed to update the scheduler name: %v", err)
+	}
+	if args := getArgs(t); !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected plugin args after the scheduler name update: %v", args)
+	}
+
+	if err := UpdateSchedulerProfiles(cm, []nrsv1alpha1.SchedulerProfile{{}}); err != nil {
+		t.Fatalf("failed to reset the plugin args: %v", err)
+	}
+	if args := getArgs(t); !reflect.DeepEqual(args, map[string]interface{}{"kubeconfigpath": ""}) {
+		t.Errorf("unexpected plugin args after the reset: %v", args)
+	}
+
+	if err := UpdateSchedulerProfiles(cm, []nrsv1alpha1.SchedulerProfile{{CacheResyncPeriod: &metav1.Duration{Duration: -time.Second}}}); err == nil {
+		t.Errorf("expected error setting a negative cache resync period")
+	}
+}
+
+func TestUpdateSchedulerLeaderElection(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cm",
+			Namespace: "test-ns",
+		},
+		Data: map[string]string{
+			"config.yaml": schedConfigOK,
+		},
+	}
+
+	if err := UpdateSchedulerLeaderElection(cm, true, "test-ns", "test-lease"); err != nil {
+		t.Fatalf("failed to update the leader election: %v", err)
+	}
+
+	schedCfg, err := manifests.KubeSchedulerConfigurationFromData([]byte(cm.Data[SchedulerConfigFileName]))
+	if err != nil {
+		t.Fatalf("failed to decode the scheduler config: %v", err)
+	}
+	leCfg := schedCfg.LeaderElection
+	if leCfg.LeaderElect == nil || !*leCfg.LeaderElect {
+		t.Errorf("expected leader election enabled, got %v", leCfg.LeaderElect)
+	}
+	if leCfg.ResourceNamespace != "test-ns" || leCfg.ResourceName != "test-lease" {
+		t.Errorf("unexpected lease: %s/%s", leCfg.ResourceNamespace, leCfg.ResourceName)
+	}
+
+	// the scheduler name update must preserve the leader election settings
+	if err := UpdateSchedulerName(cm, "foo"); err != nil {
+		t.Fatalf("failed to update the scheduler name: %v", err)
+	}
+	schedCfg, err = manifests.KubeSchedulerConfigurationFromData([]byte(cm.Data[SchedulerConfigFileName]))
+	if err != nil {
+		t.Fatalf("failed to decode the scheduler config: %v", err)
+	}
+	if schedCfg.LeaderElection.ResourceName != "test-lease" {
+		t.Errorf("lease name lost after the scheduler name update: %q", schedCfg.LeaderElection.ResourceName)
+	}
+}
+
+func TestUpdateDeploymentPlacementSettings(t *testing.T) {
+	defaultAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{
+								Key:      "node-role.kubernetes.io/master",
+								Operator: corev1.NodeSelectorOpExists,
+							},
+						},
+					},
+				},
+			},
+		},
+		PodAntiAffinity: &corev1.PodAntiAffinity{},
+	}
+	defaultTolerations := []corev1.Toleration{
+		{
+			Key:    "node-role.kubernetes.io/master",
+			Effect: corev1.TaintEffectNoSchedule,
+		},
+	}
+	userAffinity := &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{},
+	}
+	userTolerations := []corev1.Toleration{
+		{
+			Key:      "foo",
+			Operator: corev1.TolerationOpExists,
+		},
+	}
+
+	type testCase struct {
+		name                string
+		nodeSelector        map[string]string
+		affinity            *corev1.Affinity
+		tolerations         []corev1.Toleration
+		expectedAffinity    *corev1.Affinity
+		expectedTolerations []corev1.Toleration
+	}
+
+	testCases := []testCase{
+		{
+			name:                "defaults",
+			expectedAffinity:    defaultAffinity,
+			expectedTolerations: defaultTolerations,
+		},
+		{
+			name:         "node selector",
+			nodeSelector: map[string]string{"foo": "bar"},
+			expectedAffinity: &corev1.Affinity{
+				PodAntiAffinity: &corev1.PodAntiAffinity{},
+			},
+			expectedTolerations: defaultTolerations,
+		},
+		{
+			name:                "affinity and tolerations",
+			nodeSelector:        map[string]string{"foo": "bar"},
+			affinity:            userAffinity,
+			tolerations:         userTolerations,
+			expectedAffinity:    userAffinity,
+			expectedTolerations: userTolerations,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dp := &appsv1.Deployment{}
+			dp.Spec.Template.Spec.Affinity = defaultAffinity.DeepCopy()
+			dp.Spec.Template.Spec.Tolerations = append([]corev1.Toleration{}, defaultTolerations...)
+
+			UpdateDeploymentPlacementSettings(dp, tc.nodeSelector, tc.affinity, tc.tolerations)
+
+			podSpec := &dp.Spec.Template.Spec
+			if !reflect.DeepEqual(podSpec.NodeSelector, tc.nodeSelector) {
+				t.Errorf("unexpected node selector: %v", podSpec.NodeSelector)
+			}
+			if !reflect.DeepEqual(podSpec.Affinity, tc.expectedAffinity) {
+				t.Errorf("unexpected affinity: %v", podSpec.Affinity)
+			}
+			if !reflect.DeepEqual(podSpec.Tolerations, tc.expectedTolerations) {
+				t.Errorf("unexpected tolerations: %v", podSpec.Tolerations)
+			}
+		})
+	}
+}
+
+func TestUpdateSchedulerProfiles(t *testing.T) {
+	newConfigMap := func() *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cm",
+				Namespace: "test-ns",
+			},
+			Data: map[string]string{
+				"config.yaml": schedConfigOK,
+			},
+		}
+	}
+
+	cm := newConfigMap()
+	profiles := []nrsv1alpha1.SchedulerProfile{
+		{
+			// keeps the template name
+		},
+		{
+			SchedulerName: "packing-sched",
+			ScoringStrategy: &nrsv1alpha1.ScoringStrategyParams{
+				Type: nrsv1alpha1.MostAllocated,
+			},
+		},
+		{
+			SchedulerName: "spreading-sched",
+			ScoringStrategy: &nrsv1alpha1.ScoringStrategyParams{
+				Type: nrsv1alpha1.LeastAllocated,
+			},
+		},
+	}
+	if err := UpdateSchedulerProfiles(cm, profiles); err != nil {
+		t.Fatalf("failed to update the profiles: %v", err)
+	}
+
+	names, ok := SchedulerNamesFromObject(cm)
+	if !ok {
+		t.Fatalf("failed to find the profile names")
+	}
+	expectedNames := []string{"test-topo-aware-sched", "packing-sched", "spreading-sched"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("unexpected profile names: expected=%v got=%v", expectedNames, names)
+	}
+
+	schedCfg, err := manifests.KubeSchedulerConfigurationFromData([]byte(cm.Data[SchedulerConfigFileName]))
+	if err != nil {
+		t.Fatalf("failed to decode the scheduler config: %v", err)
+	}
+	expectedTypes := []string{"", "MostAllocated", "LeastAllocated"}
+	for idx, schedProf := range schedCfg.Profiles {
+		args := map[string]interface{}{}
+		if err := json.Unmarshal(schedProf.PluginConfig[0].Args.Raw, &args); err != nil {
+			t.Fatalf("failed to decode the plugin args: %v", err)
+		}
+		gotType := ""
+		if scoringStrategy, ok := args["scoringStrategy"].(map[string]interface{}); ok {
+			gotType, _ = scoringStrategy["type"].(string)
+		}
+		if gotType != expectedTypes[idx] {
+			t.Errorf("profile %q: unexpected scoring strategy: expected=%q got=%q", *schedProf.SchedulerName, expectedTypes[idx], gotType)
+		}
+	}
+
+	if err := UpdateSchedulerProfiles(newConfigMap(), []nrsv1alpha1.SchedulerProfile{{SchedulerName: "foo"}, {SchedulerName: "foo"}}); err == nil {
+		t.Errorf("expected error setting duplicate profile names")
+	}
+
+	noProfiles := newConfigMap()
+	noProfiles.Data["config.yaml"] = schedConfigNoProfiles
+	if err := UpdateSchedulerProfiles(noProfiles, profiles); err == nil {
+		t.Errorf("expected error without a template profile")
+	}
+}
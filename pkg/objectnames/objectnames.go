@@ -18,6 +18,15 @@ package objectnames
 
 import "fmt"
 
+const (
+	// DefaultNUMAResourcesOperatorCrName is the name of the only NUMAResourcesOperator object the operator reconciles
+	DefaultNUMAResourcesOperatorCrName = "numaresourcesoperator"
+	// DefaultNUMAResourcesSchedulerCrName is the name of the only NUMAResourcesScheduler object the operator reconciles
+	DefaultNUMAResourcesSchedulerCrName = "numaresourcesscheduler"
+	// NodeResourceTopologyCRDName is the name of the NodeResourceTopology CRD the operator installs and the scheduler consumes
+	NodeResourceTopologyCRDName = "noderesourcetopologies.topology.node.k8s.io"
+)
+
 func GetMachineConfigName(instanceName, mcpName string) string {
 	return fmt.Sprintf("51-%s-%s", instanceName, mcpName)
 }
@@ -42,6 +42,21 @@ func ServiceAccountForUpdate(current, updated client.Object) (client.Object, err
 	return MetadataForUpdate(current, updated)
 }
 
+func ServiceForUpdate(current, updated client.Object) (client.Object, error) {
+	curSvc, ok := current.(*corev1.Service)
+	if !ok {
+		return updated, ErrWrongObjectType
+	}
+	updSvc, ok := updated.(*corev1.Service)
+	if !ok {
+		return updated, ErrMismatchingObjects
+	}
+	// allocated by the apiserver and immutable
+	updSvc.Spec.ClusterIP = curSvc.Spec.ClusterIP
+	updSvc.Spec.ClusterIPs = curSvc.Spec.ClusterIPs
+	return MetadataForUpdate(current, updated)
+}
+
 func ObjectForUpdate(current, updated client.Object) (client.Object, error) {
 	return MetadataForUpdate(current, updated)
 }
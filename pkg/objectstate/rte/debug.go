@@ -0,0 +1,78 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2022 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/openshift-kni/numaresources-operator/pkg/flagcodec"
+)
+
+const (
+	// DebugServerPort is where the exporter serves its state and the health checks over plain HTTP, on localhost only
+	DebugServerPort = 2114
+	// HealthServerPort is where the exporter serves only the health checks over plain HTTP, for the probes
+	HealthServerPort = 2115
+
+	healthServerPortName = "health"
+
+	// the paths of the health checks served by the exporter
+	debugServerHealthzPath = "/healthz"
+	debugServerReadyzPath  = "/readyz"
+)
+
+// UpdateDaemonSetDebugServer makes the exporter serve its debug endpoints on localhost, and the health checks
+// on the pod IP, to probe the exporter liveness and readiness through them. The exporter reports ready once it
+// can talk to the podresources API, and not alive once failing to talk to it for a while.
+func UpdateDaemonSetDebugServer(ds *appsv1.DaemonSet) error {
+	// TODO: better match by name than assume container#0 is RTE proper (not minion)
+	cnt := &ds.Spec.Template.Spec.Containers[0]
+
+	fl := flagcodec.ParseArgvKeyValue(cnt.Args)
+	if fl == nil {
+		return fmt.Errorf("cannot modify the arguments for container %s", cnt.Name)
+	}
+	fl.SetOption("--debug-server-address", fmt.Sprintf("127.0.0.1:%d", DebugServerPort))
+	fl.SetOption("--health-server-address", fmt.Sprintf(":%d", HealthServerPort))
+	cnt.Args = fl.Args()
+
+	cnt.Ports = append(cnt.Ports, corev1.ContainerPort{
+		Name:          healthServerPortName,
+		ContainerPort: HealthServerPort,
+	})
+	cnt.LivenessProbe = newDebugServerProbe(debugServerHealthzPath)
+	cnt.ReadinessProbe = newDebugServerProbe(debugServerReadyzPath)
+	return nil
+}
+
+func newDebugServerProbe(path string) *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: path,
+				Port: intstr.FromString(healthServerPortName),
+			},
+		},
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       10,
+		FailureThreshold:    3,
+	}
+}
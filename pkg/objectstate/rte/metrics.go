@@ -0,0 +1,288 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2022 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
+
+	"github.com/openshift-kni/numaresources-operator/pkg/flagcodec"
+)
+
+const (
+	// DaemonSetNameLabelKey labels the pods with the name of their daemonset, to tell apart the node groups
+	DaemonSetNameLabelKey = "nodetopology.openshift.io/daemonset"
+
+	// MetricsPort is where the exporter serves the metrics over plain HTTP
+	MetricsPort = 2112
+	// MetricsTLSPort is where the exporter serves the metrics over TLS, on OpenShift
+	MetricsTLSPort = 2113
+
+	metricsPortName    = "metrics"
+	metricsTLSPortName = "metrics-tls"
+	// the name manifests.UpdateMetricsPort gives to the container port
+	manifestsMetricsPortName = "metrics-port"
+
+	metricsTLSVolumeName = "metrics-tls"
+	metricsTLSMountPath  = "/etc/secrets/rte-metrics"
+
+	// the OpenShift service CA generates the serving certificate in the secret named by this annotation
+	servingCertSecretAnnotation = "service.beta.openshift.io/serving-cert-secret-name"
+	// the service CA bundle mounted in the prometheus pods by the OpenShift cluster monitoring
+	serviceCABundlePath = "/etc/prometheus/configmaps/serving-certs-ca-bundle/service-ca.crt"
+
+	// MetricsPrometheusRBACName is the name of the role, and of its binding, letting the OpenShift
+	// cluster monitoring discover the metrics targets
+	MetricsPrometheusRBACName = "rte-metrics-prometheus"
+
+	// the service account the OpenShift cluster monitoring prometheus runs as
+	prometheusServiceAccountName      = "prometheus-k8s"
+	prometheusServiceAccountNamespace = "openshift-monitoring"
+)
+
+// ServiceMonitorGVK identifies the prometheus operator ServiceMonitor, which is not always installed
+var ServiceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// GetMetricsTLSSecretName returns the name of the secret holding the serving certificate of the given daemonset
+func GetMetricsTLSSecretName(dsName string) string {
+	return fmt.Sprintf("%s-metrics-tls", dsName)
+}
+
+// NewServiceMonitor returns an empty ServiceMonitor, to be filled by the client
+func NewServiceMonitor() *unstructured.Unstructured {
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(ServiceMonitorGVK)
+	return sm
+}
+
+// UpdateDaemonSetPodLabels labels the daemonset pods with the daemonset name, so the metrics service can select them.
+// The daemonset selector is immutable, so it is left untouched.
+func UpdateDaemonSetPodLabels(ds *appsv1.DaemonSet) {
+	if ds.Spec.Template.Labels == nil {
+		ds.Spec.Template.Labels = map[string]string{}
+	}
+	ds.Spec.Template.Labels[DaemonSetNameLabelKey] = ds.Name
+}
+
+// UpdateDaemonSetMetricsTLS makes the exporter serve the metrics over TLS, using the serving certificate
+// the OpenShift service CA generates for the metrics service.
+func UpdateDaemonSetMetricsTLS(ds *appsv1.DaemonSet) error {
+	podSpec := &ds.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: metricsTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: GetMetricsTLSSecretName(ds.Name),
+			},
+		},
+	})
+
+	// TODO: better match by name than assume container#0 is RTE proper (not minion)
+	cnt := &podSpec.Containers[0]
+	cnt.VolumeMounts = append(cnt.VolumeMounts, corev1.VolumeMount{
+		Name:      metricsTLSVolumeName,
+		MountPath: metricsTLSMountPath,
+		ReadOnly:  true,
+	})
+	cnt.Ports = append(cnt.Ports, corev1.ContainerPort{
+		Name:          metricsTLSPortName,
+		ContainerPort: MetricsTLSPort,
+	})
+
+	fl := flagcodec.ParseArgvKeyValue(cnt.Args)
+	if fl == nil {
+		return fmt.Errorf("cannot modify the arguments for container %s", cnt.Name)
+	}
+	fl.SetOption("--metrics-tls-port", strconv.Itoa(MetricsTLSPort))
+	fl.SetOption("--metrics-tls-cert", filepath.Join(metricsTLSMountPath, corev1.TLSCertKey))
+	fl.SetOption("--metrics-tls-key", filepath.Join(metricsTLSMountPath, corev1.TLSPrivateKeyKey))
+	cnt.Args = fl.Args()
+	return nil
+}
+
+// UpdateDaemonSetMetricsPort sets the port the exporter serves the metrics on over plain HTTP.
+// Unlike manifests.UpdateMetricsPort, which replaces all the container ports, the other ports are kept.
+func UpdateDaemonSetMetricsPort(ds *appsv1.DaemonSet) {
+	// TODO: better match by name than assume container#0 is RTE proper (not minion)
+	cnt := &ds.Spec.Template.Spec.Containers[0]
+	var otherPorts []corev1.ContainerPort
+	for _, port := range cnt.Ports {
+		if port.Name != manifestsMetricsPortName {
+			otherPorts = append(otherPorts, port)
+		}
+	}
+	manifests.UpdateMetricsPort(ds, MetricsPort)
+	cnt.Ports = append(cnt.Ports, otherPorts...)
+}
+
+// UpdateDaemonSetMetrics sets the port the exporter serves the metrics on, and on OpenShift enables TLS.
+func UpdateDaemonSetMetrics(ds *appsv1.DaemonSet, plat platform.Platform) error {
+	UpdateDaemonSetMetricsPort(ds)
+	if plat != platform.OpenShift {
+		return nil
+	}
+	return UpdateDaemonSetMetricsTLS(ds)
+}
+
+// NewMetricsService returns the service exposing the metrics of the pods of the given daemonset.
+// On OpenShift only the TLS port is exposed, and the service CA provides the serving certificate.
+func NewMetricsService(ds *appsv1.DaemonSet, plat platform.Platform) *corev1.Service {
+	selector := map[string]string{
+		DaemonSetNameLabelKey: ds.Name,
+	}
+	for key, value := range ds.Spec.Selector.MatchLabels {
+		selector[key] = value
+	}
+
+	port := corev1.ServicePort{
+		Name:       metricsPortName,
+		Protocol:   corev1.ProtocolTCP,
+		Port:       MetricsPort,
+		TargetPort: intstr.FromInt(MetricsPort),
+	}
+
+	svc := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ds.Name,
+			Namespace: ds.Namespace,
+			Labels: map[string]string{
+				DaemonSetNameLabelKey: ds.Name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:            corev1.ServiceTypeClusterIP,
+			SessionAffinity: corev1.ServiceAffinityNone,
+			Selector:        selector,
+		},
+	}
+
+	if plat == platform.OpenShift {
+		svc.Annotations = map[string]string{
+			servingCertSecretAnnotation: GetMetricsTLSSecretName(ds.Name),
+		}
+		port.Port = MetricsTLSPort
+		port.TargetPort = intstr.FromInt(MetricsTLSPort)
+	}
+
+	svc.Spec.Ports = []corev1.ServicePort{port}
+	return svc
+}
+
+// NewMetricsServiceMonitor returns the ServiceMonitor making prometheus scrape the given metrics service.
+// The prometheus operator API is not vendored, so the object is unstructured.
+func NewMetricsServiceMonitor(svc *corev1.Service, plat platform.Platform) *unstructured.Unstructured {
+	endpoint := map[string]interface{}{
+		"port":   metricsPortName,
+		"path":   "/metrics",
+		"scheme": "http",
+	}
+	if plat == platform.OpenShift {
+		endpoint["scheme"] = "https"
+		endpoint["tlsConfig"] = map[string]interface{}{
+			"caFile":     serviceCABundlePath,
+			"serverName": fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace),
+		}
+	}
+
+	sm := NewServiceMonitor()
+	sm.SetName(svc.Name)
+	sm.SetNamespace(svc.Namespace)
+	sm.SetLabels(map[string]string{
+		DaemonSetNameLabelKey: svc.Name,
+	})
+	sm.Object["spec"] = map[string]interface{}{
+		"endpoints": []interface{}{endpoint},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{svc.Namespace},
+		},
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				DaemonSetNameLabelKey: svc.Name,
+			},
+		},
+	}
+	return sm
+}
+
+// NewMetricsPrometheusRole returns the role the OpenShift cluster monitoring prometheus needs
+// to discover the metrics targets in the given namespace
+func NewMetricsPrometheusRole(namespace string) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MetricsPrometheusRBACName,
+			Namespace: namespace,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"services", "endpoints", "pods"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		},
+	}
+}
+
+// NewMetricsPrometheusRoleBinding returns the binding of the role NewMetricsPrometheusRole returns
+// to the OpenShift cluster monitoring prometheus
+func NewMetricsPrometheusRoleBinding(namespace string) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MetricsPrometheusRBACName,
+			Namespace: namespace,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     MetricsPrometheusRBACName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      prometheusServiceAccountName,
+				Namespace: prometheusServiceAccountNamespace,
+			},
+		},
+	}
+}
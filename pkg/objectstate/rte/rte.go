@@ -19,10 +19,14 @@ package rte
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -34,6 +38,8 @@ import (
 
 	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
 	"github.com/openshift-kni/numaresources-operator/pkg/flagcodec"
+	"github.com/openshift-kni/numaresources-operator/pkg/loglevel"
+	mcpfind "github.com/openshift-kni/numaresources-operator/pkg/machineconfigpools/find"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectstate"
 	"github.com/openshift-kni/numaresources-operator/pkg/objectstate/compare"
@@ -43,11 +49,22 @@ import (
 // MachineConfigLabelKey contains the key of generated label for machine config
 const MachineConfigLabelKey = "machineconfiguration.openshift.io/role"
 
+// GenerateDesiredManifestUpdater customizes the desired DaemonSet generated for the given node group.
+// The node group may be nil if no node group can be matched to the machine config pool.
+type GenerateDesiredManifestUpdater func(nodeGroup *nropv1alpha1.NodeGroup, ds *appsv1.DaemonSet) error
+
 type daemonSetManifest struct {
 	daemonSet      *appsv1.DaemonSet
 	daemonSetError error
 }
 
+type metricsManifest struct {
+	service             *corev1.Service
+	serviceError        error
+	serviceMonitor      *unstructured.Unstructured
+	serviceMonitorError error
+}
+
 type machineConfigManifest struct {
 	machineConfig      *machineconfigv1.MachineConfig
 	machineConfigError error
@@ -56,6 +73,7 @@ type machineConfigManifest struct {
 type ExistingManifests struct {
 	existing                rtemanifests.Manifests
 	daemonSets              map[string]daemonSetManifest
+	metrics                 map[string]metricsManifest
 	machineConfigs          map[string]machineConfigManifest
 	sccError                error
 	serviceAccountError     error
@@ -63,6 +81,11 @@ type ExistingManifests struct {
 	roleBindingError        error
 	clusterRoleError        error
 	clusterRoleBindingError error
+	// the RBAC letting the OpenShift cluster monitoring discover the metrics targets
+	prometheusRole             *rbacv1.Role
+	prometheusRoleError        error
+	prometheusRoleBinding      *rbacv1.RoleBinding
+	prometheusRoleBindingError error
 }
 
 func (em *ExistingManifests) MachineConfigsState(mf rtemanifests.Manifests, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) []objectstate.ObjectState {
@@ -116,7 +139,9 @@ func GetMachineConfigLabel(mcp *machineconfigv1.MachineConfigPool) map[string]st
 	return labels
 }
 
-func (em *ExistingManifests) State(mf rtemanifests.Manifests, plat platform.Platform, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool) []objectstate.ObjectState {
+// State returns the state of the objects of the exporter. An error customizing the desired daemonset
+// of a node group is returned, rather than skipping the daemonset, so the caller can report it.
+func (em *ExistingManifests) State(mf rtemanifests.Manifests, plat platform.Platform, instance *nropv1alpha1.NUMAResourcesOperator, mcps []*machineconfigv1.MachineConfigPool, updater GenerateDesiredManifestUpdater) ([]objectstate.ObjectState, error) {
 	ret := []objectstate.ObjectState{
 		// service account
 		{
@@ -170,12 +195,32 @@ func (em *ExistingManifests) State(mf rtemanifests.Manifests, plat platform.Plat
 		})
 	}
 
+	if plat == platform.OpenShift && !instance.Spec.DisableExporterMetrics {
+		ret = append(ret,
+			objectstate.ObjectState{
+				Existing: em.prometheusRole,
+				Error:    em.prometheusRoleError,
+				Desired:  NewMetricsPrometheusRole(mf.DaemonSet.Namespace),
+				Compare:  compare.Object,
+				Merge:    merge.ObjectForUpdate,
+			},
+			objectstate.ObjectState{
+				Existing: em.prometheusRoleBinding,
+				Error:    em.prometheusRoleBindingError,
+				Desired:  NewMetricsPrometheusRoleBinding(mf.DaemonSet.Namespace),
+				Compare:  compare.Object,
+				Merge:    merge.ObjectForUpdate,
+			},
+		)
+	}
+
 	for _, mcp := range mcps {
 		if mcp.Spec.NodeSelector == nil {
 			klog.Warningf("the machine config pool %q does not have node selector", mcp.Name)
 			continue
 		}
 
+		nodeGroup, _ := mcpfind.NodeGroupByMCP(instance.Spec.NodeGroups, mcp)
 		generatedName := objectnames.GetComponentName(instance.Name, mcp.Name)
 		desiredDaemonSet := mf.DaemonSet.DeepCopy()
 		desiredDaemonSet.Name = generatedName
@@ -194,24 +239,110 @@ func (em *ExistingManifests) State(mf rtemanifests.Manifests, plat platform.Plat
 				generatedName)
 		}
 
-		existingDaemonSet, ok := em.daemonSets[generatedName]
-		if !ok {
-			klog.Warningf("failed to find daemon set %q under the namespace %q", generatedName, desiredDaemonSet.Namespace)
+		var err error
+		ret, err = em.appendNodeGroupState(ret, desiredDaemonSet, plat, instance, nodeGroup, updater)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// node groups selecting the nodes directly, used on platforms without machine config pools
+	for i := range instance.Spec.NodeGroups {
+		nodeGroup := &instance.Spec.NodeGroups[i]
+		if nodeGroup.NodeSelector == nil {
 			continue
 		}
 
-		ret = append(ret,
-			objectstate.ObjectState{
-				Existing: existingDaemonSet.daemonSet,
-				Error:    existingDaemonSet.daemonSetError,
-				Desired:  desiredDaemonSet,
-				Compare:  compare.Object,
-				Merge:    merge.ObjectForUpdate,
-			},
-		)
+		generatedName := objectnames.GetComponentName(instance.Name, nodeGroup.Name)
+		desiredDaemonSet := mf.DaemonSet.DeepCopy()
+		desiredDaemonSet.Name = generatedName
+		UpdateDaemonSetNodeSelector(desiredDaemonSet, nodeGroup.NodeSelector)
+
+		var err error
+		ret, err = em.appendNodeGroupState(ret, desiredDaemonSet, plat, instance, nodeGroup, updater)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return ret
+	return ret, nil
+}
+
+// appendNodeGroupState appends the state of the daemonset of the node group and, unless disabled
+// or the node group runs a user-provided exporter image, of its metrics objects
+func (em *ExistingManifests) appendNodeGroupState(ret []objectstate.ObjectState, desiredDaemonSet *appsv1.DaemonSet, plat platform.Platform, instance *nropv1alpha1.NUMAResourcesOperator, nodeGroup *nropv1alpha1.NodeGroup, updater GenerateDesiredManifestUpdater) ([]objectstate.ObjectState, error) {
+	UpdateDaemonSetPodLabels(desiredDaemonSet)
+	// a user-provided exporter image does not know the TLS flags, so it gets the plain metrics port only
+	if instance.Spec.DisableExporterMetrics || UserExporterImage(instance, nodeGroup) != "" {
+		UpdateDaemonSetMetricsPort(desiredDaemonSet)
+		return em.appendDaemonSetState(ret, desiredDaemonSet, nodeGroup, updater)
+	}
+
+	if err := UpdateDaemonSetMetrics(desiredDaemonSet, plat); err != nil {
+		return nil, fmt.Errorf("failed to update daemon set %q metrics: %w", desiredDaemonSet.Name, err)
+	}
+	ret, err := em.appendDaemonSetState(ret, desiredDaemonSet, nodeGroup, updater)
+	if err != nil {
+		return nil, err
+	}
+	return em.appendMetricsState(ret, desiredDaemonSet, plat), nil
+}
+
+func (em *ExistingManifests) appendMetricsState(ret []objectstate.ObjectState, desiredDaemonSet *appsv1.DaemonSet, plat platform.Platform) []objectstate.ObjectState {
+	existingMetrics, ok := em.metrics[desiredDaemonSet.Name]
+	if !ok {
+		klog.Warningf("failed to find metrics service %q under the namespace %q", desiredDaemonSet.Name, desiredDaemonSet.Namespace)
+		return ret
+	}
+
+	desiredService := NewMetricsService(desiredDaemonSet, plat)
+	ret = append(ret,
+		objectstate.ObjectState{
+			Existing: existingMetrics.service,
+			Error:    existingMetrics.serviceError,
+			Desired:  desiredService,
+			Compare:  compare.Object,
+			Merge:    merge.ServiceForUpdate,
+		},
+	)
+
+	if meta.IsNoMatchError(existingMetrics.serviceMonitorError) {
+		klog.V(4).Infof("the ServiceMonitor API is not available, skipping %q", desiredDaemonSet.Name)
+		return ret
+	}
+	return append(ret,
+		objectstate.ObjectState{
+			Existing: existingMetrics.serviceMonitor,
+			Error:    existingMetrics.serviceMonitorError,
+			Desired:  NewMetricsServiceMonitor(desiredService, plat),
+			Compare:  compare.Object,
+			Merge:    merge.ObjectForUpdate,
+		},
+	)
+}
+
+func (em *ExistingManifests) appendDaemonSetState(ret []objectstate.ObjectState, desiredDaemonSet *appsv1.DaemonSet, nodeGroup *nropv1alpha1.NodeGroup, updater GenerateDesiredManifestUpdater) ([]objectstate.ObjectState, error) {
+	if updater != nil {
+		if err := updater(nodeGroup, desiredDaemonSet); err != nil {
+			return nil, fmt.Errorf("failed to update daemon set %q: %w", desiredDaemonSet.Name, err)
+		}
+	}
+
+	existingDaemonSet, ok := em.daemonSets[desiredDaemonSet.Name]
+	if !ok {
+		klog.Warningf("failed to find daemon set %q under the namespace %q", desiredDaemonSet.Name, desiredDaemonSet.Namespace)
+		return ret, nil
+	}
+
+	return append(ret,
+		objectstate.ObjectState{
+			Existing: existingDaemonSet.daemonSet,
+			Error:    existingDaemonSet.daemonSetError,
+			Desired:  desiredDaemonSet,
+			Compare:  compare.Object,
+			Merge:    merge.ObjectForUpdate,
+		},
+	), nil
 }
 
 func FromClient(
@@ -260,27 +391,36 @@ func FromClient(
 		}
 	}
 
-	// should have the amount of resources equals to the amount of node groups
-	for _, mcp := range mcps {
-		generatedName := objectnames.GetComponentName(instance.Name, mcp.Name)
+	if plat == platform.OpenShift && !instance.Spec.DisableExporterMetrics {
 		key := client.ObjectKey{
-			Name:      generatedName,
+			Name:      MetricsPrometheusRBACName,
 			Namespace: namespace,
 		}
+		pro := &rbacv1.Role{}
+		if ret.prometheusRoleError = cli.Get(ctx, key, pro); ret.prometheusRoleError == nil {
+			ret.prometheusRole = pro
+		}
+		prb := &rbacv1.RoleBinding{}
+		if ret.prometheusRoleBindingError = cli.Get(ctx, key, prb); ret.prometheusRoleBindingError == nil {
+			ret.prometheusRoleBinding = prb
+		}
+	}
 
-		if ret.daemonSets == nil {
-			ret.daemonSets = map[string]daemonSetManifest{}
+	// should have the amount of resources equals to the amount of node groups
+	for _, nodeGroup := range instance.Spec.NodeGroups {
+		if nodeGroup.NodeSelector == nil {
+			continue
 		}
+		ret.getDaemonSet(ctx, cli, objectnames.GetComponentName(instance.Name, nodeGroup.Name), namespace)
+		if !instance.Spec.DisableExporterMetrics {
+			ret.getMetrics(ctx, cli, objectnames.GetComponentName(instance.Name, nodeGroup.Name), namespace)
+		}
+	}
 
-		ds := &appsv1.DaemonSet{}
-		if err := cli.Get(ctx, key, ds); err == nil {
-			ret.daemonSets[generatedName] = daemonSetManifest{
-				daemonSet: ds,
-			}
-		} else {
-			ret.daemonSets[generatedName] = daemonSetManifest{
-				daemonSetError: err,
-			}
+	for _, mcp := range mcps {
+		ret.getDaemonSet(ctx, cli, objectnames.GetComponentName(instance.Name, mcp.Name), namespace)
+		if !instance.Spec.DisableExporterMetrics {
+			ret.getMetrics(ctx, cli, objectnames.GetComponentName(instance.Name, mcp.Name), namespace)
 		}
 
 		if plat == platform.OpenShift {
@@ -308,6 +448,48 @@ func FromClient(
 	return ret
 }
 
+func (em *ExistingManifests) getDaemonSet(ctx context.Context, cli client.Client, name, namespace string) {
+	if em.daemonSets == nil {
+		em.daemonSets = map[string]daemonSetManifest{}
+	}
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := cli.Get(ctx, key, ds); err == nil {
+		em.daemonSets[name] = daemonSetManifest{
+			daemonSet: ds,
+		}
+	} else {
+		em.daemonSets[name] = daemonSetManifest{
+			daemonSetError: err,
+		}
+	}
+}
+
+func (em *ExistingManifests) getMetrics(ctx context.Context, cli client.Client, name, namespace string) {
+	if em.metrics == nil {
+		em.metrics = map[string]metricsManifest{}
+	}
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+	mm := metricsManifest{}
+	svc := &corev1.Service{}
+	if mm.serviceError = cli.Get(ctx, key, svc); mm.serviceError == nil {
+		mm.service = svc
+	}
+	sm := NewServiceMonitor()
+	if mm.serviceMonitorError = cli.Get(ctx, key, sm); mm.serviceMonitorError == nil {
+		mm.serviceMonitor = sm
+	}
+	em.metrics[name] = mm
+}
+
 func DaemonSetNamespacedNameFromObject(obj client.Object) (nropv1alpha1.NamespacedName, bool) {
 	res := nropv1alpha1.NamespacedName{
 		Namespace: obj.GetNamespace(),
@@ -317,6 +499,15 @@ func DaemonSetNamespacedNameFromObject(obj client.Object) (nropv1alpha1.Namespac
 	return res, ok
 }
 
+// UserExporterImage returns the exporter image the user provided for the given node group, which may be nil,
+// falling back to the global one. An empty image means the built-in one is used.
+func UserExporterImage(instance *nropv1alpha1.NUMAResourcesOperator, nodeGroup *nropv1alpha1.NodeGroup) string {
+	if nodeGroup != nil && nodeGroup.Config != nil && nodeGroup.Config.ExporterImage != "" {
+		return nodeGroup.Config.ExporterImage
+	}
+	return instance.Spec.ExporterImage
+}
+
 func UpdateDaemonSetUserImageSettings(ds *appsv1.DaemonSet, userImageSpec, builtinImageSpec string, builtinPullPolicy corev1.PullPolicy) error {
 	// TODO: better match by name than assume container#0 is RTE proper (not minion)
 	cnt := &ds.Spec.Template.Spec.Containers[0]
@@ -342,11 +533,85 @@ func UpdateDaemonSetUserImageSettings(ds *appsv1.DaemonSet, userImageSpec, built
 		klog.Warningf("Cannot modify the command line arguments %v", cnt.Args)
 		return nil
 	}
-	fl.SetToggle("--exit-on-conf-change")
+	// the built-in exporter can apply the config changes without restarting
+	fl.SetToggle("--reload-on-conf-change")
+	cnt.Args = fl.Args()
+	// the built-in exporter serves the health checks
+	return UpdateDaemonSetDebugServer(ds)
+}
+
+// UpdateDaemonSetNodeGroupConfig applies the per-NodeGroup settings on top of the global ones.
+// Settings not explicitly set in the node group config are left untouched. The exporter image
+// is not among them: see UserExporterImage and UpdateDaemonSetUserImageSettings.
+func UpdateDaemonSetNodeGroupConfig(ds *appsv1.DaemonSet, conf *nropv1alpha1.NodeGroupConfig) error {
+	if conf == nil {
+		return nil
+	}
+
+	// TODO: better match by name than assume container#0 is RTE proper (not minion)
+	cnt := &ds.Spec.Template.Spec.Containers[0]
+	if conf.LogLevel != "" {
+		if err := loglevel.UpdatePodSpec(&ds.Spec.Template.Spec, conf.LogLevel); err != nil {
+			return err
+		}
+	}
+
+	fl := flagcodec.ParseArgvKeyValue(cnt.Args)
+	if fl == nil {
+		return fmt.Errorf("cannot modify the arguments for container %s", cnt.Name)
+	}
+
+	if conf.InfoRefreshPeriod != nil {
+		fl.SetOption("--sleep-interval", conf.InfoRefreshPeriod.Duration.String())
+	}
+
+	if conf.PodReadiness != nil {
+		fl.SetOption("--podreadiness", strconv.FormatBool(*conf.PodReadiness))
+		if !*conf.PodReadiness {
+			// the exporter will never set the custom conditions, so the pods would never become ready
+			ds.Spec.Template.Spec.ReadinessGates = nil
+		}
+	}
+
+	if conf.NotifyFilePath != "" {
+		fl.SetOption("--notify-file", conf.NotifyFilePath)
+	}
+
 	cnt.Args = fl.Args()
 	return nil
 }
 
+// UpdateDaemonSetNodeSelector makes the daemonset pods run only on the nodes selected by the given selector.
+// The match labels translate to the pod node selector, while the match expressions translate to
+// a required node affinity term.
+func UpdateDaemonSetNodeSelector(ds *appsv1.DaemonSet, sel *metav1.LabelSelector) {
+	podSpec := &ds.Spec.Template.Spec
+	podSpec.NodeSelector = sel.MatchLabels
+	if len(sel.MatchExpressions) == 0 {
+		return
+	}
+
+	var reqs []corev1.NodeSelectorRequirement
+	for _, expr := range sel.MatchExpressions {
+		reqs = append(reqs, corev1.NodeSelectorRequirement{
+			Key:      expr.Key,
+			Operator: corev1.NodeSelectorOperator(expr.Operator),
+			Values:   expr.Values,
+		})
+	}
+	podSpec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: reqs,
+					},
+				},
+			},
+		},
+	}
+}
+
 // UpdateDaemonSetRunAsIDs bump the ds container privileges to 0/0.
 // We need this in the operator-as-operand flow because the operator image itself
 // is built to run with non-root user/group, and we should keep it like this.
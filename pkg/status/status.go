@@ -18,11 +18,12 @@ package status
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
-	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,18 +37,39 @@ const (
 	ConditionProgressing = "Progressing"
 	ConditionDegraded    = "Degraded"
 	ConditionUpgradeable = "Upgradeable"
+	// ConditionPaused is reported only once the reconciliation gets paused, and it is independent from the other conditions
+	ConditionPaused = "Paused"
 )
 
 const (
 	ConditionTypeIncorrectNUMAResourcesOperatorResourceName = "IncorrectNUMAResourcesOperatorResourceName"
 )
 
+// reasons for the conditions set by the reconcilers. The conditions not set carry ReasonAsExpected.
+const (
+	ReasonAsExpected                  = "AsExpected"
+	ReasonCRDInstallFailed            = "CRDInstallFailed"
+	ReasonMachineConfigSyncFailed     = "MachineConfigSyncFailed"
+	ReasonMachineConfigPending        = "MachineConfigPending"
+	ReasonRTESyncFailed               = "RTESyncFailed"
+	ReasonNodeGroupsStatusFailed      = "NodeGroupsStatusFailed"
+	ReasonDaemonSetNotReady           = "DaemonSetNotReady"
+	ReasonDaemonSetStatusFailed       = "DaemonSetStatusFailed"
+	ReasonSchedulerSyncFailed         = "SchedulerSyncFailed"
+	ReasonDeploymentNotReady          = "DeploymentNotReady"
+	ReasonDeploymentStatusFailed      = "DeploymentStatusFailed"
+	ReasonTeardownFailed              = "TeardownFailed"
+	ReasonMachineConfigRemovalPending = "MachineConfigRemovalPending"
+	ReasonPausedByUser                = "PausedByUser"
+	ReasonDriftDetectionFailed        = "DriftDetectionFailed"
+	ReasonNRTAPIMissing               = "NodeResourceTopologyAPIMissing"
+	ReasonNRTAPICheckFailed           = "NodeResourceTopologyAPICheckFailed"
+)
+
+// Update sets the condition on the object and persists its whole status, which the reconcilers fill along the way.
+// The unchanged conditions keep their transition time, so persisting an unchanged status is a no-op.
 func Update(ctx context.Context, client k8sclient.Client, rte *nropv1alpha1.NUMAResourcesOperator, condition string, reason string, message string) error {
-	conditions := NewConditions(condition, reason, message)
-	if equality.Semantic.DeepEqual(conditions, rte.Status.Conditions) {
-		return nil
-	}
-	rte.Status.Conditions = NewConditions(condition, reason, message)
+	rte.Status.Conditions = UpdateConditions(rte.Status.Conditions, condition, reason, message, rte.Generation)
 
 	if err := client.Status().Update(ctx, rte); err != nil {
 		return errors.Wrapf(err, "could not update status for object %s", k8sclient.ObjectKeyFromObject(rte))
@@ -65,11 +87,65 @@ func FindCondition(conditions []metav1.Condition, condition string) *metav1.Cond
 	return nil
 }
 
+// UpdateConditions returns a copy of the current conditions with the given condition set to true,
+// and the other ones set to false. The transition time changes only for the conditions changing their status.
+// Setting a condition means the reconciliation runs, so the Paused condition, if any, is set to false.
+func UpdateConditions(currentConditions []metav1.Condition, condition string, reason string, message string, generation int64) []metav1.Condition {
+	conditions := make([]metav1.Condition, len(currentConditions))
+	copy(conditions, currentConditions)
+
+	for _, cond := range NewConditions(condition, reason, message) {
+		cond.ObservedGeneration = generation
+		meta.SetStatusCondition(&conditions, cond)
+	}
+	return UpdatePausedCondition(conditions, false, "", "", generation)
+}
+
+// UpdatePausedCondition returns a copy of the current conditions with the Paused condition reflecting the paused state.
+// The Paused condition is not added until the reconciliation gets paused for the first time.
+func UpdatePausedCondition(currentConditions []metav1.Condition, paused bool, reason string, message string, generation int64) []metav1.Condition {
+	conditions := make([]metav1.Condition, len(currentConditions))
+	copy(conditions, currentConditions)
+
+	if !paused && FindCondition(conditions, ConditionPaused) == nil {
+		return conditions
+	}
+
+	cond := metav1.Condition{
+		Type:               ConditionPaused,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonAsExpected,
+		ObservedGeneration: generation,
+	}
+	if paused {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = reason
+		cond.Message = message
+	}
+	meta.SetStatusCondition(&conditions, cond)
+	return conditions
+}
+
+// PausedReasonAndMessage returns the reason and the message of the Paused condition, given the names of the objects
+// the reconciliation would create or update once resumed, or the error hit while detecting them
+func PausedReasonAndMessage(drifted []string, err error) (string, string) {
+	if err != nil {
+		return ReasonDriftDetectionFailed, fmt.Sprintf("reconciliation paused, failed to detect the drifted objects: %v", err)
+	}
+	if len(drifted) == 0 {
+		return ReasonPausedByUser, "reconciliation paused, no drift detected"
+	}
+	return ReasonPausedByUser, fmt.Sprintf("reconciliation paused, objects differing from the desired state: %s", strings.Join(drifted, ", "))
+}
+
+// NewConditions returns the conditions with the given condition set to true and no transition time set
 func NewConditions(condition string, reason string, message string) []metav1.Condition {
 	conditions := newBaseConditions()
 	switch condition {
 	case ConditionAvailable:
 		conditions[0].Status = metav1.ConditionTrue
+		conditions[0].Reason = reason
+		conditions[0].Message = message
 		conditions[1].Status = metav1.ConditionTrue
 	case ConditionProgressing:
 		conditions[2].Status = metav1.ConditionTrue
@@ -84,31 +160,26 @@ func NewConditions(condition string, reason string, message string) []metav1.Con
 }
 
 func newBaseConditions() []metav1.Condition {
-	now := time.Now()
 	return []metav1.Condition{
 		{
-			Type:               ConditionAvailable,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionAvailable,
+			Type:   ConditionAvailable,
+			Status: metav1.ConditionFalse,
+			Reason: ReasonAsExpected,
 		},
 		{
-			Type:               ConditionUpgradeable,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionUpgradeable,
+			Type:   ConditionUpgradeable,
+			Status: metav1.ConditionFalse,
+			Reason: ReasonAsExpected,
 		},
 		{
-			Type:               ConditionProgressing,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionProgressing,
+			Type:   ConditionProgressing,
+			Status: metav1.ConditionFalse,
+			Reason: ReasonAsExpected,
 		},
 		{
-			Type:               ConditionDegraded,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionDegraded,
+			Type:   ConditionDegraded,
+			Status: metav1.ConditionFalse,
+			Reason: ReasonAsExpected,
 		},
 	}
 }
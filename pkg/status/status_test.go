@@ -19,6 +19,7 @@ package status
 import (
 	"context"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -55,3 +56,61 @@ func TestUpdate(t *testing.T) {
 		t.Errorf("Update() failed to set correct status, expected: %q, got: %q", v1.ConditionTrue, progressingCondition.Status)
 	}
 }
+
+func TestUpdateConditions(t *testing.T) {
+	conditions := UpdateConditions(nil, ConditionProgressing, ReasonDaemonSetNotReady, "waiting", 1)
+	progressingCondition := FindCondition(conditions, ConditionProgressing)
+	if progressingCondition == nil || progressingCondition.Status != v1.ConditionTrue {
+		t.Fatalf("UpdateConditions() failed to set the progressing condition: %v", conditions)
+	}
+	if progressingCondition.Reason != ReasonDaemonSetNotReady || progressingCondition.Message != "waiting" {
+		t.Errorf("UpdateConditions() set unexpected reason %q and message %q", progressingCondition.Reason, progressingCondition.Message)
+	}
+
+	// pretend the conditions were set long ago to detect the transition time changes
+	past := v1.NewTime(time.Now().Add(-time.Hour))
+	for idx := range conditions {
+		conditions[idx].LastTransitionTime = past
+	}
+
+	updated := UpdateConditions(conditions, ConditionAvailable, ReasonAsExpected, "", 2)
+	for _, cond := range updated {
+		if cond.ObservedGeneration != 2 {
+			t.Errorf("condition %q has observed generation %d, expected 2", cond.Type, cond.ObservedGeneration)
+		}
+	}
+	for _, conditionType := range []string{ConditionAvailable, ConditionProgressing, ConditionUpgradeable} {
+		if cond := FindCondition(updated, conditionType); cond.LastTransitionTime.Equal(&past) {
+			t.Errorf("condition %q changed status but kept the transition time", conditionType)
+		}
+	}
+	if cond := FindCondition(updated, ConditionDegraded); !cond.LastTransitionTime.Equal(&past) {
+		t.Errorf("condition %q did not change status but got a new transition time", ConditionDegraded)
+	}
+
+	// the input must not be modified
+	if cond := FindCondition(conditions, ConditionProgressing); cond.Status != v1.ConditionTrue || cond.ObservedGeneration != 1 {
+		t.Errorf("UpdateConditions() modified the current conditions: %v", conditions)
+	}
+}
+
+func TestUpdatePausedCondition(t *testing.T) {
+	conditions := UpdateConditions(nil, ConditionAvailable, ReasonAsExpected, "", 1)
+	if cond := FindCondition(UpdatePausedCondition(conditions, false, "", "", 1), ConditionPaused); cond != nil {
+		t.Errorf("UpdatePausedCondition() added the condition while never paused: %v", cond)
+	}
+
+	paused := UpdatePausedCondition(conditions, true, ReasonPausedByUser, "paused", 2)
+	cond := FindCondition(paused, ConditionPaused)
+	if cond == nil || cond.Status != v1.ConditionTrue || cond.Reason != ReasonPausedByUser {
+		t.Fatalf("UpdatePausedCondition() failed to set the condition: %v", paused)
+	}
+	if available := FindCondition(paused, ConditionAvailable); available.Status != v1.ConditionTrue {
+		t.Errorf("UpdatePausedCondition() changed the other conditions: %v", paused)
+	}
+
+	resumed := UpdateConditions(paused, ConditionProgressing, ReasonDaemonSetNotReady, "", 3)
+	if cond := FindCondition(resumed, ConditionPaused); cond == nil || cond.Status != v1.ConditionFalse {
+		t.Errorf("UpdateConditions() did not clear the paused condition: %v", resumed)
+	}
+}
@@ -20,8 +20,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/docker/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
 	machineconfigv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 )
@@ -32,7 +36,6 @@ const (
 )
 
 // MachineConfigPoolDuplicates selected MCPs for duplicates
-// TODO: move it under the validation webhook once we will have one
 func MachineConfigPoolDuplicates(mcps []*machineconfigv1.MachineConfigPool) error {
 	duplicates := map[string]int{}
 	for _, mcp := range mcps {
@@ -57,7 +60,6 @@ func MachineConfigPoolDuplicates(mcps []*machineconfigv1.MachineConfigPool) erro
 }
 
 // NodeGroups validates the node groups for nil values and duplicates.
-// TODO: move it under the validation webhook once we will have one
 func NodeGroups(nodeGroups []nropv1alpha1.NodeGroup) error {
 	if err := nodeGroupsMachineConfigPoolSelector(nodeGroups); err != nil {
 		return err
@@ -71,24 +73,85 @@ func NodeGroups(nodeGroups []nropv1alpha1.NodeGroup) error {
 		return err
 	}
 
+	if err := nodeGroupsNodeSelector(nodeGroups); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NodeGroupsForPlatform validates the node groups use the selector supported on the given platform:
+// machine config pools are available only on OpenShift, so other platforms must select the nodes directly.
+func NodeGroupsForPlatform(nodeGroups []nropv1alpha1.NodeGroup, plat platform.Platform) error {
+	for _, nodeGroup := range nodeGroups {
+		if plat == platform.OpenShift && nodeGroup.NodeSelector != nil {
+			return fmt.Errorf("the node group %q uses nodeSelector, which is not supported on platform %q", nodeGroup.Name, plat)
+		}
+		if plat != platform.OpenShift && nodeGroup.MachineConfigPoolSelector != nil {
+			return fmt.Errorf("the node group uses machineConfigPoolSelector, which is not supported on platform %q", plat)
+		}
+	}
+
 	return nil
 }
 
-// TODO: move it under the validation webhook once we will have one
 func nodeGroupsMachineConfigPoolSelector(nodeGroups []nropv1alpha1.NodeGroup) error {
 	for _, nodeGroup := range nodeGroups {
-		if nodeGroup.MachineConfigPoolSelector == nil {
-			return fmt.Errorf("one of the node groups does not have machineConfigPoolSelector")
+		if nodeGroup.MachineConfigPoolSelector == nil && nodeGroup.NodeSelector == nil {
+			return fmt.Errorf("one of the node groups does not have machineConfigPoolSelector nor nodeSelector")
+		}
+		if nodeGroup.MachineConfigPoolSelector != nil && nodeGroup.NodeSelector != nil {
+			return fmt.Errorf("one of the node groups has both machineConfigPoolSelector and nodeSelector")
 		}
 	}
 
 	return nil
 }
 
-// TODO: move it under the validation webhook once we will have one
+func nodeGroupsNodeSelector(nodeGroups []nropv1alpha1.NodeGroup) error {
+	names := map[string]int{}
+	var selectorsErrors []string
+	for _, nodeGroup := range nodeGroups {
+		if nodeGroup.NodeSelector == nil {
+			continue
+		}
+
+		if nodeGroup.Name == "" {
+			selectorsErrors = append(selectorsErrors, fmt.Sprintf("the node group with the nodeSelector %q does not have a name", nodeGroup.NodeSelector.String()))
+			continue
+		}
+
+		for _, msg := range k8svalidation.IsDNS1123Label(nodeGroup.Name) {
+			selectorsErrors = append(selectorsErrors, fmt.Sprintf("the node group name %q is invalid: %s", nodeGroup.Name, msg))
+		}
+		names[nodeGroup.Name] += 1
+
+		if _, err := metav1.LabelSelectorAsSelector(nodeGroup.NodeSelector); err != nil {
+			selectorsErrors = append(selectorsErrors, err.Error())
+		}
+	}
+
+	for name, count := range names {
+		if count > 1 {
+			selectorsErrors = append(selectorsErrors, fmt.Sprintf("the node group name %q has duplicates", name))
+		}
+	}
+
+	if len(selectorsErrors) > 0 {
+		return fmt.Errorf(strings.Join(selectorsErrors, "; "))
+	}
+
+	return nil
+}
+
 func nodeGroupsDuplicates(nodeGroups []nropv1alpha1.NodeGroup) error {
 	duplicates := map[string]int{}
+	nodeDuplicates := map[string]int{}
 	for _, nodeGroup := range nodeGroups {
+		if nodeGroup.NodeSelector != nil {
+			nodeDuplicates[nodeGroup.NodeSelector.String()] += 1
+		}
+
 		if nodeGroup.MachineConfigPoolSelector == nil {
 			continue
 		}
@@ -106,6 +169,11 @@ func nodeGroupsDuplicates(nodeGroups []nropv1alpha1.NodeGroup) error {
 			duplicateErrors = append(duplicateErrors, fmt.Sprintf("the node group with the machineConfigPoolSelector %q has duplicates", selector))
 		}
 	}
+	for selector, count := range nodeDuplicates {
+		if count > 1 {
+			duplicateErrors = append(duplicateErrors, fmt.Sprintf("the node group with the nodeSelector %q has duplicates", selector))
+		}
+	}
 
 	if len(duplicateErrors) > 0 {
 		return fmt.Errorf(strings.Join(duplicateErrors, "; "))
@@ -114,7 +182,6 @@ func nodeGroupsDuplicates(nodeGroups []nropv1alpha1.NodeGroup) error {
 	return nil
 }
 
-// TODO: move it under the validation webhook once we will have one
 func nodeGroupMachineConfigPoolSelector(nodeGroups []nropv1alpha1.NodeGroup) error {
 	var selectorsErrors []string
 	for _, nodeGroup := range nodeGroups {
@@ -133,3 +200,34 @@ func nodeGroupMachineConfigPoolSelector(nodeGroups []nropv1alpha1.NodeGroup) err
 
 	return nil
 }
+
+// SchedulerName validates the scheduler name can be used as profile name and referenced by the pods
+func SchedulerName(name string) error {
+	if name == "" {
+		return fmt.Errorf("the scheduler name is empty")
+	}
+
+	if name == corev1.DefaultSchedulerName {
+		return fmt.Errorf("the scheduler name %q clashes with the default scheduler", name)
+	}
+
+	// the pods must be able to reference the scheduler by name
+	if msgs := k8svalidation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		return fmt.Errorf("the scheduler name %q is invalid: %s", name, strings.Join(msgs, "; "))
+	}
+
+	return nil
+}
+
+// SchedulerImage validates the scheduler image is a valid image reference
+func SchedulerImage(image string) error {
+	if image == "" {
+		return fmt.Errorf("the scheduler image is empty")
+	}
+
+	if _, err := reference.ParseNormalizedNamed(image); err != nil {
+		return fmt.Errorf("the scheduler image %q is invalid: %w", image, err)
+	}
+
+	return nil
+}
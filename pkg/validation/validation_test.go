@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	machineconfigv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
@@ -130,5 +131,191 @@ var _ = Describe("Validation", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("with both machineConfigPoolSelector and nodeSelector", func() {
+			It("should return an error", func() {
+				nodeGroups := []nropv1alpha1.NodeGroup{
+					{
+						Name: "test",
+						MachineConfigPoolSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test": "test",
+							},
+						},
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test": "test",
+							},
+						},
+					},
+				}
+
+				err := NodeGroups(nodeGroups)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("has both machineConfigPoolSelector and nodeSelector"))
+			})
+		})
+
+		Context("with nodeSelector without name", func() {
+			It("should return an error", func() {
+				nodeGroups := []nropv1alpha1.NodeGroup{
+					{
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test": "test",
+							},
+						},
+					},
+				}
+
+				err := NodeGroups(nodeGroups)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not have a name"))
+			})
+		})
+
+		Context("with nodeSelector and invalid name", func() {
+			It("should return an error", func() {
+				nodeGroups := []nropv1alpha1.NodeGroup{
+					{
+						Name: "Bad_Name",
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test": "test",
+							},
+						},
+					},
+				}
+
+				err := NodeGroups(nodeGroups)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("is invalid"))
+			})
+		})
+
+		Context("with nodeSelector duplicated names", func() {
+			It("should return an error", func() {
+				nodeGroups := []nropv1alpha1.NodeGroup{
+					{
+						Name: "test",
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test": "test",
+							},
+						},
+					},
+					{
+						Name: "test",
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test1": "test1",
+							},
+						},
+					},
+				}
+
+				err := NodeGroups(nodeGroups)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the node group name \"test\" has duplicates"))
+			})
+		})
+
+		Context("with nodeSelector correct values", func() {
+			It("should not return any error", func() {
+				nodeGroups := []nropv1alpha1.NodeGroup{
+					{
+						Name: "test",
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test": "test",
+							},
+						},
+					},
+					{
+						Name: "test1",
+						NodeSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{
+								"test1": "test1",
+							},
+						},
+					},
+				}
+
+				err := NodeGroups(nodeGroups)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("SchedulerName", func() {
+		It("should accept a valid name", func() {
+			Expect(SchedulerName("topo-aware-scheduler")).To(Succeed())
+		})
+
+		It("should reject an empty name", func() {
+			Expect(SchedulerName("")).ToNot(Succeed())
+		})
+
+		It("should reject the default scheduler name", func() {
+			err := SchedulerName("default-scheduler")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("clashes with the default scheduler"))
+		})
+
+		It("should reject an invalid name", func() {
+			err := SchedulerName("Topo_Aware")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is invalid"))
+		})
+	})
+
+	Describe("SchedulerImage", func() {
+		It("should accept valid references", func() {
+			Expect(SchedulerImage("quay.io/openshift-kni/scheduler-plugins:4.10-snapshot")).To(Succeed())
+			Expect(SchedulerImage("scheduler-plugins")).To(Succeed())
+			Expect(SchedulerImage("quay.io/openshift-kni/scheduler-plugins@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")).To(Succeed())
+		})
+
+		It("should reject an empty reference", func() {
+			Expect(SchedulerImage("")).ToNot(Succeed())
+		})
+
+		It("should reject an invalid reference", func() {
+			err := SchedulerImage("quay.io/openshift-kni/Scheduler:bad tag")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is invalid"))
+		})
+	})
+
+	Describe("NodeGroupsForPlatform", func() {
+		nodeSelectorGroups := []nropv1alpha1.NodeGroup{
+			{
+				Name: "test",
+				NodeSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+		mcpSelectorGroups := []nropv1alpha1.NodeGroup{
+			{
+				MachineConfigPoolSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"test": "test",
+					},
+				},
+			},
+		}
+
+		It("should accept only machineConfigPoolSelector on OpenShift", func() {
+			Expect(NodeGroupsForPlatform(mcpSelectorGroups, platform.OpenShift)).To(Succeed())
+			Expect(NodeGroupsForPlatform(nodeSelectorGroups, platform.OpenShift)).ToNot(Succeed())
+		})
+
+		It("should accept only nodeSelector on Kubernetes", func() {
+			Expect(NodeGroupsForPlatform(nodeSelectorGroups, platform.Kubernetes)).To(Succeed())
+			Expect(NodeGroupsForPlatform(mcpSelectorGroups, platform.Kubernetes)).ToNot(Succeed())
+		})
 	})
 })
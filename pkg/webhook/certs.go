@@ -0,0 +1,182 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	certValidity    = 365 * 24 * time.Hour
+	certRenewBefore = 30 * 24 * time.Hour
+)
+
+// Certificates holds the PEM encoded self-signed CA and the serving certificate and key signed by it.
+// The CA bundle may also hold the previous CA, still trusted while the certificates are renewed.
+type Certificates struct {
+	CACert []byte
+	Cert   []byte
+	Key    []byte
+}
+
+// ServiceDNSNames returns the names the serving certificate must be valid for
+func ServiceDNSNames(serviceName, namespace string) []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespace),
+	}
+}
+
+// NewCertificates generates a new CA and a serving certificate for the given service
+func NewCertificates(serviceName, namespace string, now time.Time) (*Certificates, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%s-ca@%d", serviceName, now.Unix())},
+		NotBefore:             now.Add(-1 * time.Hour),
+		NotAfter:              now.Add(certValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	dnsNames := ServiceDNSNames(serviceName, namespace)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-1 * time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificates{
+		CACert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// Verify checks the serving certificate is signed by the CA, is valid for the given service
+// and will not expire before the renewal window.
+func (c *Certificates) Verify(serviceName, namespace string, now time.Time) error {
+	caCerts, err := parseCertificates(c.CACert)
+	if err != nil {
+		return fmt.Errorf("invalid CA certificate: %w", err)
+	}
+	cert, err := parseCertificate(c.Cert)
+	if err != nil {
+		return fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	if block, _ := pem.Decode(c.Key); block == nil {
+		return fmt.Errorf("invalid serving key")
+	}
+
+	roots := x509.NewCertPool()
+	for _, caCert := range caCerts {
+		roots.AddCert(caCert)
+	}
+	for _, dnsName := range ServiceDNSNames(serviceName, namespace) {
+		_, err := cert.Verify(x509.VerifyOptions{
+			DNSName:     dnsName,
+			Roots:       roots,
+			CurrentTime: now.Add(certRenewBefore),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendPreviousCA adds to the CA bundle the first CA of the given previous bundle, if not expired yet.
+// Only the CA in use is carried over, so the bundle does not grow on each renewal.
+func (c *Certificates) AppendPreviousCA(previousCACert []byte, now time.Time) {
+	block, _ := pem.Decode(previousCACert)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || now.After(caCert.NotAfter) {
+		return
+	}
+	c.CACert = append(c.CACert, pem.EncodeToMemory(block)...)
+}
+
+// parseCertificates parses all the certificates of the PEM bundle, which must hold at least one
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("failed to decode PEM certificate")
+	}
+	return certs, nil
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("failed to decode PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,114 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+
+	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
+	"github.com/openshift-kni/numaresources-operator/pkg/machineconfigpools"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
+	"github.com/openshift-kni/numaresources-operator/pkg/validation"
+)
+
+// NUMAResourcesOperatorValidatingPath is the path the NUMAResourcesOperator validating webhook is served on
+const NUMAResourcesOperatorValidatingPath = "/validate-nodetopology-openshift-io-v1alpha1-numaresourcesoperator"
+
+// NUMAResourcesOperatorValidator rejects the NUMAResourcesOperator objects the reconciler would flag as degraded
+type NUMAResourcesOperatorValidator struct {
+	Client   client.Client
+	Platform platform.Platform
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &NUMAResourcesOperatorValidator{}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *NUMAResourcesOperatorValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler
+func (v *NUMAResourcesOperatorValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	instance := &nropv1alpha1.NUMAResourcesOperator{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if req.Operation == admissionv1.Update {
+		// the updates not touching the spec, like the finalizer removal, must go through even if the
+		// spec is no longer valid (e.g. the machine config pools changed), or the object cannot be deleted
+		if !instance.DeletionTimestamp.IsZero() {
+			return admission.Allowed("")
+		}
+		oldInstance := &nropv1alpha1.NUMAResourcesOperator{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldInstance); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if equality.Semantic.DeepEqual(oldInstance.Spec, instance.Spec) {
+			return admission.Allowed("")
+		}
+	}
+
+	if err := v.Validate(ctx, instance); err != nil {
+		klog.InfoS("rejected", "object", instance.Name, "operation", req.Operation, "reason", err)
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// Validate runs on the given object the same checks the reconciler does
+func (v *NUMAResourcesOperatorValidator) Validate(ctx context.Context, instance *nropv1alpha1.NUMAResourcesOperator) error {
+	if instance.Name != objectnames.DefaultNUMAResourcesOperatorCrName {
+		return fmt.Errorf("incorrect NUMAResourcesOperator resource name: %s, only %q is supported", instance.Name, objectnames.DefaultNUMAResourcesOperatorCrName)
+	}
+
+	if err := validation.NodeGroups(instance.Spec.NodeGroups); err != nil {
+		return err
+	}
+
+	if err := validation.NodeGroupsForPlatform(instance.Spec.NodeGroups, v.Platform); err != nil {
+		return err
+	}
+
+	if v.Platform != platform.OpenShift {
+		return nil
+	}
+
+	mcps, err := machineconfigpools.GetNodeGroupsMCPs(ctx, v.Client, instance.Spec.NodeGroups)
+	if err != nil {
+		return err
+	}
+
+	return validation.MachineConfigPoolDuplicates(mcps)
+}
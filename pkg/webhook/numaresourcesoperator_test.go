@@ -0,0 +1,236 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	machineconfigv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+
+	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
+	"github.com/openshift-kni/numaresources-operator/pkg/testutils"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		nropv1alpha1.AddToScheme,
+		machineconfigv1.Install,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatalf("failed to set up the scheme: %v", err)
+		}
+	}
+	return scheme
+}
+
+func newTestValidator(t *testing.T, plat platform.Platform, objs ...runtime.Object) *NUMAResourcesOperatorValidator {
+	scheme := newTestScheme(t)
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatalf("failed to create the decoder: %v", err)
+	}
+
+	v := &NUMAResourcesOperatorValidator{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build(),
+		Platform: plat,
+	}
+	if err := v.InjectDecoder(decoder); err != nil {
+		t.Fatalf("failed to inject the decoder: %v", err)
+	}
+	return v
+}
+
+func newAdmissionRequest(t *testing.T, op admissionv1.Operation, obj client.Object) admission.Request {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed to encode the object: %v", err)
+	}
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: op,
+			Name:      obj.GetName(),
+			Object:    runtime.RawExtension{Raw: data},
+		},
+	}
+}
+
+func newUpdateAdmissionRequest(t *testing.T, oldObj, obj client.Object) admission.Request {
+	req := newAdmissionRequest(t, admissionv1.Update, obj)
+	data, err := json.Marshal(oldObj)
+	if err != nil {
+		t.Fatalf("failed to encode the old object: %v", err)
+	}
+	req.OldObject = runtime.RawExtension{Raw: data}
+	return req
+}
+
+func TestNUMAResourcesOperatorValidatorHandle(t *testing.T) {
+	label1 := map[string]string{"test1": "test1"}
+	label2 := map[string]string{"test2": "test2"}
+	mcp1 := testutils.NewMachineConfigPool("test1", label1, &metav1.LabelSelector{MatchLabels: label1}, &metav1.LabelSelector{MatchLabels: label1})
+	mcp2 := testutils.NewMachineConfigPool("test2", label2, &metav1.LabelSelector{MatchLabels: label2}, &metav1.LabelSelector{MatchLabels: label2})
+
+	type testCase struct {
+		name      string
+		platform  platform.Platform
+		operation admissionv1.Operation
+		// the object before the update, for the update operations
+		oldNRO  *nropv1alpha1.NUMAResourcesOperator
+		nro     *nropv1alpha1.NUMAResourcesOperator
+		allowed bool
+	}
+
+	testCases := []testCase{
+		{
+			name:      "valid object",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Create,
+			nro: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+				{MatchLabels: label2},
+			}),
+			allowed: true,
+		},
+		{
+			name:      "wrong name",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Create,
+			nro: testutils.NewNUMAResourcesOperator("test", []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			}),
+		},
+		{
+			name:      "nil selector",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Create,
+			nro:       testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{nil}),
+		},
+		{
+			name:      "duplicate selectors",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Update,
+			oldNRO: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			}),
+			nro: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+				{MatchLabels: label1},
+			}),
+		},
+		{
+			name:      "update not changing an invalid spec",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Update,
+			oldNRO: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: map[string]string{"test3": "test3"}},
+			}),
+			nro: withFinalizer(testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: map[string]string{"test3": "test3"}},
+			})),
+			allowed: true,
+		},
+		{
+			name:      "update of an object being deleted",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Update,
+			oldNRO: withDeletionTimestamp(testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			})),
+			nro: withDeletionTimestamp(testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: map[string]string{"test3": "test3"}},
+			})),
+			allowed: true,
+		},
+		{
+			name:      "unparsable selector",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Create,
+			nro: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      "test",
+							Operator: "bad-operator",
+						},
+					},
+				},
+			}),
+		},
+		{
+			name:      "selector not matching any machine config pool",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Create,
+			nro: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: map[string]string{"test3": "test3"}},
+			}),
+		},
+		{
+			name:      "machine config pool selector on kubernetes",
+			platform:  platform.Kubernetes,
+			operation: admissionv1.Create,
+			nro: testutils.NewNUMAResourcesOperator(objectnames.DefaultNUMAResourcesOperatorCrName, []*metav1.LabelSelector{
+				{MatchLabels: label1},
+			}),
+		},
+		{
+			name:      "delete is always allowed",
+			platform:  platform.OpenShift,
+			operation: admissionv1.Delete,
+			nro:       testutils.NewNUMAResourcesOperator("test", nil),
+			allowed:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newTestValidator(t, tc.platform, mcp1, mcp2)
+			req := newAdmissionRequest(t, tc.operation, tc.nro)
+			if tc.oldNRO != nil {
+				req = newUpdateAdmissionRequest(t, tc.oldNRO, tc.nro)
+			}
+			resp := v.Handle(context.TODO(), req)
+			if resp.Allowed != tc.allowed {
+				t.Errorf("expected allowed=%v got %v (result: %v)", tc.allowed, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}
+
+func withFinalizer(nro *nropv1alpha1.NUMAResourcesOperator) *nropv1alpha1.NUMAResourcesOperator {
+	nro.Finalizers = append(nro.Finalizers, "test.finalizer")
+	return nro
+}
+
+func withDeletionTimestamp(nro *nropv1alpha1.NUMAResourcesOperator) *nropv1alpha1.NUMAResourcesOperator {
+	now := metav1.Now()
+	nro.DeletionTimestamp = &now
+	return nro
+}
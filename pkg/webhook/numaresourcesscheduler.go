@@ -0,0 +1,155 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
+	schedstate "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/objectstate/sched"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
+	"github.com/openshift-kni/numaresources-operator/pkg/validation"
+)
+
+const (
+	// NUMAResourcesSchedulerDefaultingPath is the path the NUMAResourcesScheduler defaulting webhook is served on
+	NUMAResourcesSchedulerDefaultingPath = "/mutate-nodetopology-openshift-io-v1alpha1-numaresourcesscheduler"
+	// NUMAResourcesSchedulerValidatingPath is the path the NUMAResourcesScheduler validating webhook is served on
+	NUMAResourcesSchedulerValidatingPath = "/validate-nodetopology-openshift-io-v1alpha1-numaresourcesscheduler"
+)
+
+// NUMAResourcesSchedulerDefaulter sets the scheduler name, if missing, to the one the scheduler config ships
+type NUMAResourcesSchedulerDefaulter struct {
+	DefaultSchedulerName string
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &NUMAResourcesSchedulerDefaulter{}
+
+// InjectDecoder implements admission.DecoderInjector
+func (d *NUMAResourcesSchedulerDefaulter) InjectDecoder(dec *admission.Decoder) error {
+	d.decoder = dec
+	return nil
+}
+
+// Handle implements admission.Handler
+func (d *NUMAResourcesSchedulerDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &nropv1alpha1.NUMAResourcesScheduler{}
+	if err := d.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if instance.Spec.SchedulerName != "" {
+		return admission.Allowed("")
+	}
+	instance.Spec.SchedulerName = d.DefaultSchedulerName
+
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, data)
+}
+
+// NUMAResourcesSchedulerValidator rejects the NUMAResourcesScheduler objects the reconciler would fail to deploy
+type NUMAResourcesSchedulerValidator struct {
+	// SchedulerConfigMap is the scheduler config the reconciler renders the scheduler name into
+	SchedulerConfigMap *corev1.ConfigMap
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &NUMAResourcesSchedulerValidator{}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *NUMAResourcesSchedulerValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler
+func (v *NUMAResourcesSchedulerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	instance := &nropv1alpha1.NUMAResourcesScheduler{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if req.Operation == admissionv1.Update {
+		// the updates not touching the spec, like the finalizer removal, must go through even if the
+		// spec is no longer valid (e.g. the scheduler config changed), or the object cannot be deleted
+		if !instance.DeletionTimestamp.IsZero() {
+			return admission.Allowed("")
+		}
+		oldInstance := &nropv1alpha1.NUMAResourcesScheduler{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldInstance); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if equality.Semantic.DeepEqual(oldInstance.Spec, instance.Spec) {
+			return admission.Allowed("")
+		}
+	}
+
+	if err := v.Validate(instance); err != nil {
+		klog.InfoS("rejected", "object", instance.Name, "operation", req.Operation, "reason", err)
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// Validate checks the given object can be rendered into the scheduler manifests
+func (v *NUMAResourcesSchedulerValidator) Validate(instance *nropv1alpha1.NUMAResourcesScheduler) error {
+	if instance.Name != objectnames.DefaultNUMAResourcesSchedulerCrName {
+		return fmt.Errorf("incorrect NUMAResourcesScheduler resource name: %s, only %q is supported", instance.Name, objectnames.DefaultNUMAResourcesSchedulerCrName)
+	}
+
+	if err := validation.SchedulerImage(instance.Spec.SchedulerImage); err != nil {
+		return err
+	}
+
+	// the defaulting webhook runs first, so an empty name can only come from a missing default
+	if err := validation.SchedulerName(instance.Spec.SchedulerName); err != nil {
+		return err
+	}
+
+	for _, profile := range instance.Spec.Profiles {
+		if err := validation.SchedulerName(profile.SchedulerName); err != nil {
+			return err
+		}
+	}
+
+	// catch at admission time the failures the reconciler would otherwise report as degraded
+	cm := v.SchedulerConfigMap.DeepCopy()
+	if err := schedstate.UpdateSchedulerProfiles(cm, schedstate.ProfilesFromSpec(instance.Spec)); err != nil {
+		return fmt.Errorf("cannot set the scheduler profiles: %w", err)
+	}
+
+	return nil
+}
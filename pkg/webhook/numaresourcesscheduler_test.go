@@ -0,0 +1,219 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
+	schedmanifests "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/manifests/sched"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectnames"
+	"github.com/openshift-kni/numaresources-operator/pkg/testutils"
+)
+
+const testSchedulerImage = "quay.io/openshift-kni/scheduler-plugins:4.10-snapshot"
+
+func newTestDecoder(t *testing.T) *admission.Decoder {
+	decoder, err := admission.NewDecoder(newTestScheme(t))
+	if err != nil {
+		t.Fatalf("failed to create the decoder: %v", err)
+	}
+	return decoder
+}
+
+func TestNUMAResourcesSchedulerDefaulterHandle(t *testing.T) {
+	d := &NUMAResourcesSchedulerDefaulter{
+		DefaultSchedulerName: "topo-aware-scheduler",
+	}
+	if err := d.InjectDecoder(newTestDecoder(t)); err != nil {
+		t.Fatalf("failed to inject the decoder: %v", err)
+	}
+
+	nrs := testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "")
+	resp := d.Handle(context.TODO(), newAdmissionRequest(t, admissionv1.Create, nrs))
+	if !resp.Allowed {
+		t.Fatalf("expected the request to be allowed: %v", resp.Result)
+	}
+	if len(resp.Patches) != 1 || resp.Patches[0].Path != "/spec/schedulerName" || resp.Patches[0].Value != "topo-aware-scheduler" {
+		t.Errorf("unexpected patches: %v", resp.Patches)
+	}
+
+	nrs = testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler")
+	resp = d.Handle(context.TODO(), newAdmissionRequest(t, admissionv1.Create, nrs))
+	if !resp.Allowed {
+		t.Fatalf("expected the request to be allowed: %v", resp.Result)
+	}
+	if len(resp.Patches) != 0 {
+		t.Errorf("unexpected patches for the user-provided name: %v", resp.Patches)
+	}
+}
+
+func TestNUMAResourcesSchedulerValidatorHandle(t *testing.T) {
+	mf, err := schedmanifests.GetManifests(testNamespace)
+	if err != nil {
+		t.Fatalf("failed to load the scheduler manifests: %v", err)
+	}
+
+	brokenConfigMap := mf.ConfigMap.DeepCopy()
+	brokenConfigMap.Data = nil
+
+	negativeResync := testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler")
+	negativeResync.Spec.CacheResyncPeriod = &metav1.Duration{Duration: -time.Second}
+
+	withProfiles := testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler")
+	withProfiles.Spec.Profiles = []nropv1alpha1.SchedulerProfile{
+		{
+			SchedulerName: "my-packing-scheduler",
+			ScoringStrategy: &nropv1alpha1.ScoringStrategyParams{
+				Type: nropv1alpha1.MostAllocated,
+			},
+		},
+	}
+
+	duplicateProfile := withProfiles.DeepCopy()
+	duplicateProfile.Spec.Profiles[0].SchedulerName = "my-scheduler"
+
+	invalidProfileName := withProfiles.DeepCopy()
+	invalidProfileName.Spec.Profiles[0].SchedulerName = "My_Scheduler"
+
+	invalidSchedulerName := testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "My_Scheduler")
+	invalidSchedulerNameLabeled := invalidSchedulerName.DeepCopy()
+	invalidSchedulerNameLabeled.Labels = map[string]string{"test": "test"}
+	invalidSchedulerNameDeleted := invalidSchedulerName.DeepCopy()
+	now := metav1.Now()
+	invalidSchedulerNameDeleted.DeletionTimestamp = &now
+
+	type testCase struct {
+		name      string
+		configMap *corev1.ConfigMap
+		// the object before the update, for the update operations
+		oldNRS  *nropv1alpha1.NUMAResourcesScheduler
+		nrs     *nropv1alpha1.NUMAResourcesScheduler
+		allowed bool
+	}
+
+	testCases := []testCase{
+		{
+			name:      "valid object",
+			configMap: mf.ConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler"),
+			allowed:   true,
+		},
+		{
+			name:      "wrong name",
+			configMap: mf.ConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler("test", testSchedulerImage, "my-scheduler"),
+		},
+		{
+			name:      "missing image",
+			configMap: mf.ConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, "", "my-scheduler"),
+		},
+		{
+			name:      "invalid image",
+			configMap: mf.ConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, "quay.io/Bad Image", "my-scheduler"),
+		},
+		{
+			name:      "default scheduler name",
+			configMap: mf.ConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, corev1.DefaultSchedulerName),
+		},
+		{
+			name:      "invalid scheduler name",
+			configMap: mf.ConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "My_Scheduler"),
+		},
+		{
+			name:      "negative cache resync period",
+			configMap: mf.ConfigMap,
+			nrs:       negativeResync,
+		},
+		{
+			name:      "additional profiles",
+			configMap: mf.ConfigMap,
+			nrs:       withProfiles,
+			allowed:   true,
+		},
+		{
+			name:      "duplicate profile name",
+			configMap: mf.ConfigMap,
+			nrs:       duplicateProfile,
+		},
+		{
+			name:      "invalid profile name",
+			configMap: mf.ConfigMap,
+			nrs:       invalidProfileName,
+		},
+		{
+			name:      "update to an invalid spec",
+			configMap: mf.ConfigMap,
+			oldNRS:    testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler"),
+			nrs:       invalidSchedulerName,
+		},
+		{
+			name:      "metadata-only update of an invalid spec",
+			configMap: mf.ConfigMap,
+			oldNRS:    invalidSchedulerName,
+			nrs:       invalidSchedulerNameLabeled,
+			allowed:   true,
+		},
+		{
+			name:      "update of an object being deleted",
+			configMap: mf.ConfigMap,
+			oldNRS:    testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler"),
+			nrs:       invalidSchedulerNameDeleted,
+			allowed:   true,
+		},
+		{
+			name:      "scheduler name not settable",
+			configMap: brokenConfigMap,
+			nrs:       testutils.NewNUMAResourcesScheduler(objectnames.DefaultNUMAResourcesSchedulerCrName, testSchedulerImage, "my-scheduler"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &NUMAResourcesSchedulerValidator{
+				SchedulerConfigMap: tc.configMap,
+			}
+			if err := v.InjectDecoder(newTestDecoder(t)); err != nil {
+				t.Fatalf("failed to inject the decoder: %v", err)
+			}
+
+			req := newAdmissionRequest(t, admissionv1.Create, tc.nrs)
+			if tc.oldNRS != nil {
+				req = newUpdateAdmissionRequest(t, tc.oldNRS, tc.nrs)
+			}
+			resp := v.Handle(context.TODO(), req)
+			if resp.Allowed != tc.allowed {
+				t.Errorf("expected allowed=%v got %v (result: %v)", tc.allowed, resp.Allowed, resp.Result)
+			}
+		})
+	}
+
+	if _, ok := mf.ConfigMap.Data["config.yaml"]; !ok {
+		t.Errorf("the validation modified the scheduler config")
+	}
+}
@@ -0,0 +1,431 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+
+	nropv1alpha1 "github.com/openshift-kni/numaresources-operator/api/numaresourcesoperator/v1alpha1"
+	"github.com/openshift-kni/numaresources-operator/pkg/apply"
+	schedstate "github.com/openshift-kni/numaresources-operator/pkg/numaresourcesscheduler/objectstate/sched"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectstate"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectstate/compare"
+	"github.com/openshift-kni/numaresources-operator/pkg/objectstate/merge"
+)
+
+const (
+	// ServiceName is the name of the service exposing the operator webhooks
+	ServiceName = "numaresources-operator-webhook"
+	// SecretName is the name of the secret holding the webhook serving certificates
+	SecretName = "numaresources-operator-webhook-cert"
+	// ValidatingWebhookConfigurationName is the name of the validating webhooks registration
+	ValidatingWebhookConfigurationName = "numaresources-operator-validating-webhook"
+	// MutatingWebhookConfigurationName is the name of the defaulting webhooks registration
+	MutatingWebhookConfigurationName = "numaresources-operator-mutating-webhook"
+
+	caCertKey = "ca.crt"
+
+	// how often the running operator checks the serving certificates, well within the renewal window
+	certCheckInterval = time.Hour
+
+	// the serving pod labels, which the service selects, carry over the hash of the
+	// replicaset which created the pod: we must not select on it to survive the upgrades.
+	podTemplateHashLabel = "pod-template-hash"
+)
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;list;watch;create;update
+
+// Options tells where the webhook server runs
+type Options struct {
+	// Namespace is the namespace the operator runs into
+	Namespace string
+	// PodName is the name of the operator pod serving the webhooks
+	PodName string
+	// Port is the port the webhook server listens on
+	Port int
+	// CertDir is the directory the webhook server loads the serving certificates from
+	CertDir string
+	// EnableScheduler enables the webhooks for the NUMAResourcesScheduler objects
+	EnableScheduler bool
+}
+
+// Setup makes sure the serving certificates, the service and the webhook registration are in place.
+// Must be called before the webhook server starts, using a client which does not need the manager cache.
+func Setup(ctx context.Context, cli client.Client, opts Options) error {
+	pod := &corev1.Pod{}
+	if err := cli.Get(ctx, client.ObjectKey{Namespace: opts.Namespace, Name: opts.PodName}, pod); err != nil {
+		return errors.Wrapf(err, "failed to get the operator pod %s/%s", opts.Namespace, opts.PodName)
+	}
+	if err := applyObject(ctx, cli, NewService(opts.Namespace, pod.Labels, opts.Port)); err != nil {
+		return err
+	}
+	return syncServing(ctx, cli, opts, time.Now())
+}
+
+// CertificatesRotator renews the serving certificates while the operator runs. The secret holds the
+// certificates all the replicas serve, so each replica picks up the certificates renewed by any of them.
+type CertificatesRotator struct {
+	client   client.Client
+	opts     Options
+	interval time.Duration
+}
+
+// NewCertificatesRotator creates the rotator of the certificates Setup made. The client must not need the manager cache.
+func NewCertificatesRotator(cli client.Client, opts Options) *CertificatesRotator {
+	return &CertificatesRotator{
+		client:   cli,
+		opts:     opts,
+		interval: certCheckInterval,
+	}
+}
+
+// Start implements manager.Runnable
+func (cr *CertificatesRotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(cr.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// failures are retried on the next check, long before the certificates expire
+			if err := syncServing(ctx, cr.client, cr.opts, time.Now()); err != nil {
+				klog.ErrorS(err, "failed to sync the webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: all the replicas serve the webhooks
+func (cr *CertificatesRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// syncServing renews the certificates if needed, and makes the webhook registration and the webhook
+// server use the ones in the secret. The registration trusts the new CA before the server uses the
+// new certificate, and keeps trusting the previous CA, so the replicas can switch at different times.
+func syncServing(ctx context.Context, cli client.Client, opts Options, now time.Time) error {
+	certs, err := syncCertificates(ctx, cli, opts.Namespace, now)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sync the webhook certificates")
+	}
+
+	objs := []client.Object{
+		NewValidatingWebhookConfiguration(opts.Namespace, certs.CACert, opts.EnableScheduler),
+	}
+	if opts.EnableScheduler {
+		objs = append(objs, NewMutatingWebhookConfiguration(opts.Namespace, certs.CACert))
+	}
+	for _, desired := range objs {
+		if err := applyObject(ctx, cli, desired); err != nil {
+			return err
+		}
+	}
+
+	if err := writeCertificates(opts.CertDir, certs); err != nil {
+		return errors.Wrapf(err, "failed to write the webhook certificates")
+	}
+	return nil
+}
+
+// Register adds the webhook handlers to the manager webhook server
+func Register(mgr manager.Manager, plat platform.Platform) {
+	mgr.GetWebhookServer().Register(NUMAResourcesOperatorValidatingPath, &crwebhook.Admission{
+		Handler: &NUMAResourcesOperatorValidator{
+			Client:   mgr.GetClient(),
+			Platform: plat,
+		},
+	})
+}
+
+// RegisterScheduler adds the NUMAResourcesScheduler webhook handlers to the manager webhook server.
+// The given config map is the scheduler config the reconciler deploys.
+func RegisterScheduler(mgr manager.Manager, schedConfigMap *corev1.ConfigMap) error {
+	schedulerName, ok := schedstate.SchedulerNameFromObject(schedConfigMap)
+	if !ok {
+		return fmt.Errorf("cannot find the default scheduler name in ConfigMap %s/%s", schedConfigMap.Namespace, schedConfigMap.Name)
+	}
+
+	mgr.GetWebhookServer().Register(NUMAResourcesSchedulerDefaultingPath, &crwebhook.Admission{
+		Handler: &NUMAResourcesSchedulerDefaulter{
+			DefaultSchedulerName: schedulerName,
+		},
+	})
+	mgr.GetWebhookServer().Register(NUMAResourcesSchedulerValidatingPath, &crwebhook.Admission{
+		Handler: &NUMAResourcesSchedulerValidator{
+			SchedulerConfigMap: schedConfigMap.DeepCopy(),
+		},
+	})
+	return nil
+}
+
+// NewService creates the service routing the webhook requests to the operator pods
+func NewService(namespace string, podLabels map[string]string, port int) *corev1.Service {
+	selector := map[string]string{}
+	for key, value := range podLabels {
+		if key == podTemplateHashLabel {
+			continue
+		}
+		selector[key] = value
+	}
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceName,
+			Namespace: namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "webhook",
+					Port:       443,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(port),
+				},
+			},
+		},
+	}
+}
+
+// NewValidatingWebhookConfiguration creates the registration of the validating webhooks served by the operator
+func NewValidatingWebhookConfiguration(namespace string, caBundle []byte, withScheduler bool) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ValidatingWebhookConfiguration",
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ValidatingWebhookConfigurationName,
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{
+				Name:                    "vnumaresourcesoperator.nodetopology.openshift.io",
+				ClientConfig:            newWebhookClientConfig(namespace, NUMAResourcesOperatorValidatingPath, caBundle),
+				Rules:                   newWebhookRules("numaresourcesoperators"),
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+		},
+	}
+
+	if withScheduler {
+		vwc.Webhooks = append(vwc.Webhooks, admissionregistrationv1.ValidatingWebhook{
+			Name:                    "vnumaresourcesscheduler.nodetopology.openshift.io",
+			ClientConfig:            newWebhookClientConfig(namespace, NUMAResourcesSchedulerValidatingPath, caBundle),
+			Rules:                   newWebhookRules("numaresourcesschedulers"),
+			FailurePolicy:           &failurePolicy,
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: []string{"v1"},
+		})
+	}
+	return vwc
+}
+
+// NewMutatingWebhookConfiguration creates the registration of the defaulting webhooks served by the operator
+func NewMutatingWebhookConfiguration(namespace string, caBundle []byte) *admissionregistrationv1.MutatingWebhookConfiguration {
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "MutatingWebhookConfiguration",
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: MutatingWebhookConfigurationName,
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{
+				Name:                    "mnumaresourcesscheduler.nodetopology.openshift.io",
+				ClientConfig:            newWebhookClientConfig(namespace, NUMAResourcesSchedulerDefaultingPath, caBundle),
+				Rules:                   newWebhookRules("numaresourcesschedulers"),
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+		},
+	}
+}
+
+func newWebhookClientConfig(namespace, path string, caBundle []byte) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Name:      ServiceName,
+			Namespace: namespace,
+			Path:      &path,
+		},
+		CABundle: caBundle,
+	}
+}
+
+func newWebhookRules(resource string) []admissionregistrationv1.RuleWithOperations {
+	scope := admissionregistrationv1.ClusterScope
+	return []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{
+				admissionregistrationv1.Create,
+				admissionregistrationv1.Update,
+			},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{nropv1alpha1.GroupVersion.Group},
+				APIVersions: []string{nropv1alpha1.GroupVersion.Version},
+				Resources:   []string{resource},
+				Scope:       &scope,
+			},
+		},
+	}
+}
+
+// syncCertificates returns the certificates stored in the secret, generating new ones if they
+// are missing, invalid or about to expire.
+func syncCertificates(ctx context.Context, cli client.Client, namespace string, now time.Time) (*Certificates, error) {
+	certs, err := syncCertificatesOnce(ctx, cli, namespace, now)
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		// another replica renewed the certificates meanwhile, use them
+		klog.InfoS("webhook certificates renewed concurrently, reloading them", "reason", err)
+		return syncCertificatesOnce(ctx, cli, namespace, now)
+	}
+	return certs, err
+}
+
+func syncCertificatesOnce(ctx context.Context, cli client.Client, namespace string, now time.Time) (*Certificates, error) {
+	var previousCACert []byte
+	secret := &corev1.Secret{}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: SecretName}, secret)
+	if err == nil {
+		certs := &Certificates{
+			CACert: secret.Data[caCertKey],
+			Cert:   secret.Data[corev1.TLSCertKey],
+			Key:    secret.Data[corev1.TLSPrivateKeyKey],
+		}
+		verr := certs.Verify(ServiceName, namespace, now)
+		if verr == nil {
+			return certs, nil
+		}
+		klog.InfoS("regenerating webhook certificates", "reason", verr)
+		previousCACert = secret.Data[caCertKey]
+	}
+
+	certs, err := NewCertificates(ServiceName, namespace, now)
+	if err != nil {
+		return nil, err
+	}
+	// the replicas not switched yet still serve a certificate signed by the previous CA
+	certs.AppendPreviousCA(previousCACert, now)
+
+	desired := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SecretName,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			caCertKey:               certs.CACert,
+			corev1.TLSCertKey:       certs.Cert,
+			corev1.TLSPrivateKeyKey: certs.Key,
+		},
+	}
+	if err := applyObject(ctx, cli, desired); err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// writeCertificates writes the certificates the webhook server loads, which reloads them on change
+func writeCertificates(certDir string, certs *Certificates) error {
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		return err
+	}
+	for name, data := range map[string][]byte{
+		corev1.TLSCertKey:       certs.Cert,
+		corev1.TLSPrivateKeyKey: certs.Key,
+	} {
+		path := filepath.Join(certDir, name)
+		if current, err := ioutil.ReadFile(path); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyObject(ctx context.Context, cli client.Client, desired client.Object) error {
+	existing, ok := desired.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", desired)
+	}
+	err := cli.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if err != nil {
+		existing = nil
+	}
+
+	objState := objectstate.ObjectState{
+		Existing: existing,
+		Error:    err,
+		Desired:  desired,
+		Compare:  compare.Object,
+		Merge:    merge.ObjectForUpdate,
+	}
+	if _, ok := desired.(*corev1.Service); ok {
+		objState.Merge = merge.ServiceForUpdate
+	}
+	if err != nil && !objState.IsNotFoundError() {
+		return errors.Wrapf(err, "failed to get %s %s", desired.GetObjectKind().GroupVersionKind().Kind, desired.GetName())
+	}
+
+	if _, err := apply.ApplyObject(ctx, cli, objState); err != nil {
+		return errors.Wrapf(err, "could not apply (%s) %s/%s", desired.GetObjectKind().GroupVersionKind(), desired.GetNamespace(), desired.GetName())
+	}
+	return nil
+}
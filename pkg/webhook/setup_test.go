@@ -0,0 +1,229 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "test-namespace"
+
+func TestCertificatesVerify(t *testing.T) {
+	now := time.Now()
+	certs, err := NewCertificates(ServiceName, testNamespace, now)
+	if err != nil {
+		t.Fatalf("failed to generate the certificates: %v", err)
+	}
+
+	if err := certs.Verify(ServiceName, testNamespace, now); err != nil {
+		t.Errorf("unexpected error verifying fresh certificates: %v", err)
+	}
+	if err := certs.Verify("other-service", testNamespace, now); err == nil {
+		t.Errorf("expected error verifying the certificates for another service")
+	}
+	if err := certs.Verify(ServiceName, testNamespace, now.Add(certValidity-certRenewBefore/2)); err == nil {
+		t.Errorf("expected error verifying the certificates within the renewal window")
+	}
+
+	other, err := NewCertificates(ServiceName, testNamespace, now)
+	if err != nil {
+		t.Fatalf("failed to generate the certificates: %v", err)
+	}
+	mixed := &Certificates{
+		CACert: other.CACert,
+		Cert:   certs.Cert,
+		Key:    certs.Key,
+	}
+	if err := mixed.Verify(ServiceName, testNamespace, now); err == nil {
+		t.Errorf("expected error verifying a certificate signed by another CA")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "operator-pod",
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				"control-plane":      "controller-manager",
+				podTemplateHashLabel: "12345",
+			},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithRuntimeObjects(pod).Build()
+	opts := Options{
+		Namespace:       testNamespace,
+		PodName:         pod.Name,
+		Port:            9443,
+		CertDir:         t.TempDir(),
+		EnableScheduler: true,
+	}
+
+	if err := Setup(context.TODO(), cli, opts); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: SecretName}, secret); err != nil {
+		t.Fatalf("failed to get the secret: %v", err)
+	}
+	certData, err := ioutil.ReadFile(filepath.Join(opts.CertDir, corev1.TLSCertKey))
+	if err != nil {
+		t.Fatalf("failed to read the serving certificate: %v", err)
+	}
+	if !bytes.Equal(certData, secret.Data[corev1.TLSCertKey]) {
+		t.Errorf("the serving certificate on disk does not match the secret")
+	}
+
+	svc := &corev1.Service{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: ServiceName}, svc); err != nil {
+		t.Fatalf("failed to get the service: %v", err)
+	}
+	if !reflect.DeepEqual(svc.Spec.Selector, map[string]string{"control-plane": "controller-manager"}) {
+		t.Errorf("unexpected service selector: %v", svc.Spec.Selector)
+	}
+
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Name: ValidatingWebhookConfigurationName}, vwc); err != nil {
+		t.Fatalf("failed to get the webhook configuration: %v", err)
+	}
+	if len(vwc.Webhooks) != 2 {
+		t.Errorf("unexpected validating webhooks: %v", vwc.Webhooks)
+	}
+	for _, wh := range vwc.Webhooks {
+		if !bytes.Equal(wh.ClientConfig.CABundle, secret.Data[caCertKey]) {
+			t.Errorf("the webhook %q does not carry the CA bundle", wh.Name)
+		}
+	}
+
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Name: MutatingWebhookConfigurationName}, mwc); err != nil {
+		t.Fatalf("failed to get the mutating webhook configuration: %v", err)
+	}
+	if len(mwc.Webhooks) != 1 || *mwc.Webhooks[0].ClientConfig.Service.Path != NUMAResourcesSchedulerDefaultingPath {
+		t.Errorf("unexpected mutating webhooks: %v", mwc.Webhooks)
+	}
+
+	// a restart must reuse the certificates, or the pods would serve mismatching certificates
+	if err := Setup(context.TODO(), cli, opts); err != nil {
+		t.Fatalf("second setup failed: %v", err)
+	}
+	updatedSecret := &corev1.Secret{}
+	if err := cli.Get(context.TODO(), client.ObjectKeyFromObject(secret), updatedSecret); err != nil {
+		t.Fatalf("failed to get the secret: %v", err)
+	}
+	if !reflect.DeepEqual(secret.Data, updatedSecret.Data) {
+		t.Errorf("the certificates were regenerated while still valid")
+	}
+}
+
+func TestSyncServingRenewsCertificates(t *testing.T) {
+	now := time.Now()
+	cli := fake.NewClientBuilder().WithScheme(newTestScheme(t)).Build()
+	opts := Options{
+		Namespace: testNamespace,
+		CertDir:   t.TempDir(),
+	}
+
+	if err := syncServing(context.TODO(), cli, opts, now); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Namespace: testNamespace, Name: SecretName}, secret); err != nil {
+		t.Fatalf("failed to get the secret: %v", err)
+	}
+	oldCerts := &Certificates{
+		CACert: secret.Data[caCertKey],
+		Cert:   secret.Data[corev1.TLSCertKey],
+		Key:    secret.Data[corev1.TLSPrivateKeyKey],
+	}
+
+	// within the renewal window
+	later := now.Add(certValidity - certRenewBefore/2)
+	if err := syncServing(context.TODO(), cli, opts, later); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if err := cli.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret); err != nil {
+		t.Fatalf("failed to get the secret: %v", err)
+	}
+	certs := &Certificates{
+		CACert: secret.Data[caCertKey],
+		Cert:   secret.Data[corev1.TLSCertKey],
+		Key:    secret.Data[corev1.TLSPrivateKeyKey],
+	}
+	if bytes.Equal(certs.Cert, oldCerts.Cert) {
+		t.Fatalf("the certificates were not renewed within the renewal window")
+	}
+	if err := certs.Verify(ServiceName, testNamespace, later); err != nil {
+		t.Errorf("unexpected error verifying the renewed certificates: %v", err)
+	}
+
+	// the replicas still serving the previous certificate must keep working
+	stale := &Certificates{
+		CACert: certs.CACert,
+		Cert:   oldCerts.Cert,
+		Key:    oldCerts.Key,
+	}
+	caCerts, err := parseCertificates(stale.CACert)
+	if err != nil {
+		t.Fatalf("failed to parse the CA bundle: %v", err)
+	}
+	if len(caCerts) != 2 {
+		t.Errorf("expected the CA bundle to hold the new and the previous CA, got %d certificates", len(caCerts))
+	}
+	if err := stale.Verify(ServiceName, testNamespace, now); err != nil {
+		t.Errorf("unexpected error verifying the previous certificate against the renewed CA bundle: %v", err)
+	}
+
+	certData, err := ioutil.ReadFile(filepath.Join(opts.CertDir, corev1.TLSCertKey))
+	if err != nil {
+		t.Fatalf("failed to read the serving certificate: %v", err)
+	}
+	if !bytes.Equal(certData, certs.Cert) {
+		t.Errorf("the serving certificate on disk was not renewed")
+	}
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Name: ValidatingWebhookConfigurationName}, vwc); err != nil {
+		t.Fatalf("failed to get the webhook configuration: %v", err)
+	}
+	if !bytes.Equal(vwc.Webhooks[0].ClientConfig.CABundle, certs.CACert) {
+		t.Errorf("the webhook configuration does not carry the renewed CA bundle")
+	}
+
+	// a further renewal only carries over the CA in use
+	if err := syncServing(context.TODO(), cli, opts, later.Add(certValidity-certRenewBefore/2)); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if err := cli.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret); err != nil {
+		t.Fatalf("failed to get the secret: %v", err)
+	}
+	if caCerts, err := parseCertificates(secret.Data[caCertKey]); err != nil || len(caCerts) != 2 {
+		t.Errorf("unexpected CA bundle after the second renewal: %d certificates, error %v", len(caCerts), err)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/kubeconf"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/notification"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podreadiness"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/ratelimiter"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcetopologyexporter"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/topologypolicy"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+const (
+	// must match the zone names the resource monitor reports
+	zoneNameFormat = "node-%d"
+
+	isolatedCPUsAttribute = "isolated-cpus"
+	noHZFullCPUsAttribute = "nohz-full-cpus"
+	offlineCPUsAttribute  = "offline-cpus"
+)
+
+// zoneUpdater adds to the zones the details about the NUMA cells the resource monitor doesn't report
+type zoneUpdater struct {
+	// the online NUMA cells, sorted by ID
+	numaIDs   []int
+	numaCPUs  map[int]cpuset.CPUSet
+	cpuTuning sysinfo.CPUTuning
+}
+
+func (zu zoneUpdater) IsEmpty() bool {
+	return len(zu.numaIDs) == 0 && len(zu.numaCPUs) == 0
+}
+
+func (zu zoneUpdater) Update(zones v1alpha1.ZoneList) {
+	fixZoneCostNames(zones, zu.numaIDs)
+	setZoneCPUAttributes(zones, zu.numaCPUs, zu.cpuTuning)
+}
+
+// execute runs the exporter like resourcetopologyexporter.Execute, but updates the zones with
+// the zoneUpdater before they are published. If the zoneUpdater is empty, the zones are published
+// as the resource monitor reports them. If given, onZones gets the zones before they are published.
+// The vendored exporter offers no hook to do this, so the pipeline is assembled from its exported
+// parts: TestExecuteHandlesAllArgs fails when a vendor bump adds arguments execute doesn't know about.
+func execute(cli podresourcesapi.PodResourcesListerClient, nrtupdaterArgs nrtupdater.Args, resourcemonitorArgs resourcemonitor.Args, rteArgs resourcetopologyexporter.Args, zu zoneUpdater, onZones func(v1alpha1.ZoneList)) error {
+	tmPolicy, err := getTopologyManagerPolicy(rteArgs)
+	if err != nil {
+		return err
+	}
+
+	var condChan chan v1.PodCondition
+	if rteArgs.PodReadinessEnable {
+		condChan = make(chan v1.PodCondition)
+		condIn, err := podreadiness.NewConditionInjector()
+		if err != nil {
+			return err
+		}
+		condIn.Run(condChan)
+	}
+
+	eventSource, err := createEventSource(rteArgs)
+	if err != nil {
+		return err
+	}
+
+	resObs, err := resourcetopologyexporter.NewResourceObserver(cli, resourcemonitorArgs)
+	if err != nil {
+		return err
+	}
+	go resObs.Run(eventSource.Events(), condChan)
+
+	infos := resObs.Infos
+	if !zu.IsEmpty() || onZones != nil {
+		infoChan := make(chan nrtupdater.MonitorInfo)
+		go func() {
+			for info := range resObs.Infos {
+				if !zu.IsEmpty() {
+					zu.Update(info.Zones)
+				}
+				if onZones != nil {
+					onZones(info.Zones)
+				}
+				infoChan <- info
+			}
+		}()
+		infos = infoChan
+	}
+
+	upd := nrtupdater.NewNRTUpdater(nrtupdaterArgs, string(tmPolicy))
+	go upd.Run(infos, condChan)
+
+	go eventSource.Run()
+
+	eventSource.Wait()  // will never return
+	eventSource.Close() // still we try to clean after ourselves :)
+	return nil          // unreachable
+}
+
+// fixZoneCostNames names the costs the resource monitor reports after the online NUMA cells.
+// The resource monitor names the costs after their position in the distance vector of the cell,
+// which lists only the online cells, so the names are wrong past the first offline cell.
+// The costs not matching the online cells are left untouched.
+// TODO: drop once the vendored resource monitor names the costs after the cell IDs.
+func fixZoneCostNames(zones v1alpha1.ZoneList, numaIDs []int) {
+	for idx := range zones {
+		if len(zones[idx].Costs) != len(numaIDs) {
+			if len(zones[idx].Costs) > 0 {
+				klog.V(4).Infof("zone %q: found %d costs, expected %d", zones[idx].Name, len(zones[idx].Costs), len(numaIDs))
+			}
+			continue
+		}
+		for pos, numaID := range numaIDs {
+			zones[idx].Costs[pos].Name = fmt.Sprintf(zoneNameFormat, numaID)
+		}
+	}
+}
+
+// setZoneCPUAttributes publishes the tuned CPUs of the NUMA cells as attributes of the zones.
+// The existing attributes with the same names are replaced, the tuning kinds without CPUs in the cell are omitted.
+func setZoneCPUAttributes(zones v1alpha1.ZoneList, numaCPUs map[int]cpuset.CPUSet, cpuTuning sysinfo.CPUTuning) {
+	for idx := range zones {
+		var numaID int
+		if _, err := fmt.Sscanf(zones[idx].Name, zoneNameFormat, &numaID); err != nil {
+			continue
+		}
+		cpus, ok := numaCPUs[numaID]
+		if !ok {
+			continue
+		}
+		attrs := []v1alpha1.AttributeInfo{
+			{Name: isolatedCPUsAttribute, Value: cpuTuning.Isolated.Intersection(cpus).String()},
+			{Name: noHZFullCPUsAttribute, Value: cpuTuning.NoHZFull.Intersection(cpus).String()},
+			{Name: offlineCPUsAttribute, Value: cpuTuning.Offline.Intersection(cpus).String()},
+		}
+		for _, attr := range attrs {
+			zones[idx].Attributes = setAttribute(zones[idx].Attributes, attr)
+		}
+	}
+}
+
+// setAttribute adds or replaces the attribute, or removes it if its value is empty
+func setAttribute(attrs v1alpha1.AttributeList, attr v1alpha1.AttributeInfo) v1alpha1.AttributeList {
+	ret := make(v1alpha1.AttributeList, 0, len(attrs)+1)
+	for _, cur := range attrs {
+		if cur.Name != attr.Name {
+			ret = append(ret, cur)
+		}
+	}
+	if attr.Value != "" {
+		ret = append(ret, attr)
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+func createEventSource(rteArgs resourcetopologyexporter.Args) (notification.EventSource, error) {
+	var es notification.EventSource
+
+	eventSource, err := notification.NewUnlimitedEventSource(rteArgs.SleepInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventSource.AddFile(rteArgs.NotifyFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eventSource.AddDirs(rteArgs.KubeletStateDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	es = eventSource
+
+	// If rate limit parameters are configured set it up
+	if rteArgs.MaxEventsPerTimeUnit > 0 && rteArgs.TimeUnitToLimitEvents > 0 {
+		es, err = ratelimiter.NewRateLimitedEventSource(eventSource, uint64(rteArgs.MaxEventsPerTimeUnit), rteArgs.TimeUnitToLimitEvents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return es, nil
+}
+
+func getTopologyManagerPolicy(rteArgs resourcetopologyexporter.Args) (v1alpha1.TopologyManagerPolicy, error) {
+	if rteArgs.TopologyManagerPolicy != "" && rteArgs.TopologyManagerScope != "" {
+		klog.Infof("using given Topology Manager policy %q scope %q", rteArgs.TopologyManagerPolicy, rteArgs.TopologyManagerScope)
+		return topologypolicy.DetectTopologyPolicy(rteArgs.TopologyManagerPolicy, rteArgs.TopologyManagerScope), nil
+	}
+	if rteArgs.KubeletConfigFile != "" {
+		klConfig, err := kubeconf.GetKubeletConfigFromLocalFile(rteArgs.KubeletConfigFile)
+		if err != nil {
+			return "", fmt.Errorf("error getting topology Manager Policy: %w", err)
+		}
+		klog.Infof("detected kubelet Topology Manager policy %q scope %q", klConfig.TopologyManagerPolicy, klConfig.TopologyManagerScope)
+		return topologypolicy.DetectTopologyPolicy(klConfig.TopologyManagerPolicy, klConfig.TopologyManagerScope), nil
+	}
+	return "", fmt.Errorf("cannot find the kubelet Topology Manager policy")
+}
@@ -0,0 +1,159 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcemonitor"
+	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/resourcetopologyexporter"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+func TestFixZoneCostNames(t *testing.T) {
+	zones := v1alpha1.ZoneList{
+		{
+			Name: "node-0",
+			Type: "Node",
+			Costs: v1alpha1.CostList{
+				{Name: "node-0", Value: 10},
+				{Name: "node-1", Value: 32},
+			},
+		},
+		{
+			Name: "node-2",
+			Type: "Node",
+			Costs: v1alpha1.CostList{
+				{Name: "node-0", Value: 32},
+				{Name: "node-1", Value: 10},
+			},
+		},
+		{Name: "node-3", Type: "Node", Costs: v1alpha1.CostList{{Name: "node-0", Value: 10}}},
+		{Name: "socket-0", Type: "Socket"},
+	}
+
+	// node-1 is offline
+	fixZoneCostNames(zones, []int{0, 2})
+
+	expected := v1alpha1.ZoneList{
+		{
+			Name: "node-0",
+			Type: "Node",
+			Costs: v1alpha1.CostList{
+				{Name: "node-0", Value: 10},
+				{Name: "node-2", Value: 32},
+			},
+		},
+		{
+			Name: "node-2",
+			Type: "Node",
+			Costs: v1alpha1.CostList{
+				{Name: "node-0", Value: 32},
+				{Name: "node-2", Value: 10},
+			},
+		},
+		// costs not matching the online cells are left untouched
+		{Name: "node-3", Type: "Node", Costs: v1alpha1.CostList{{Name: "node-0", Value: 10}}},
+		{Name: "socket-0", Type: "Socket"},
+	}
+	if !reflect.DeepEqual(zones, expected) {
+		t.Errorf("got %v, want %v", zones, expected)
+	}
+}
+
+// execute mirrors resourcetopologyexporter.Execute: if this fails after a vendor bump,
+// review execute against the new upstream code, then update the expected arguments.
+func TestExecuteHandlesAllArgs(t *testing.T) {
+	var _ func(podresourcesapi.PodResourcesListerClient, nrtupdater.Args, resourcemonitor.Args, resourcetopologyexporter.Args) error = resourcetopologyexporter.Execute
+
+	expected := map[reflect.Type][]string{
+		reflect.TypeOf(nrtupdater.Args{}): {
+			"NoPublish", "Oneshot", "Hostname",
+		},
+		reflect.TypeOf(resourcetopologyexporter.Args{}): {
+			"Debug", "ReferenceContainer", "TopologyManagerPolicy", "TopologyManagerScope",
+			"KubeletConfigFile", "KubeletStateDirs", "PodResourcesSocketPath", "SleepInterval",
+			"PodReadinessEnable", "NotifyFilePath", "MaxEventsPerTimeUnit", "TimeUnitToLimitEvents",
+		},
+	}
+	for typ, names := range expected {
+		var got []string
+		for idx := 0; idx < typ.NumField(); idx++ {
+			got = append(got, typ.Field(idx).Name)
+		}
+		if !reflect.DeepEqual(got, names) {
+			t.Errorf("%s: got fields %v, want %v", typ, got, names)
+		}
+	}
+}
+
+func TestSetZoneCPUAttributes(t *testing.T) {
+	zones := v1alpha1.ZoneList{
+		{
+			Name: "node-0",
+			Type: "Node",
+			Attributes: v1alpha1.AttributeList{
+				{Name: "foo", Value: "bar"},
+				{Name: offlineCPUsAttribute, Value: "1"},
+			},
+		},
+		{Name: "node-1", Type: "Node"},
+		{Name: "node-2", Type: "Node"},
+	}
+	numaCPUs := map[int]cpuset.CPUSet{
+		0: cpuset.NewCPUSet(0, 1, 2, 3),
+		1: cpuset.NewCPUSet(4, 5, 6, 7),
+	}
+	cpuTuning := sysinfo.CPUTuning{
+		Isolated: cpuset.NewCPUSet(2, 3, 6, 7),
+		NoHZFull: cpuset.NewCPUSet(2, 3),
+		Offline:  cpuset.NewCPUSet(5),
+	}
+
+	setZoneCPUAttributes(zones, numaCPUs, cpuTuning)
+
+	expected := v1alpha1.ZoneList{
+		{
+			Name: "node-0",
+			Type: "Node",
+			Attributes: v1alpha1.AttributeList{
+				{Name: "foo", Value: "bar"},
+				{Name: isolatedCPUsAttribute, Value: "2-3"},
+				{Name: noHZFullCPUsAttribute, Value: "2-3"},
+			},
+		},
+		{
+			Name: "node-1",
+			Type: "Node",
+			Attributes: v1alpha1.AttributeList{
+				{Name: isolatedCPUsAttribute, Value: "6-7"},
+				{Name: offlineCPUsAttribute, Value: "5"},
+			},
+		},
+		// unknown cells are left untouched
+		{Name: "node-2", Type: "Node"},
+	}
+	if !reflect.DeepEqual(zones, expected) {
+		t.Errorf("got %v, want %v", zones, expected)
+	}
+}
@@ -23,6 +23,8 @@ import (
 
 	"k8s.io/klog/v2"
 
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/nrtupdater"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/podrescli"
 	"github.com/k8stopologyawareschedwg/resource-topology-exporter/pkg/prometheus"
@@ -32,14 +34,29 @@ import (
 	"github.com/openshift-kni/numaresources-operator/pkg/version"
 
 	"github.com/openshift-kni/numaresources-operator/rte/pkg/config"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/debug"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/metrics"
 	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
 	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
 )
 
+// the number of failed podresources API polls after which the debug server reports the exporter unhealthy
+const debugUnhealthyPolls = 3
+
 type localArgs struct {
-	SysConf             sysinfo.Config
-	ConfigPath          string
-	ExitOnConfigChanges bool
+	SysConf sysinfo.Config
+	// SysConfOverrides holds the settings given in the command line, which take precedence over the configuration file
+	SysConfOverrides sysinfo.Config
+	// Config is the effective configuration: the configuration file with the command line settings applied
+	Config config.Config
+	// ResourcesMode tells how the sysinfo resources are combined with the kubelet ones
+	ResourcesMode         podrescompat.Mode
+	ConfigPath            string
+	ExitOnConfigChanges   bool
+	ReloadOnConfigChanges bool
+	MetricsTLS            metrics.TLSConfig
+	DebugServerAddress    string
+	HealthServerAddress   string
 }
 
 type ProgArgs struct {
@@ -49,6 +66,9 @@ type ProgArgs struct {
 	LocalArgs       localArgs
 	Version         bool
 	SysinfoOnly     bool
+	SysinfoOutput   string
+	SysinfoCapture  string
+	SysinfoReplay   string
 }
 
 func main() {
@@ -62,17 +82,43 @@ func main() {
 		os.Exit(0)
 	}
 
+	if parsedArgs.SysinfoCapture != "" {
+		err = sysinfo.CaptureSnapshot(parsedArgs.SysinfoCapture, parsedArgs.Resourcemonitor.SysfsRoot)
+		if err != nil {
+			klog.Fatalf("failed to capture the system info snapshot: %v", err)
+		}
+		klog.Infof("system info snapshot captured in %q", parsedArgs.SysinfoCapture)
+		os.Exit(0)
+	}
+
+	if parsedArgs.SysinfoReplay != "" {
+		err = replaySysinfo(os.Stdout, parsedArgs.SysinfoReplay, parsedArgs.LocalArgs.SysConf)
+		if err != nil {
+			klog.Fatalf("failed to replay the system info snapshot: %v", err)
+		}
+		os.Exit(0)
+	}
+
 	// only for debug purposes
 	// printing the header so early includes any debug message from the sysinfo package
 	klog.Infof("=== System information ===\n")
-	sysInfo, err := sysinfo.NewSysinfo(parsedArgs.LocalArgs.SysConf)
+	hnd := sysinfo.Handle{Sysfs: parsedArgs.Resourcemonitor.SysfsRoot}
+	sysInfo, err := sysinfo.NewSysinfoFromHandle(parsedArgs.LocalArgs.SysConf, hnd)
 	if err != nil {
 		klog.Fatalf("failed to query system info: %v", err)
 	}
 	klog.Infof("\n%s", sysInfo)
+	zu := makeZoneUpdater(hnd)
 	klog.Infof("==========================\n")
 
 	if parsedArgs.SysinfoOnly {
+		if parsedArgs.SysinfoOutput != "" {
+			data, err := sysInfo.Report().Render(parsedArgs.SysinfoOutput)
+			if err != nil {
+				klog.Fatalf("failed to render the system info: %v", err)
+			}
+			fmt.Println(strings.TrimSpace(string(data)))
+		}
 		os.Exit(0)
 	}
 
@@ -81,9 +127,24 @@ func main() {
 		klog.Fatalf("failed to start prometheus server: %v", err)
 	}
 
+	var debugState *debug.State
+	if parsedArgs.LocalArgs.DebugServerAddress != "" || parsedArgs.LocalArgs.HealthServerAddress != "" {
+		// tolerate a few failed polls before asking for a restart
+		debugState = debug.NewState(parsedArgs.LocalArgs.Config, debugUnhealthyPolls*parsedArgs.RTE.SleepInterval)
+		k8sCli = debugState.TrackConnectivity(k8sCli)
+	}
+
+	var reloadCli *podrescompat.ReloadableClient
 	sysCli := k8sCli
-	if !parsedArgs.LocalArgs.SysConf.IsEmpty() {
-		sysCli = podrescompat.NewSysinfoClientFromLister(k8sCli, parsedArgs.LocalArgs.SysConf)
+	if parsedArgs.LocalArgs.ReloadOnConfigChanges {
+		reloadCli = podrescompat.NewReloadableClientFromLister(k8sCli, parsedArgs.NRTupdater.Hostname, hnd, parsedArgs.LocalArgs.SysConf, parsedArgs.LocalArgs.ResourcesMode, parsedArgs.Resourcemonitor.ExcludeList.ExcludeList)
+		sysCli = reloadCli
+		// the client applies the exclude list, because the resource monitor cannot change it once running
+		parsedArgs.Resourcemonitor.ExcludeList = resourcemonitor.ResourceExcludeList{}
+		// the allocatable resources must be fetched again to pick up the configuration changes
+		parsedArgs.Resourcemonitor.RefreshNodeResources = true
+	} else if !parsedArgs.LocalArgs.SysConf.IsEmpty() || parsedArgs.LocalArgs.ResourcesMode == podrescompat.ModeMerge {
+		sysCli = podrescompat.NewSysinfoClientFromLister(k8sCli, hnd, parsedArgs.LocalArgs.SysConf, parsedArgs.LocalArgs.ResourcesMode)
 	}
 
 	cli, err := podrescli.NewFilteringClientFromLister(sysCli, parsedArgs.RTE.Debug, parsedArgs.RTE.ReferenceContainer)
@@ -91,11 +152,42 @@ func main() {
 		klog.Fatalf("failed to get podresources filtering client: %v", err)
 	}
 
+	var onZones func(v1alpha1.ZoneList)
+	if debugState != nil {
+		cli = debugState.TrackAllocatable(cli)
+		if sr, ok := sysCli.(podrescompat.SourcesReporter); ok {
+			debugState.SetSourcesReporter(sr)
+		}
+		onZones = debugState.SetZones
+		if parsedArgs.LocalArgs.DebugServerAddress != "" {
+			getSysInfo := func(conf sysinfo.Config) (sysinfo.SysInfo, error) {
+				return sysinfo.NewSysinfoFromHandle(conf, hnd)
+			}
+			err = debug.Serve(parsedArgs.LocalArgs.DebugServerAddress, debug.NewHandler(debugState, getSysInfo))
+			if err != nil {
+				klog.Fatalf("failed to start the debug server: %v", err)
+			}
+		}
+		if parsedArgs.LocalArgs.HealthServerAddress != "" {
+			err = debug.Serve(parsedArgs.LocalArgs.HealthServerAddress, debug.NewHealthHandler(debugState))
+			if err != nil {
+				klog.Fatalf("failed to start the health server: %v", err)
+			}
+		}
+	}
+
 	err = prometheus.InitPrometheus()
 	if err != nil {
 		klog.Fatalf("failed to start prometheus server: %v", err)
 	}
 
+	if !parsedArgs.LocalArgs.MetricsTLS.IsEmpty() {
+		err = metrics.ServeTLS(parsedArgs.LocalArgs.MetricsTLS)
+		if err != nil {
+			klog.Fatalf("failed to start prometheus TLS server: %v", err)
+		}
+	}
+
 	if parsedArgs.LocalArgs.ExitOnConfigChanges {
 		cw, err := config.NewWatcher(parsedArgs.LocalArgs.ConfigPath, func() error {
 			klog.Infof("configuration file %q changed: exit", parsedArgs.LocalArgs.ConfigPath)
@@ -108,11 +200,54 @@ func main() {
 		go cw.WaitUntilChanges()
 	}
 
-	err = resourcetopologyexporter.Execute(cli, parsedArgs.NRTupdater, parsedArgs.Resourcemonitor, parsedArgs.RTE)
+	if parsedArgs.LocalArgs.ReloadOnConfigChanges {
+		rl, err := newConfigReloader(parsedArgs.LocalArgs, reloadCli)
+		if err != nil {
+			klog.Fatalf("cannot read the configuration file %q: %v", parsedArgs.LocalArgs.ConfigPath, err)
+		}
+		if debugState != nil {
+			rl.onReload = debugState.SetConfig
+		}
+		cw, err := config.NewWatcher(parsedArgs.LocalArgs.ConfigPath, rl.Reload)
+		if err != nil {
+			klog.Fatalf("cannot watch the configuration file %q: %v", parsedArgs.LocalArgs.ConfigPath, err)
+		}
+		go cw.WaitUntilChanges()
+	}
+
+	err = execute(cli, parsedArgs.NRTupdater, parsedArgs.Resourcemonitor, parsedArgs.RTE, zu, onZones)
 	// must never execute; if it does, we want to know
 	klog.Fatalf("failed to execute: %v", err)
 }
 
+// makeZoneUpdater collects the NUMA cells details to add to the zones.
+// The failures are not fatal: the affected details are left as the resource monitor reports them.
+func makeZoneUpdater(hnd sysinfo.Handle) zoneUpdater {
+	var zu zoneUpdater
+	var err error
+
+	numaDistances, err := sysinfo.GetNUMADistances(hnd.SysfsRoot())
+	if err != nil {
+		klog.Warningf("failed to query the NUMA distances: %v", err)
+	} else {
+		klog.Infof("\n%s", numaDistances)
+		zu.numaIDs = numaDistances.SortedIDs()
+	}
+
+	zu.numaCPUs, err = sysinfo.GetNUMACPUs(hnd.SysfsRoot())
+	if err != nil {
+		klog.Warningf("failed to query the NUMA cpus: %v", err)
+		return zu
+	}
+	zu.cpuTuning, err = sysinfo.GetCPUTuning(hnd.SysfsRoot(), hnd.ProcfsRoot())
+	if err != nil {
+		klog.Warningf("failed to query the tuned cpus: %v", err)
+		// without the tuned cpus, the attributes would be wrongly cleared
+		zu.numaCPUs = nil
+	}
+	return zu
+}
+
 // The args is passed only for testing purposes.
 func parseArgs(args ...string) (ProgArgs, error) {
 	pArgs := ProgArgs{}
@@ -153,12 +288,23 @@ func parseArgs(args ...string) (ProgArgs, error) {
 	pArgs.RTE.TimeUnitToLimitEvents = time.Second
 
 	flags.StringVar(&sysReservedCPUs, "system-info-reserved-cpus", "", "kubelet reserved CPUs (cpuset format: 0,1 or 0,1-3 ...)")
-	flags.StringVar(&sysReservedMemory, "system-info-reserved-memory", "", "kubelet reserved memory: comma-separated 'numaID=amount' (example: '0=16Gi,1=8192Mi,3=1Gi')")
-	flags.StringVar(&sysResourceMapping, "system-info-resource-mapping", "", "kubelet resource mapping: comma-separated 'vendor:device=resourcename'")
+	flags.StringVar(&sysReservedMemory, "system-info-reserved-memory", "", "kubelet reserved memory: comma-separated 'numaID[:memorytype]=amount', memorytype defaults to memory (example: '0=16Gi,1=8192Mi,0:hugepages-1Gi=2Gi,1:hugepages-2Mi=512Mi')")
+	flags.StringVar(&sysResourceMapping, "system-info-resource-mapping", "", "kubelet resource mapping: comma-separated 'selector=resourcename', the selector being either 'vendor[:device]' or '+'-separated terms among 'vendor:', 'device:', 'class:', 'driver:', 'addr:', 'pfaddr:', 'pf' and 'vf' (example: 'vendor:8086+device:154c+driver:vfio-pci=intel_sriov_dpdk')")
 	flags.BoolVar(&pArgs.SysinfoOnly, "system-info", false, "Output detected system info and exit")
+	flags.StringVar(&pArgs.SysinfoOutput, "system-info-output", "", "Output detected system info to stdout in the given format (json or yaml) and exit. Implies --system-info.")
+	flags.StringVar(&pArgs.SysinfoCapture, "system-info-capture", "", "Capture in the given tarball the host files the system info is detected from, and exit")
+	flags.StringVar(&pArgs.SysinfoReplay, "system-info-replay", "", "Detect the system info from the given tarball captured with --system-info-capture, output the allocatable resources as JSON and exit")
 
 	flags.BoolVar(&pArgs.Version, "version", false, "Output version and exit")
 	flags.BoolVar(&pArgs.LocalArgs.ExitOnConfigChanges, "exit-on-conf-change", false, "Exits when configuration file changes - so the supervisor can restart")
+	flags.BoolVar(&pArgs.LocalArgs.ReloadOnConfigChanges, "reload-on-conf-change", false, "Reloads the configuration when the configuration file changes, without restarting. Exits only if the topology manager settings change.")
+
+	flags.IntVar(&pArgs.LocalArgs.MetricsTLS.Port, "metrics-tls-port", 2113, "Port to serve the metrics over TLS, if the certificate and the key are given.")
+	flags.StringVar(&pArgs.LocalArgs.MetricsTLS.CertFile, "metrics-tls-cert", "", "Certificate file to serve the metrics over TLS.")
+	flags.StringVar(&pArgs.LocalArgs.MetricsTLS.KeyFile, "metrics-tls-key", "", "Private key file to serve the metrics over TLS.")
+
+	flags.StringVar(&pArgs.LocalArgs.DebugServerAddress, "debug-server-address", "", "Loopback address (host:port) to serve the debug endpoints on: the system info, the configuration, the allocatable resources and the zones as JSON, and the podresources API health checks. Disabled if empty.")
+	flags.StringVar(&pArgs.LocalArgs.HealthServerAddress, "health-server-address", "", "Address (host:port) to serve only the podresources API health checks on, like for the kubelet probes. Disabled if empty.")
 
 	err := flags.Parse(args)
 	if err != nil {
@@ -169,6 +315,25 @@ func parseArgs(args ...string) (ProgArgs, error) {
 		return pArgs, err
 	}
 
+	if pArgs.LocalArgs.ExitOnConfigChanges && pArgs.LocalArgs.ReloadOnConfigChanges {
+		return pArgs, fmt.Errorf("--exit-on-conf-change and --reload-on-conf-change are mutually exclusive")
+	}
+
+	if pArgs.SysinfoOutput != "" {
+		if !sysinfo.IsValidReportFormat(pArgs.SysinfoOutput) {
+			return pArgs, fmt.Errorf("unsupported system info output format %q", pArgs.SysinfoOutput)
+		}
+		pArgs.SysinfoOnly = true
+	}
+
+	if pArgs.LocalArgs.DebugServerAddress != "" && !debug.IsLoopbackAddress(pArgs.LocalArgs.DebugServerAddress) {
+		return pArgs, fmt.Errorf("--debug-server-address must be a loopback address, got %q", pArgs.LocalArgs.DebugServerAddress)
+	}
+
+	if pArgs.SysinfoCapture != "" && pArgs.SysinfoReplay != "" {
+		return pArgs, fmt.Errorf("--system-info-capture and --system-info-replay are mutually exclusive")
+	}
+
 	pArgs.RTE.KubeletStateDirs, err = setKubeletStateDirs(*kubeletStateDirs)
 	if err != nil {
 		return pArgs, err
@@ -190,17 +355,15 @@ func parseArgs(args ...string) (ProgArgs, error) {
 		pArgs.Resourcemonitor.ExcludeList.ExcludeList = conf.ExcludeList
 		klog.V(2).Infof("using exclude list:\n%s", pArgs.Resourcemonitor.ExcludeList.String())
 	}
-	pArgs.LocalArgs.SysConf = conf.Resources
-
-	// override from the command line
-	if sysReservedCPUs != "" {
-		pArgs.LocalArgs.SysConf.ReservedCPUs = sysReservedCPUs
-	}
-	if rmap := sysinfo.ResourceMappingFromString(sysResourceMapping); len(rmap) > 0 {
-		pArgs.LocalArgs.SysConf.ResourceMapping = rmap
+	pArgs.LocalArgs.SysConfOverrides = sysinfo.Config{
+		ReservedCPUs:    sysReservedCPUs,
+		ResourceMapping: sysinfo.ResourceMappingFromString(sysResourceMapping),
+		ReservedMemory:  sysinfo.ReservedMemoryFromString(sysReservedMemory),
 	}
-	if rmap := sysinfo.ReservedMemoryFromString(sysReservedMemory); len(rmap) > 0 {
-		pArgs.LocalArgs.SysConf.ReservedMemory = rmap
+	pArgs.LocalArgs.SysConf = overrideSysConf(conf.Resources, pArgs.LocalArgs.SysConfOverrides)
+	pArgs.LocalArgs.ResourcesMode, err = podrescompat.ModeFromString(conf.ResourcesMode)
+	if err != nil {
+		return pArgs, fmt.Errorf("error getting the resources mode from the configuration: %v", err)
 	}
 
 	klog.Infof("using sysinfo:\n%s", pArgs.LocalArgs.SysConf.ToYAMLString())
@@ -215,9 +378,29 @@ func parseArgs(args ...string) (ProgArgs, error) {
 		pArgs.RTE.TopologyManagerScope = conf.TopologyManagerScope
 	}
 
+	conf.ExcludeList = pArgs.Resourcemonitor.ExcludeList.ExcludeList
+	conf.Resources = pArgs.LocalArgs.SysConf
+	conf.TopologyManagerPolicy = pArgs.RTE.TopologyManagerPolicy
+	conf.TopologyManagerScope = pArgs.RTE.TopologyManagerScope
+	pArgs.LocalArgs.Config = conf
+
 	return pArgs, nil
 }
 
+// overrideSysConf returns the sysinfo config from the configuration file, with the non-empty overrides replacing its values
+func overrideSysConf(conf, overrides sysinfo.Config) sysinfo.Config {
+	if overrides.ReservedCPUs != "" {
+		conf.ReservedCPUs = overrides.ReservedCPUs
+	}
+	if len(overrides.ResourceMapping) > 0 {
+		conf.ResourceMapping = overrides.ResourceMapping
+	}
+	if len(overrides.ReservedMemory) > 0 {
+		conf.ReservedMemory = overrides.ReservedMemory
+	}
+	return conf
+}
+
 func defaultHostName() string {
 	var err error
 
@@ -26,10 +26,13 @@ import (
 )
 
 type Config struct {
-	ExcludeList           map[string][]string `json:"excludeList,omitempty"`
-	Resources             sysinfo.Config      `json:"resources,omitempty"`
-	TopologyManagerPolicy string              `json:"topologyManagerPolicy,omitempty"`
-	TopologyManagerScope  string              `json:"topologyManagerScope,omitempty"`
+	ExcludeList map[string][]string `json:"excludeList,omitempty"`
+	Resources   sysinfo.Config      `json:"resources,omitempty"`
+	// how the Resources are combined with the allocatable resources kubelet reports:
+	// "fallback" (default) uses them only if kubelet fails, "merge" fills in the resource types kubelet leaves out
+	ResourcesMode         string `json:"resourcesMode,omitempty"`
+	TopologyManagerPolicy string `json:"topologyManagerPolicy,omitempty"`
+	TopologyManagerScope  string `json:"topologyManagerScope,omitempty"`
 }
 
 func ReadConfig(configPath string) (Config, error) {
@@ -67,6 +67,9 @@ func TestReadValidData(t *testing.T) {
 	if cfg.Resources.ResourceMapping["8086:1520"] != "intel_sriov_netdevice" {
 		t.Errorf("unexpected values: %#v", cfg)
 	}
+	if cfg.ResourcesMode != "merge" {
+		t.Errorf("unexpected values: %#v", cfg)
+	}
 	if cfg.ExcludeList["masternode"][0] != "memory" {
 		t.Errorf("unexpected values: %#v", cfg)
 	}
@@ -76,6 +79,7 @@ const testData string = `resources:
   reservedcpus: "0"
   resourcemapping:
     "8086:1520": "intel_sriov_netdevice"
+resourcesmode: "merge"
 topologymanagerpolicy: "restricted"
 topologymanagerscope: "pod"
 excludelist:
@@ -15,14 +15,20 @@ limitations under the License.
 package config
 
 import (
+	"path/filepath"
+
 	"github.com/fsnotify/fsnotify"
 
 	"k8s.io/klog/v2"
 )
 
+// Watcher notifies the changes of the configuration file.
+// It watches the directory containing the file rather than the file itself, so the watch survives
+// the file being replaced, like the atomic symlink swaps the kubelet does to update the ConfigMap volumes.
 type Watcher struct {
 	watcher    *fsnotify.Watcher
 	configPath string
+	realPath   string
 	stopChan   chan struct{}
 	callback   func() error
 }
@@ -34,9 +40,12 @@ func NewWatcher(configPath string, callback func() error) (*Watcher, error) {
 		return nil, err
 	}
 
-	err = watcher.Add(configPath)
+	configPath = filepath.Clean(configPath)
+	configDir := filepath.Dir(configPath)
+	err = watcher.Add(configDir)
 	if err != nil {
-		klog.Warningf("config watch: failed to watch configuration file %q: %v", configPath, err)
+		klog.Warningf("config watch: failed to watch configuration directory %q: %v", configDir, err)
+		watcher.Close()
 		return nil, err
 	}
 	klog.Infof("config watch: added %q", configPath)
@@ -44,6 +53,7 @@ func NewWatcher(configPath string, callback func() error) (*Watcher, error) {
 	return &Watcher{
 		watcher:    watcher,
 		configPath: configPath,
+		realPath:   resolvePath(configPath),
 		callback:   callback,
 		stopChan:   make(chan struct{}),
 	}, nil
@@ -57,8 +67,8 @@ func (cw *Watcher) Stop() {
 	cw.stopChan <- struct{}{}
 }
 
-// WaitUntilChanges wait until it notices the first change of the config file.
-// It nevers rearm itself, so it fires at most once.
+// WaitUntilChanges waits for the changes of the config file, calling the callback for each of them, until stopped.
+// A single update of the file may trigger the callback more than once.
 // Make sure this run on a separate (not main) goroutine: see https://github.com/fsnotify/fsnotify#faq
 func (cw *Watcher) WaitUntilChanges() {
 	for {
@@ -68,13 +78,12 @@ func (cw *Watcher) WaitUntilChanges() {
 
 		case event := <-cw.watcher.Events:
 			klog.V(2).Infof("config watch: fsnotify event from %q: %v", event.Name, event.Op)
-			if filterEvent(event) {
-				err := cw.callback()
-				if err != nil {
-					klog.Warning("config watch: callback failed for %q: %v", cw.configPath, err)
-				}
-				// we would need to rearm the watch, which we don't yet. So exit.
-				return
+			if !cw.isChanged(event) {
+				continue
+			}
+			err := cw.callback()
+			if err != nil {
+				klog.Warningf("config watch: callback failed for %q: %v", cw.configPath, err)
 			}
 
 		case err := <-cw.watcher.Errors:
@@ -84,9 +93,39 @@ func (cw *Watcher) WaitUntilChanges() {
 	}
 }
 
+// isChanged tells if the event changed the config file, either directly or by moving the symlinks pointing to it
+func (cw *Watcher) isChanged(event fsnotify.Event) bool {
+	realPath := resolvePath(cw.configPath)
+	if realPath != cw.realPath {
+		klog.V(2).Infof("config watch: %q now points to %q", cw.configPath, realPath)
+		cw.realPath = realPath
+		return true
+	}
+	if filepath.Clean(event.Name) != cw.configPath {
+		return false
+	}
+	return filterEvent(event)
+}
+
 func filterEvent(event fsnotify.Event) bool {
 	if (event.Op & fsnotify.Write) == fsnotify.Write {
 		return true
 	}
+	if (event.Op & fsnotify.Create) == fsnotify.Create {
+		return true
+	}
+	if (event.Op & fsnotify.Rename) == fsnotify.Rename {
+		return true
+	}
 	return (event.Op & fsnotify.Remove) == fsnotify.Remove
 }
+
+// resolvePath returns the file the path points to after following the symlinks,
+// or an empty string if the file does not exist.
+func resolvePath(path string) string {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+	return realPath
+}
@@ -16,7 +16,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"k8s.io/klog/v2"
 )
@@ -83,6 +85,59 @@ func TestWatchWritesInPlace(t *testing.T) {
 	}
 }
 
+func TestWatchSymlinkSwaps(t *testing.T) {
+	dir := t.TempDir()
+	// mimic the layout of the ConfigMap volumes
+	swapData := func(content string) {
+		tsDir, err := os.MkdirTemp(dir, "..ts")
+		if err != nil {
+			t.Fatalf("MkdirTemp failed: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(tsDir, "config.yaml"), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		tmpLink := filepath.Join(dir, "..data_tmp")
+		if err := os.Symlink(filepath.Base(tsDir), tmpLink); err != nil {
+			t.Fatalf("Symlink failed: %v", err)
+		}
+		if err := os.Rename(tmpLink, filepath.Join(dir, "..data")); err != nil {
+			t.Fatalf("Rename failed: %v", err)
+		}
+	}
+
+	swapData("initial content")
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), configPath); err != nil {
+		t.Fatalf("Symlink failed: %v", err)
+	}
+
+	changeChan := make(chan bool, 16)
+	cw, err := NewWatcher(configPath, func() error {
+		changeChan <- true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot watch %q: %v", configPath, err)
+	}
+	defer cw.Close()
+	go cw.WaitUntilChanges()
+
+	for _, content := range []string{"first update", "second update"} {
+		swapData(content)
+
+		select {
+		case <-changeChan:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("failed to detect change (symlink swap to %q)", content)
+		}
+		// drain the notifications of the same update
+		time.Sleep(100 * time.Millisecond)
+		for len(changeChan) > 0 {
+			<-changeChan
+		}
+	}
+}
+
 func writeTempFile(content string) (string, error) {
 	f, err := os.CreateTemp("", "testwatchconf")
 	if err != nil {
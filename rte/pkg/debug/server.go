@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"k8s.io/klog/v2"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+const (
+	HealthzPath     = "/healthz"
+	ReadyzPath      = "/readyz"
+	SysinfoPath     = "/sysinfo"
+	ConfigPath      = "/config"
+	AllocatablePath = "/allocatable"
+	ZonesPath       = "/zones"
+)
+
+// AllocatableReport is the allocatable resources response along with the sources of the resources
+type AllocatableReport struct {
+	Response *podresourcesapi.AllocatableResourcesResponse `json:"response"`
+	Sources  map[string]podrescompat.Source                `json:"sources,omitempty"`
+}
+
+// NewHandler returns the handler serving the state as JSON, and the health endpoints.
+// The system information is computed on each request, from the current configuration.
+// The state includes the host details, so the handler must be served on a loopback address only.
+func NewHandler(st *State, getSysInfo func(sysinfo.Config) (sysinfo.SysInfo, error)) http.Handler {
+	mux := http.NewServeMux()
+	handleChecks(mux, st)
+	mux.HandleFunc(SysinfoPath, func(w http.ResponseWriter, r *http.Request) {
+		sysInfo, err := getSysInfo(st.Config().Resources)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to query system info: %v", err), http.StatusInternalServerError)
+			return
+		}
+		serveJSON(w, sysInfo.Report())
+	})
+	mux.HandleFunc(ConfigPath, func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(w, st.Config())
+	})
+	mux.HandleFunc(AllocatablePath, func(w http.ResponseWriter, r *http.Request) {
+		resp, sources := st.Allocatable()
+		serveJSON(w, AllocatableReport{Response: resp, Sources: sources})
+	})
+	mux.HandleFunc(ZonesPath, func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(w, st.Zones())
+	})
+	return mux
+}
+
+// NewHealthHandler returns the handler serving only the health endpoints, safe to serve on any address
+func NewHealthHandler(st *State) http.Handler {
+	mux := http.NewServeMux()
+	handleChecks(mux, st)
+	return mux
+}
+
+func handleChecks(mux *http.ServeMux, st *State) {
+	mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		serveCheck(w, st.Healthy())
+	})
+	mux.HandleFunc(ReadyzPath, func(w http.ResponseWriter, r *http.Request) {
+		serveCheck(w, st.Ready())
+	})
+}
+
+// IsLoopbackAddress tells if the address (host:port) can be reached only from the local host
+func IsLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Serve serves the handler on the given address. The address is bound before returning,
+// so the errors like the address being in use are reported to the caller.
+func Serve(addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler: handler,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			klog.Fatalf("failed to run the debug server: %v", err)
+		}
+	}()
+	klog.Infof("serving the debug endpoints on %s", ln.Addr())
+	return nil
+}
+
+func serveCheck(w http.ResponseWriter, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func serveJSON(w http.ResponseWriter, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode the response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,194 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/config"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
+)
+
+// State collects what the exporter computed last, and the outcome of the podresources API calls
+type State struct {
+	// the podresources API is considered unhealthy once failing continuously for longer than this
+	unhealthyAfter time.Duration
+	// replaceable for testing purposes
+	now func() time.Time
+
+	lock        sync.RWMutex
+	conf        config.Config
+	allocatable *podresourcesapi.AllocatableResourcesResponse
+	sources     podrescompat.SourcesReporter
+	zones       v1alpha1.ZoneList
+	lastSuccess time.Time
+	// zero if the last call succeeded
+	failingSince time.Time
+	lastError    error
+}
+
+func NewState(conf config.Config, unhealthyAfter time.Duration) *State {
+	return &State{
+		conf:           conf,
+		unhealthyAfter: unhealthyAfter,
+		now:            time.Now,
+	}
+}
+
+// SetConfig replaces the effective configuration, like when the configuration file is reloaded
+func (st *State) SetConfig(conf config.Config) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.conf = conf
+}
+
+func (st *State) Config() config.Config {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.conf
+}
+
+// SetSourcesReporter sets where to learn the sources of the allocatable resources from
+func (st *State) SetSourcesReporter(sr podrescompat.SourcesReporter) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.sources = sr
+}
+
+// Allocatable returns the last allocatable resources response, and the sources of the resources if known
+func (st *State) Allocatable() (*podresourcesapi.AllocatableResourcesResponse, map[string]podrescompat.Source) {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	if st.sources == nil {
+		return st.allocatable, nil
+	}
+	return st.allocatable, st.sources.AllocatableSources()
+}
+
+// SetZones records the zones computed last. The zones are copied, because they are updated once published.
+func (st *State) SetZones(zones v1alpha1.ZoneList) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.zones = zones.DeepCopy()
+}
+
+func (st *State) Zones() v1alpha1.ZoneList {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.zones
+}
+
+// Ready tells if the last podresources API call succeeded
+func (st *State) Ready() error {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	if st.lastError != nil {
+		return fmt.Errorf("podresources API failing since %s: %w", st.failingSince.Format(time.RFC3339), st.lastError)
+	}
+	if st.lastSuccess.IsZero() {
+		return fmt.Errorf("podresources API not queried yet")
+	}
+	return nil
+}
+
+// Healthy tells if the podresources API calls are not failing for too long
+func (st *State) Healthy() error {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	if st.lastError == nil || st.now().Sub(st.failingSince) <= st.unhealthyAfter {
+		return nil
+	}
+	return fmt.Errorf("podresources API failing since %s: %w", st.failingSince.Format(time.RFC3339), st.lastError)
+}
+
+func (st *State) recordCall(err error) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if err == nil {
+		st.lastSuccess = st.now()
+		st.failingSince = time.Time{}
+		st.lastError = nil
+		return
+	}
+	if st.lastError == nil {
+		st.failingSince = st.now()
+	}
+	st.lastError = err
+}
+
+func (st *State) recordAllocatable(resp *podresourcesapi.AllocatableResourcesResponse) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.allocatable = resp
+}
+
+// TrackConnectivity wraps the client talking to kubelet to record the outcome of all the calls
+func (st *State) TrackConnectivity(cli podresourcesapi.PodResourcesListerClient) podresourcesapi.PodResourcesListerClient {
+	return &connectivityClient{
+		cli: cli,
+		st:  st,
+	}
+}
+
+// TrackAllocatable wraps the client the exporter uses to record the allocatable resources it gets
+func (st *State) TrackAllocatable(cli podresourcesapi.PodResourcesListerClient) podresourcesapi.PodResourcesListerClient {
+	return &allocatableClient{
+		cli: cli,
+		st:  st,
+	}
+}
+
+type connectivityClient struct {
+	cli podresourcesapi.PodResourcesListerClient
+	st  *State
+}
+
+func (cc *connectivityClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	resp, err := cc.cli.List(ctx, in, opts...)
+	cc.st.recordCall(err)
+	return resp, err
+}
+
+func (cc *connectivityClient) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	resp, err := cc.cli.GetAllocatableResources(ctx, in, opts...)
+	cc.st.recordCall(err)
+	return resp, err
+}
+
+type allocatableClient struct {
+	cli podresourcesapi.PodResourcesListerClient
+	st  *State
+}
+
+func (ac *allocatableClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return ac.cli.List(ctx, in, opts...)
+}
+
+func (ac *allocatableClient) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	resp, err := ac.cli.GetAllocatableResources(ctx, in, opts...)
+	if err == nil {
+		ac.st.recordAllocatable(resp)
+	}
+	return resp, err
+}
@@ -0,0 +1,257 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/config"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+type fakeLister struct {
+	resp *podresourcesapi.AllocatableResourcesResponse
+	err  error
+}
+
+func (fl *fakeLister) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return &podresourcesapi.ListPodResourcesResponse{}, fl.err
+}
+
+func (fl *fakeLister) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	if fl.err != nil {
+		return nil, fl.err
+	}
+	return fl.resp, nil
+}
+
+type fakeSourcesReporter map[string]podrescompat.Source
+
+func (fsr fakeSourcesReporter) AllocatableSources() map[string]podrescompat.Source {
+	return fsr
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	return fc.now
+}
+
+func TestStateChecks(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st := NewState(config.Config{}, 30*time.Second)
+	st.now = clock.Now
+
+	fl := &fakeLister{resp: &podresourcesapi.AllocatableResourcesResponse{}}
+	cli := st.TrackConnectivity(fl)
+
+	if err := st.Ready(); err == nil {
+		t.Errorf("expected not ready before any call")
+	}
+	if err := st.Healthy(); err != nil {
+		t.Errorf("expected healthy before any call, got %v", err)
+	}
+
+	if _, err := cli.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := st.Ready(); err != nil {
+		t.Errorf("expected ready after a successful call, got %v", err)
+	}
+
+	fl.err = errors.New("connection refused")
+	cli.List(context.TODO(), &podresourcesapi.ListPodResourcesRequest{})
+	if err := st.Ready(); err == nil {
+		t.Errorf("expected not ready after a failed call")
+	}
+	if err := st.Healthy(); err != nil {
+		t.Errorf("expected healthy right after a failed call, got %v", err)
+	}
+
+	clock.now = clock.now.Add(20 * time.Second)
+	cli.List(context.TODO(), &podresourcesapi.ListPodResourcesRequest{})
+	if err := st.Healthy(); err != nil {
+		t.Errorf("expected healthy while failing shorter than the threshold, got %v", err)
+	}
+
+	clock.now = clock.now.Add(20 * time.Second)
+	cli.List(context.TODO(), &podresourcesapi.ListPodResourcesRequest{})
+	if err := st.Healthy(); err == nil {
+		t.Errorf("expected unhealthy while failing longer than the threshold")
+	}
+
+	fl.err = nil
+	cli.List(context.TODO(), &podresourcesapi.ListPodResourcesRequest{})
+	if err := st.Healthy(); err != nil {
+		t.Errorf("expected healthy once recovered, got %v", err)
+	}
+	if err := st.Ready(); err != nil {
+		t.Errorf("expected ready once recovered, got %v", err)
+	}
+}
+
+func TestStateTrackAllocatable(t *testing.T) {
+	st := NewState(config.Config{}, time.Minute)
+	resp := &podresourcesapi.AllocatableResourcesResponse{
+		CpuIds: []int64{1, 2, 3},
+	}
+	fl := &fakeLister{resp: resp}
+	cli := st.TrackAllocatable(fl)
+
+	if got, _ := st.Allocatable(); got != nil {
+		t.Errorf("expected no allocatable response before any call, got %v", got)
+	}
+	if _, err := cli.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fl.err = errors.New("connection refused")
+	cli.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+	// the last successful response is kept
+	if got, sources := st.Allocatable(); got != resp || sources != nil {
+		t.Errorf("unexpected allocatable response %v sources %v", got, sources)
+	}
+
+	st.SetSourcesReporter(fakeSourcesReporter{"cpu": podrescompat.SourceKubelet})
+	if _, sources := st.Allocatable(); sources["cpu"] != podrescompat.SourceKubelet {
+		t.Errorf("unexpected sources %v", sources)
+	}
+}
+
+func TestStateSetZonesCopies(t *testing.T) {
+	st := NewState(config.Config{}, time.Minute)
+	zones := v1alpha1.ZoneList{
+		{Name: "node-0", Type: "Node"},
+	}
+	st.SetZones(zones)
+	zones[0].Name = "node-1"
+	if got := st.Zones(); got[0].Name != "node-0" {
+		t.Errorf("unexpected zones %v", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	st := NewState(config.Config{ExcludeList: map[string][]string{"*": {"memory"}}}, time.Minute)
+	st.SetZones(v1alpha1.ZoneList{{Name: "node-0", Type: "Node"}})
+	getSysInfo := func(conf sysinfo.Config) (sysinfo.SysInfo, error) {
+		return sysinfo.SysInfo{}, nil
+	}
+	srv := httptest.NewServer(NewHandler(st, getSysInfo))
+	defer srv.Close()
+
+	testCases := []struct {
+		path         string
+		expectedCode int
+	}{
+		{path: HealthzPath, expectedCode: http.StatusOK},
+		// no podresources API call yet
+		{path: ReadyzPath, expectedCode: http.StatusServiceUnavailable},
+		{path: SysinfoPath, expectedCode: http.StatusOK},
+		{path: ConfigPath, expectedCode: http.StatusOK},
+		{path: AllocatablePath, expectedCode: http.StatusOK},
+		{path: ZonesPath, expectedCode: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectedCode {
+				t.Errorf("expected status %d got %d", tc.expectedCode, resp.StatusCode)
+			}
+		})
+	}
+
+	resp, err := http.Get(srv.URL + ConfigPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var conf config.Config
+	if err := json.NewDecoder(resp.Body).Decode(&conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(conf.ExcludeList["*"]) != 1 || conf.ExcludeList["*"][0] != "memory" {
+		t.Errorf("unexpected config %+v", conf)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	st := NewState(config.Config{}, time.Minute)
+	srv := httptest.NewServer(NewHealthHandler(st))
+	defer srv.Close()
+
+	testCases := []struct {
+		path         string
+		expectedCode int
+	}{
+		{path: HealthzPath, expectedCode: http.StatusOK},
+		{path: ReadyzPath, expectedCode: http.StatusServiceUnavailable},
+		// the state is served only by the debug handler
+		{path: SysinfoPath, expectedCode: http.StatusNotFound},
+		{path: ConfigPath, expectedCode: http.StatusNotFound},
+		{path: AllocatablePath, expectedCode: http.StatusNotFound},
+		{path: ZonesPath, expectedCode: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectedCode {
+				t.Errorf("expected status %d got %d", tc.expectedCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	testCases := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "127.0.0.1:2114", expected: true},
+		{addr: "[::1]:2114", expected: true},
+		{addr: "localhost:2114", expected: true},
+		{addr: ":2114", expected: false},
+		{addr: "0.0.0.0:2114", expected: false},
+		{addr: "10.0.0.1:2114", expected: false},
+		{addr: "127.0.0.1", expected: false},
+	}
+	for _, tc := range testCases {
+		if got := IsLoopbackAddress(tc.addr); got != tc.expected {
+			t.Errorf("%q: got %v, want %v", tc.addr, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+type TLSConfig struct {
+	Port     int
+	CertFile string
+	KeyFile  string
+}
+
+func (conf TLSConfig) IsEmpty() bool {
+	return conf.CertFile == "" && conf.KeyFile == ""
+}
+
+func (conf TLSConfig) Validate() error {
+	if conf.CertFile == "" || conf.KeyFile == "" {
+		return fmt.Errorf("both the certificate and the key are required to serve the metrics over TLS")
+	}
+	if conf.Port <= 0 {
+		return fmt.Errorf("invalid metrics TLS port %d", conf.Port)
+	}
+	return nil
+}
+
+// ServeTLS serves the metrics endpoint, registered on the default HTTP mux, over TLS.
+// The key pair is reloaded when the files change, so the certificate can be rotated
+// (e.g. by the OpenShift service CA) without restarting the process.
+func ServeTLS(conf TLSConfig) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	kp := NewKeyPairReloader(conf.CertFile, conf.KeyFile)
+	if _, err := kp.GetCertificate(nil); err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr: fmt.Sprintf("0.0.0.0:%d", conf.Port),
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: kp.GetCertificate,
+		},
+	}
+
+	go func() {
+		// the certificate comes from the TLS config
+		if err := srv.ListenAndServeTLS("", ""); err != nil {
+			klog.Fatalf("failed to run the prometheus TLS server: %v", err)
+		}
+	}()
+
+	klog.Infof("serving the metrics over TLS on %s", srv.Addr)
+	return nil
+}
+
+// KeyPairReloader loads the key pair again once the certificate file changes.
+type KeyPairReloader struct {
+	certFile string
+	keyFile  string
+
+	lock    sync.Mutex
+	modTime time.Time
+	cert    *tls.Certificate
+}
+
+func NewKeyPairReloader(certFile, keyFile string) *KeyPairReloader {
+	return &KeyPairReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+func (kp *KeyPairReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	st, err := os.Stat(kp.certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	kp.lock.Lock()
+	defer kp.lock.Unlock()
+
+	if kp.cert != nil && st.ModTime().Equal(kp.modTime) {
+		return kp.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		if kp.cert != nil {
+			// the files may be in the middle of an update, keep serving the previous certificate
+			klog.Warningf("failed to reload the metrics key pair: %v", err)
+			return kp.cert, nil
+		}
+		return nil, err
+	}
+
+	klog.V(2).Infof("loaded the metrics key pair from %q", kp.certFile)
+	kp.cert = &cert
+	kp.modTime = st.ModTime()
+	return kp.cert, nil
+}
@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift-kni/numaresources-operator/pkg/webhook"
+)
+
+func TestKeyPairReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	kp := NewKeyPairReloader(certFile, keyFile)
+	if _, err := kp.GetCertificate(nil); err == nil {
+		t.Fatalf("expected failure with missing key pair")
+	}
+
+	now := time.Now()
+	initial := writeKeyPair(t, certFile, keyFile, now)
+	cert, err := kp.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("cannot load the key pair: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], initial.Certificate[0]) {
+		t.Fatalf("unexpected certificate loaded")
+	}
+
+	// keep serving the loaded certificate while the update is in progress
+	if err := ioutil.WriteFile(keyFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(certFile, now, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	cert, err = kp.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected failure with a broken key pair: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], initial.Certificate[0]) {
+		t.Fatalf("expected the previous certificate to be served")
+	}
+
+	rotated := writeKeyPair(t, certFile, keyFile, now.Add(time.Hour))
+	if err := os.Chtimes(certFile, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	cert, err = kp.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("cannot reload the key pair: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], rotated.Certificate[0]) {
+		t.Fatalf("expected the rotated certificate to be served")
+	}
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		conf        TLSConfig
+		expectError bool
+	}{
+		{
+			name: "complete",
+			conf: TLSConfig{Port: 2113, CertFile: "tls.crt", KeyFile: "tls.key"},
+		},
+		{
+			name:        "missing key",
+			conf:        TLSConfig{Port: 2113, CertFile: "tls.crt"},
+			expectError: true,
+		},
+		{
+			name:        "missing port",
+			conf:        TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.conf.Validate()
+			if (err != nil) != tc.expectError {
+				t.Errorf("expected error=%v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func writeKeyPair(t *testing.T, certFile, keyFile string, now time.Time) *tls.Certificate {
+	certs, err := webhook.NewCertificates("rte", "test", now)
+	if err != nil {
+		t.Fatalf("cannot generate the certificates: %v", err)
+	}
+	if err := ioutil.WriteFile(certFile, certs.Cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, certs.Key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := NewKeyPairReloader(certFile, keyFile).GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
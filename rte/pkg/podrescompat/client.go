@@ -16,6 +16,11 @@ package podrescompat
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"google.golang.org/grpc"
 
@@ -25,15 +30,70 @@ import (
 	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
 )
 
+// Mode tells how the sysinfo client combines the allocatable resources kubelet reports with the sysinfo ones
+type Mode string
+
+const (
+	// ModeFallback reports the sysinfo resources only if kubelet fails to report the allocatable resources
+	ModeFallback Mode = "fallback"
+	// ModeMerge also fills in the resource types kubelet leaves out, like the memory and the hugepages
+	// when the kubelet memory manager is not enabled
+	ModeMerge Mode = "merge"
+)
+
+// ModeFromString parses the mode, the empty string being the default ModeFallback
+func ModeFromString(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeFallback:
+		return ModeFallback, nil
+	case ModeMerge:
+		return ModeMerge, nil
+	default:
+		return "", fmt.Errorf("unsupported mode %q", s)
+	}
+}
+
+// Source tells where an allocatable resource comes from
+type Source string
+
+const (
+	SourceKubelet Source = "kubelet"
+	SourceSysinfo Source = "sysinfo"
+)
+
+// SourcesReporter is implemented by the clients which know where the allocatable resources they last reported come from
+type SourcesReporter interface {
+	// AllocatableSources maps the resource names, "cpu" included, to their sources
+	AllocatableSources() map[string]Source
+}
+
 type sysinfoClient struct {
-	sysConf sysinfo.Config
-	cli     podresourcesapi.PodResourcesListerClient
+	sysConf    sysinfo.Config
+	mode       Mode
+	cli        podresourcesapi.PodResourcesListerClient
+	getSysInfo func() (sysinfo.SysInfo, error)
+
+	lock    sync.Mutex
+	sources map[string]Source
+
+	sysInfoLock sync.Mutex
+	sysInfo     *sysinfo.SysInfo
 }
 
-func NewSysinfoClientFromLister(cli podresourcesapi.PodResourcesListerClient, sysConf sysinfo.Config) podresourcesapi.PodResourcesListerClient {
+// NewSysinfoClientFromLister returns the client computing the allocatable resources from the system information
+// read through the Handle, as the mode tells
+func NewSysinfoClientFromLister(cli podresourcesapi.PodResourcesListerClient, hnd sysinfo.Handle, sysConf sysinfo.Config, mode Mode) podresourcesapi.PodResourcesListerClient {
+	return newSysinfoClient(cli, sysConf, mode, func() (sysinfo.SysInfo, error) {
+		return sysinfo.NewSysinfoFromHandle(sysConf, hnd)
+	})
+}
+
+func newSysinfoClient(cli podresourcesapi.PodResourcesListerClient, sysConf sysinfo.Config, mode Mode, getSysInfo func() (sysinfo.SysInfo, error)) *sysinfoClient {
 	return &sysinfoClient{
-		cli:     cli,
-		sysConf: sysConf,
+		cli:        cli,
+		sysConf:    sysConf,
+		mode:       mode,
+		getSysInfo: getSysInfo,
 	}
 }
 
@@ -50,19 +110,138 @@ func (sc *sysinfoClient) GetAllocatableResources(ctx context.Context, in *podres
 			klog.Warningf("sysinfo makeAllocatableResourcesResponse failed with %v", sysErr)
 			return resp, err
 		}
+		sc.recordSources(sourcesOf(sysResp, SourceSysinfo))
 		return sysResp, nil
 	}
-	return resp, nil
+	if sc.mode != ModeMerge {
+		sc.recordSources(sourcesOf(resp, SourceKubelet))
+		return resp, nil
+	}
+
+	sysResp, sysErr := sc.makeAllocatableResourcesResponse()
+	if sysErr != nil {
+		klog.Warningf("sysinfo makeAllocatableResourcesResponse failed with %v - using podresourcesapi only", sysErr)
+		sc.recordSources(sourcesOf(resp, SourceKubelet))
+		return resp, nil
+	}
+	merged, sources := MergeAllocatableResources(resp, sysResp)
+	sc.recordSources(sources)
+	return merged, nil
+}
+
+func (sc *sysinfoClient) AllocatableSources() map[string]Source {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	ret := make(map[string]Source, len(sc.sources))
+	for name, source := range sc.sources {
+		ret[name] = source
+	}
+	return ret
+}
+
+// recordSources stores the sources of the last response, and logs them when they change
+func (sc *sysinfoClient) recordSources(sources map[string]Source) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	if reflect.DeepEqual(sources, sc.sources) {
+		return
+	}
+	sc.sources = sources
+	klog.Infof("podresources: allocatable resources sources: %s", sourcesToString(sources))
 }
 
 func (sc *sysinfoClient) makeAllocatableResourcesResponse() (*podresourcesapi.AllocatableResourcesResponse, error) {
-	sysInfo, err := sysinfo.NewSysinfo(sc.sysConf)
+	sysInfo, err := sc.cachedSysInfo()
 	if err != nil {
 		return nil, err
 	}
 	return MakeAllocatableResourcesResponseFromSysInfo(sysInfo), nil
 }
 
+// cachedSysInfo computes the system information on first use only, because the computation scans the whole
+// hardware. The client is replaced when the configuration is reloaded, so the system information is computed
+// again then. The failures are not cached.
+func (sc *sysinfoClient) cachedSysInfo() (sysinfo.SysInfo, error) {
+	sc.sysInfoLock.Lock()
+	defer sc.sysInfoLock.Unlock()
+	if sc.sysInfo != nil {
+		return *sc.sysInfo, nil
+	}
+	sysInfo, err := sc.getSysInfo()
+	if err != nil {
+		return sysInfo, err
+	}
+	sc.sysInfo = &sysInfo
+	return sysInfo, nil
+}
+
+// MergeAllocatableResources fills in the kubelet response the resource types it leaves out with the sysinfo ones.
+// The resource types kubelet reports, even partially, are never changed. Returns the merged response and the
+// source of each resource.
+func MergeAllocatableResources(kubeResp, sysResp *podresourcesapi.AllocatableResourcesResponse) (*podresourcesapi.AllocatableResourcesResponse, map[string]Source) {
+	merged := &podresourcesapi.AllocatableResourcesResponse{
+		CpuIds:  kubeResp.GetCpuIds(),
+		Devices: append([]*podresourcesapi.ContainerDevices{}, kubeResp.GetDevices()...),
+		Memory:  append([]*podresourcesapi.ContainerMemory{}, kubeResp.GetMemory()...),
+	}
+	sources := sourcesOf(kubeResp, SourceKubelet)
+
+	if len(merged.CpuIds) == 0 && len(sysResp.GetCpuIds()) > 0 {
+		merged.CpuIds = sysResp.GetCpuIds()
+		sources["cpu"] = SourceSysinfo
+	}
+	for _, dev := range sysResp.GetDevices() {
+		if sources[dev.GetResourceName()] == SourceKubelet {
+			continue
+		}
+		merged.Devices = append(merged.Devices, dev)
+		sources[dev.GetResourceName()] = SourceSysinfo
+	}
+	for _, mem := range sysResp.GetMemory() {
+		if sources[mem.GetMemoryType()] == SourceKubelet {
+			continue
+		}
+		merged.Memory = append(merged.Memory, mem)
+		sources[mem.GetMemoryType()] = SourceSysinfo
+	}
+
+	if len(merged.Devices) == 0 {
+		merged.Devices = nil
+	}
+	if len(merged.Memory) == 0 {
+		merged.Memory = nil
+	}
+	return merged, sources
+}
+
+// sourcesOf reports all the resources in the response as coming from the given source
+func sourcesOf(resp *podresourcesapi.AllocatableResourcesResponse, source Source) map[string]Source {
+	sources := make(map[string]Source)
+	if len(resp.GetCpuIds()) > 0 {
+		sources["cpu"] = source
+	}
+	for _, dev := range resp.GetDevices() {
+		sources[dev.GetResourceName()] = source
+	}
+	for _, mem := range resp.GetMemory() {
+		sources[mem.GetMemoryType()] = source
+	}
+	return sources
+}
+
+func sourcesToString(sources map[string]Source) string {
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var items []string
+	for _, name := range names {
+		items = append(items, fmt.Sprintf("%s=%s", name, sources[name]))
+	}
+	return strings.Join(items, ",")
+}
+
 func MakeAllocatableResourcesResponseFromSysInfo(sysInfo sysinfo.SysInfo) *podresourcesapi.AllocatableResourcesResponse {
 	resp := podresourcesapi.AllocatableResourcesResponse{
 		CpuIds: sysInfo.CPUs.ToSliceInt64(),
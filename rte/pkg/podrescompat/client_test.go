@@ -15,6 +15,8 @@ limitations under the License.
 package podrescompat
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -65,3 +67,219 @@ func TestMakeAllocatableResourcesResponseFromSysInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeAllocatableResources(t *testing.T) {
+	topo := func(numaID int64) *podresourcesapi.TopologyInfo {
+		return &podresourcesapi.TopologyInfo{
+			Nodes: []*podresourcesapi.NUMANode{
+				{ID: numaID},
+			},
+		}
+	}
+	kubeNICs := &podresourcesapi.ContainerDevices{ResourceName: "intel_nics", DeviceIds: []string{"0000:00:02.0"}, Topology: topo(0)}
+	sysNICs := &podresourcesapi.ContainerDevices{ResourceName: "intel_nics", DeviceIds: []string{"0000:00:02.0", "0000:00:02.1"}, Topology: topo(0)}
+	sysGPUs := &podresourcesapi.ContainerDevices{ResourceName: "gpus", DeviceIds: []string{"0000:00:03.0"}, Topology: topo(1)}
+	kubeMemory := &podresourcesapi.ContainerMemory{MemoryType: "memory", Size_: 1024, Topology: topo(0)}
+	sysMemory := &podresourcesapi.ContainerMemory{MemoryType: "memory", Size_: 2048, Topology: topo(0)}
+	sysHugepages0 := &podresourcesapi.ContainerMemory{MemoryType: "hugepages-2Mi", Size_: 4194304, Topology: topo(0)}
+	sysHugepages1 := &podresourcesapi.ContainerMemory{MemoryType: "hugepages-2Mi", Size_: 2097152, Topology: topo(1)}
+
+	sysResp := &podresourcesapi.AllocatableResourcesResponse{
+		CpuIds:  []int64{1, 2, 3},
+		Devices: []*podresourcesapi.ContainerDevices{sysNICs, sysGPUs},
+		Memory:  []*podresourcesapi.ContainerMemory{sysMemory, sysHugepages0, sysHugepages1},
+	}
+
+	var testCases = []struct {
+		name            string
+		kubeResp        *podresourcesapi.AllocatableResourcesResponse
+		expected        *podresourcesapi.AllocatableResourcesResponse
+		expectedSources map[string]Source
+	}{
+		{
+			"kubelet reports nothing",
+			&podresourcesapi.AllocatableResourcesResponse{},
+			sysResp,
+			map[string]Source{
+				"cpu":           SourceSysinfo,
+				"intel_nics":    SourceSysinfo,
+				"gpus":          SourceSysinfo,
+				"memory":        SourceSysinfo,
+				"hugepages-2Mi": SourceSysinfo,
+			},
+		},
+		{
+			"kubelet without memory manager",
+			&podresourcesapi.AllocatableResourcesResponse{
+				CpuIds:  []int64{0, 1, 2, 3},
+				Devices: []*podresourcesapi.ContainerDevices{kubeNICs},
+			},
+			&podresourcesapi.AllocatableResourcesResponse{
+				CpuIds:  []int64{0, 1, 2, 3},
+				Devices: []*podresourcesapi.ContainerDevices{kubeNICs, sysGPUs},
+				Memory:  []*podresourcesapi.ContainerMemory{sysMemory, sysHugepages0, sysHugepages1},
+			},
+			map[string]Source{
+				"cpu":           SourceKubelet,
+				"intel_nics":    SourceKubelet,
+				"gpus":          SourceSysinfo,
+				"memory":        SourceSysinfo,
+				"hugepages-2Mi": SourceSysinfo,
+			},
+		},
+		{
+			"kubelet reports memory but not hugepages",
+			&podresourcesapi.AllocatableResourcesResponse{
+				CpuIds: []int64{0, 1, 2, 3},
+				Memory: []*podresourcesapi.ContainerMemory{kubeMemory},
+			},
+			&podresourcesapi.AllocatableResourcesResponse{
+				CpuIds:  []int64{0, 1, 2, 3},
+				Devices: []*podresourcesapi.ContainerDevices{sysNICs, sysGPUs},
+				Memory:  []*podresourcesapi.ContainerMemory{kubeMemory, sysHugepages0, sysHugepages1},
+			},
+			map[string]Source{
+				"cpu":           SourceKubelet,
+				"intel_nics":    SourceSysinfo,
+				"gpus":          SourceSysinfo,
+				"memory":        SourceKubelet,
+				"hugepages-2Mi": SourceSysinfo,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, sources := MergeAllocatableResources(testCase.kubeResp, sysResp)
+			if !reflect.DeepEqual(got, testCase.expected) {
+				t.Errorf("got %v, want %v", got, testCase.expected)
+			}
+			if !reflect.DeepEqual(sources, testCase.expectedSources) {
+				t.Errorf("got sources %v, want %v", sources, testCase.expectedSources)
+			}
+		})
+	}
+}
+
+func TestSysinfoClientModes(t *testing.T) {
+	kubeResp := &podresourcesapi.AllocatableResourcesResponse{
+		CpuIds: []int64{0, 1, 2, 3},
+	}
+	getSysInfo := func() (sysinfo.SysInfo, error) {
+		return sysinfo.SysInfo{
+			CPUs: cpuset.MustParse("1-3"),
+			Memory: map[string]sysinfo.PerNUMACounters{
+				"memory": {0: 2048},
+			},
+		}, nil
+	}
+
+	var testCases = []struct {
+		name            string
+		mode            Mode
+		kubeErr         error
+		expectedCPUs    []int64
+		expectedSources map[string]Source
+	}{
+		{
+			"fallback with kubelet",
+			ModeFallback,
+			nil,
+			[]int64{0, 1, 2, 3},
+			map[string]Source{"cpu": SourceKubelet},
+		},
+		{
+			"fallback without kubelet",
+			ModeFallback,
+			fmt.Errorf("kubelet unavailable"),
+			[]int64{1, 2, 3},
+			map[string]Source{"cpu": SourceSysinfo, "memory": SourceSysinfo},
+		},
+		{
+			"merge with kubelet",
+			ModeMerge,
+			nil,
+			[]int64{0, 1, 2, 3},
+			map[string]Source{"cpu": SourceKubelet, "memory": SourceSysinfo},
+		},
+		{
+			"merge without kubelet",
+			ModeMerge,
+			fmt.Errorf("kubelet unavailable"),
+			[]int64{1, 2, 3},
+			map[string]Source{"cpu": SourceSysinfo, "memory": SourceSysinfo},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cli := fakeLister{
+				allocatable: kubeResp,
+				err:         testCase.kubeErr,
+			}
+			sc := newSysinfoClient(cli, sysinfo.Config{}, testCase.mode, getSysInfo)
+			got, err := sc.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.GetCpuIds(), testCase.expectedCPUs) {
+				t.Errorf("got cpus %v, want %v", got.GetCpuIds(), testCase.expectedCPUs)
+			}
+			if sources := sc.AllocatableSources(); !reflect.DeepEqual(sources, testCase.expectedSources) {
+				t.Errorf("got sources %v, want %v", sources, testCase.expectedSources)
+			}
+		})
+	}
+}
+
+func TestSysinfoClientCachesSysInfo(t *testing.T) {
+	calls := 0
+	var sysErr error
+	getSysInfo := func() (sysinfo.SysInfo, error) {
+		calls++
+		return sysinfo.SysInfo{CPUs: cpuset.MustParse("1-3")}, sysErr
+	}
+	cli := fakeLister{
+		allocatable: &podresourcesapi.AllocatableResourcesResponse{},
+	}
+	sc := newSysinfoClient(cli, sysinfo.Config{}, ModeMerge, getSysInfo)
+
+	// failures are not cached
+	sysErr = fmt.Errorf("scan failed")
+	sc.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+	sysErr = nil
+	for i := 0; i < 3; i++ {
+		got, err := sc.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got.GetCpuIds(), []int64{1, 2, 3}) {
+			t.Errorf("got cpus %v", got.GetCpuIds())
+		}
+	}
+	if calls != 2 {
+		t.Errorf("system information computed %d times, expected 2", calls)
+	}
+}
+
+func TestModeFromString(t *testing.T) {
+	var testCases = []struct {
+		value    string
+		expected Mode
+		fails    bool
+	}{
+		{"", ModeFallback, false},
+		{"fallback", ModeFallback, false},
+		{"merge", ModeMerge, false},
+		{"replace", "", true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.value, func(t *testing.T) {
+			got, err := ModeFromString(testCase.value)
+			if (err != nil) != testCase.fails {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != testCase.expected {
+				t.Errorf("got %q, want %q", got, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podrescompat
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+// the exclude list key matching all the nodes
+const excludeListAllNodes = "*"
+
+// ReloadableClient applies to the allocatable resources the sysinfo config and the exclude list,
+// which can be replaced while the client is in use, to reload the configuration without restarting.
+type ReloadableClient struct {
+	cli      podresourcesapi.PodResourcesListerClient
+	nodeName string
+	hnd      sysinfo.Handle
+
+	lock     sync.RWMutex
+	sysCli   podresourcesapi.PodResourcesListerClient
+	excluded sets.String
+}
+
+func NewReloadableClientFromLister(cli podresourcesapi.PodResourcesListerClient, nodeName string, hnd sysinfo.Handle, sysConf sysinfo.Config, mode Mode, excludeList map[string][]string) *ReloadableClient {
+	rc := &ReloadableClient{
+		cli:      cli,
+		nodeName: nodeName,
+		hnd:      hnd,
+	}
+	rc.Update(sysConf, mode, excludeList)
+	return rc
+}
+
+// Update replaces the sysinfo config, the sysinfo mode and the exclude list used from the next request on
+func (rc *ReloadableClient) Update(sysConf sysinfo.Config, mode Mode, excludeList map[string][]string) {
+	sysCli := rc.cli
+	if !sysConf.IsEmpty() || mode == ModeMerge {
+		sysCli = NewSysinfoClientFromLister(rc.cli, rc.hnd, sysConf, mode)
+	}
+	excluded := sets.NewString(excludeList[excludeListAllNodes]...)
+	excluded.Insert(excludeList[rc.nodeName]...)
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.sysCli = sysCli
+	rc.excluded = excluded
+	klog.V(2).Infof("podresources: using sysinfo in %s mode:\n%s", mode, sysConf.ToYAMLString())
+	klog.V(2).Infof("podresources: excluding resources %v", excluded.List())
+}
+
+func (rc *ReloadableClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return rc.cli.List(ctx, in, opts...)
+}
+
+func (rc *ReloadableClient) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	rc.lock.RLock()
+	sysCli := rc.sysCli
+	excluded := rc.excluded
+	rc.lock.RUnlock()
+
+	resp, err := sysCli.GetAllocatableResources(ctx, in, opts...)
+	if err != nil || excluded.Len() == 0 {
+		return resp, err
+	}
+	return filterAllocatableResources(resp, excluded), nil
+}
+
+// AllocatableSources reports the sources of the resources last reported by the sysinfo client, if any.
+// The exclude list is not taken into account.
+func (rc *ReloadableClient) AllocatableSources() map[string]Source {
+	rc.lock.RLock()
+	sysCli := rc.sysCli
+	rc.lock.RUnlock()
+
+	sr, ok := sysCli.(SourcesReporter)
+	if !ok {
+		return nil
+	}
+	return sr.AllocatableSources()
+}
+
+// filterAllocatableResources drops the excluded resources, so they are not reported
+func filterAllocatableResources(resp *podresourcesapi.AllocatableResourcesResponse, excluded sets.String) *podresourcesapi.AllocatableResourcesResponse {
+	ret := &podresourcesapi.AllocatableResourcesResponse{}
+	if !excluded.Has("cpu") {
+		ret.CpuIds = resp.GetCpuIds()
+	}
+	for _, dev := range resp.GetDevices() {
+		if excluded.Has(dev.GetResourceName()) {
+			continue
+		}
+		ret.Devices = append(ret.Devices, dev)
+	}
+	for _, mem := range resp.GetMemory() {
+		if excluded.Has(mem.GetMemoryType()) {
+			continue
+		}
+		ret.Memory = append(ret.Memory, mem)
+	}
+	return ret
+}
@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podrescompat
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+type fakeLister struct {
+	allocatable *podresourcesapi.AllocatableResourcesResponse
+	err         error
+}
+
+func (fl fakeLister) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return &podresourcesapi.ListPodResourcesResponse{}, nil
+}
+
+func (fl fakeLister) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	return fl.allocatable, fl.err
+}
+
+func TestReloadableClientExcludeList(t *testing.T) {
+	nics := &podresourcesapi.ContainerDevices{
+		ResourceName: "intel_nics",
+		DeviceIds:    []string{"0000:00:02.0"},
+	}
+	gpus := &podresourcesapi.ContainerDevices{
+		ResourceName: "gpus",
+		DeviceIds:    []string{"0000:00:03.0"},
+	}
+	memory := &podresourcesapi.ContainerMemory{
+		MemoryType: "memory",
+		Size_:      1024,
+	}
+	cli := fakeLister{
+		allocatable: &podresourcesapi.AllocatableResourcesResponse{
+			CpuIds:  []int64{0, 1, 2, 3},
+			Devices: []*podresourcesapi.ContainerDevices{nics, gpus},
+			Memory:  []*podresourcesapi.ContainerMemory{memory},
+		},
+	}
+
+	rc := NewReloadableClientFromLister(cli, "node-0", sysinfo.Handle{}, sysinfo.Config{}, ModeFallback, nil)
+	got, err := rc.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, cli.allocatable) {
+		t.Errorf("got %v, want %v", got, cli.allocatable)
+	}
+
+	rc.Update(sysinfo.Config{}, ModeFallback, map[string][]string{
+		"*":      {"memory"},
+		"node-0": {"gpus", "cpu"},
+		"node-1": {"intel_nics"},
+	})
+	got, err = rc.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &podresourcesapi.AllocatableResourcesResponse{
+		Devices: []*podresourcesapi.ContainerDevices{nics},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+const (
+	DefaultSysfsRoot  = "/sys"
+	DefaultProcfsRoot = "/proc"
+
+	// relative to the sysfs root
+	SysDevicesCPUs = "devices/system/cpu"
+	// relative to the procfs root
+	ProcCmdline = "cmdline"
+)
+
+// the isolcpus flags which can precede the cpu list, see the kernel-parameters documentation
+var isolcpusFlags = map[string]bool{
+	"nohz":        true,
+	"domain":      true,
+	"managed_irq": true,
+}
+
+// CPUTuning holds the CPUs the kernel set apart from the general purpose ones
+type CPUTuning struct {
+	// isolated from the scheduler load balancing (isolcpus)
+	Isolated cpuset.CPUSet
+	// running without the scheduler tick when possible (nohz_full)
+	NoHZFull cpuset.CPUSet
+	Offline  cpuset.CPUSet
+}
+
+func (ct CPUTuning) String() string {
+	return fmt.Sprintf("cpus: isolated %q nohz_full %q offline %q\n", ct.Isolated.String(), ct.NoHZFull.String(), ct.Offline.String())
+}
+
+// GetCPUTuning discovers the tuned CPUs merging the sysfs, which reports the settings in effect,
+// and the kernel command line, because the sysfs only reports the domain isolation of isolcpus.
+// The missing files are not errors, because their presence depends on the kernel configuration.
+func GetCPUTuning(sysfsRoot, procfsRoot string) (CPUTuning, error) {
+	cpusDir := filepath.Join(sysfsRoot, SysDevicesCPUs)
+	var ct CPUTuning
+	var err error
+
+	ct.Isolated, err = readCPUList(filepath.Join(cpusDir, "isolated"))
+	if err != nil {
+		return ct, err
+	}
+	ct.NoHZFull, err = readCPUList(filepath.Join(cpusDir, "nohz_full"))
+	if err != nil {
+		return ct, err
+	}
+	ct.Offline, err = readCPUList(filepath.Join(cpusDir, "offline"))
+	if err != nil {
+		return ct, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(procfsRoot, ProcCmdline))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			klog.V(2).Infof("cpus: missing kernel command line, skipped")
+			return ct, nil
+		}
+		return ct, err
+	}
+	isolated, nohzFull, err := CPUTuningFromCmdline(string(data))
+	if err != nil {
+		return ct, err
+	}
+	ct.Isolated = ct.Isolated.Union(isolated)
+	ct.NoHZFull = ct.NoHZFull.Union(nohzFull)
+	return ct, nil
+}
+
+// CPUTuningFromCmdline returns the isolcpus and nohz_full CPUs set in the kernel command line
+func CPUTuningFromCmdline(cmdline string) (cpuset.CPUSet, cpuset.CPUSet, error) {
+	isolated := cpuset.NewCPUSet()
+	nohzFull := cpuset.NewCPUSet()
+	for _, param := range strings.Fields(cmdline) {
+		items := strings.SplitN(param, "=", 2)
+		if len(items) != 2 {
+			continue
+		}
+		switch items[0] {
+		case "isolcpus":
+			cpus, err := parseIsolcpus(items[1])
+			if err != nil {
+				return isolated, nohzFull, fmt.Errorf("cannot parse %q: %w", param, err)
+			}
+			isolated = isolated.Union(cpus)
+		case "nohz_full":
+			cpus, err := cpuset.Parse(items[1])
+			if err != nil {
+				return isolated, nohzFull, fmt.Errorf("cannot parse %q: %w", param, err)
+			}
+			nohzFull = nohzFull.Union(cpus)
+		}
+	}
+	return isolated, nohzFull, nil
+}
+
+// parseIsolcpus parses the '[flag-list,]cpu-list' isolcpus value
+func parseIsolcpus(value string) (cpuset.CPUSet, error) {
+	items := strings.Split(value, ",")
+	idx := 0
+	for idx < len(items) && isolcpusFlags[items[idx]] {
+		idx++
+	}
+	return cpuset.Parse(strings.Join(items[idx:], ","))
+}
+
+// readCPUList reads a file in the kernel cpu list format. The missing files are reported as empty lists.
+func readCPUList(path string) (cpuset.CPUSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			klog.V(2).Infof("cpus: missing %q, skipped", path)
+			return cpuset.NewCPUSet(), nil
+		}
+		return cpuset.NewCPUSet(), err
+	}
+	cpus := strings.TrimSpace(string(data))
+	// nohz_full reports this when the feature is built in the kernel but not enabled
+	if cpus == "(null)" {
+		return cpuset.NewCPUSet(), nil
+	}
+	return cpuset.Parse(cpus)
+}
+
+// GetNUMACPUs returns the CPUs of each NUMA cell, including the offline ones, from the sysfs mounted at sysfsRoot.
+// The cpulist file of the cells only reports the online CPUs, while the per-CPU entries are kept while offline.
+func GetNUMACPUs(sysfsRoot string) (map[int]cpuset.CPUSet, error) {
+	nodesDir := filepath.Join(sysfsRoot, SysDevicesNodes)
+	nodeEntries, err := ioutil.ReadDir(nodesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	numaCPUs := make(map[int]cpuset.CPUSet)
+	for _, nodeEntry := range nodeEntries {
+		numaID, ok := parseIndexedName(nodeEntry.Name(), "node")
+		if !ok {
+			continue
+		}
+		cpuEntries, err := ioutil.ReadDir(filepath.Join(nodesDir, nodeEntry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b := cpuset.NewBuilder()
+		for _, cpuEntry := range cpuEntries {
+			cpuID, ok := parseIndexedName(cpuEntry.Name(), "cpu")
+			if !ok {
+				continue
+			}
+			b.Add(cpuID)
+		}
+		numaCPUs[numaID] = b.Result()
+	}
+	return numaCPUs, nil
+}
+
+// parseIndexedName parses the sysfs names like "node0" or "cpu12"
+func parseIndexedName(name, prefix string) (int, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(name[len(prefix):])
+	if err != nil || idx < 0 {
+		return 0, false
+	}
+	return idx, true
+}
@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+func TestCPUTuningFromCmdline(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		cmdline          string
+		expectedIsolated string
+		expectedNoHZFull string
+		expectErr        bool
+	}{
+		{
+			name:    "untuned",
+			cmdline: "BOOT_IMAGE=/vmlinuz root=UUID=1234 ro quiet\n",
+		},
+		{
+			name:             "cpu list only",
+			cmdline:          "ro isolcpus=2-5,8 nohz_full=2-5,8 quiet",
+			expectedIsolated: "2-5,8",
+			expectedNoHZFull: "2-5,8",
+		},
+		{
+			name:             "isolcpus flags",
+			cmdline:          "ro isolcpus=managed_irq,domain,1,3-5 rcu_nocbs=1-5",
+			expectedIsolated: "1,3-5",
+		},
+		{
+			name:             "repeated",
+			cmdline:          "isolcpus=1 isolcpus=nohz,3 nohz_full=1 nohz_full=3",
+			expectedIsolated: "1,3",
+			expectedNoHZFull: "1,3",
+		},
+		{
+			name:      "malformed",
+			cmdline:   "isolcpus=domain,foo",
+			expectErr: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			isolated, nohzFull, err := CPUTuningFromCmdline(testCase.cmdline)
+			if testCase.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got isolated %q nohz_full %q", isolated.String(), nohzFull.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if isolated.String() != testCase.expectedIsolated {
+				t.Errorf("isolated: got %q, want %q", isolated.String(), testCase.expectedIsolated)
+			}
+			if nohzFull.String() != testCase.expectedNoHZFull {
+				t.Errorf("nohz_full: got %q, want %q", nohzFull.String(), testCase.expectedNoHZFull)
+			}
+		})
+	}
+}
+
+func TestGetCPUTuning(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		sysfs    map[string]string
+		cmdline  string
+		expected string
+	}{
+		{
+			name:     "missing files",
+			expected: "cpus: isolated \"\" nohz_full \"\" offline \"\"\n",
+		},
+		{
+			name: "nohz_full not enabled",
+			sysfs: map[string]string{
+				"isolated":  "\n",
+				"nohz_full": "(null)\n",
+				"offline":   "\n",
+			},
+			cmdline:  "ro quiet",
+			expected: "cpus: isolated \"\" nohz_full \"\" offline \"\"\n",
+		},
+		{
+			name: "tuned",
+			sysfs: map[string]string{
+				"isolated":  "2-3\n",
+				"nohz_full": "2-5\n",
+				"offline":   "6-7\n",
+			},
+			// isolcpus=managed_irq is not reported in sysfs
+			cmdline:  "ro isolcpus=managed_irq,4-5 nohz_full=2-5",
+			expected: "cpus: isolated \"2-5\" nohz_full \"2-5\" offline \"6-7\"\n",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sysfsRoot := t.TempDir()
+			procfsRoot := t.TempDir()
+			for name, content := range testCase.sysfs {
+				writeSysfsFile(t, filepath.Join(sysfsRoot, SysDevicesCPUs, name), content)
+			}
+			if testCase.cmdline != "" {
+				writeSysfsFile(t, filepath.Join(procfsRoot, ProcCmdline), testCase.cmdline)
+			}
+
+			got, err := GetCPUTuning(sysfsRoot, procfsRoot)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != testCase.expected {
+				t.Errorf("got %q, want %q", got.String(), testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetNUMACPUs(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	nodesDir := filepath.Join(sysfsRoot, SysDevicesNodes)
+	// cpu3 is offline: it's missing from the cpulist, but the per-CPU entry is kept
+	writeSysfsFile(t, filepath.Join(nodesDir, "online"), "0-1\n")
+	writeSysfsFile(t, filepath.Join(nodesDir, "node0", "cpulist"), "0,2\n")
+	writeSysfsFile(t, filepath.Join(nodesDir, "node1", "cpulist"), "1\n")
+	for node, cpus := range map[string][]string{
+		"node0": {"cpu0", "cpu2"},
+		"node1": {"cpu1", "cpu3"},
+	} {
+		for _, cpu := range cpus {
+			if err := os.MkdirAll(filepath.Join(nodesDir, node, cpu), 0755); err != nil {
+				t.Fatalf("cannot create the %s entry for %s: %v", cpu, node, err)
+			}
+		}
+	}
+
+	got, err := GetNUMACPUs(sysfsRoot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[int]cpuset.CPUSet{
+		0: cpuset.NewCPUSet(0, 2),
+		1: cpuset.NewCPUSet(1, 3),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, want %v", got, expected)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+const (
+	// relative to the sysfs root
+	SysDevicesNodes = "devices/system/node"
+)
+
+// NUMA Cell -> NUMA Cell -> distance
+type NUMADistances map[int]PerNUMACounters
+
+func (nd NUMADistances) String() string {
+	b := strings.Builder{}
+	for _, src := range nd.SortedIDs() {
+		fmt.Fprintf(&b, "numa cell %d distances:", src)
+		for _, dst := range nd[src].SortedIDs() {
+			fmt.Fprintf(&b, " %d=%d", dst, nd[src][dst])
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	return b.String()
+}
+
+// SortedIDs returns the NUMA cell IDs of the distances in ascending order
+func (nd NUMADistances) SortedIDs() []int {
+	var numaIDs []int
+	for numaID := range nd {
+		numaIDs = append(numaIDs, numaID)
+	}
+	sort.Ints(numaIDs)
+	return numaIDs
+}
+
+// GetNUMADistances reads the distances between the online NUMA cells from the sysfs mounted at sysfsRoot.
+// The kernel reports in the distance file of each cell one value per online cell, sorted by cell ID,
+// so the values are mapped back to the cell IDs to handle the holes left by the offline cells.
+func GetNUMADistances(sysfsRoot string) (NUMADistances, error) {
+	nodesDir := filepath.Join(sysfsRoot, SysDevicesNodes)
+	data, err := ioutil.ReadFile(filepath.Join(nodesDir, "online"))
+	if err != nil {
+		return nil, err
+	}
+	// same list format used for the cpus
+	online, err := cpuset.Parse(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the online NUMA cells: %w", err)
+	}
+	numaIDs := online.ToSlice()
+
+	distances := make(NUMADistances)
+	for _, numaID := range numaIDs {
+		data, err := ioutil.ReadFile(filepath.Join(nodesDir, fmt.Sprintf("node%d", numaID), "distance"))
+		if err != nil {
+			return nil, err
+		}
+		values := strings.Fields(string(data))
+		if len(values) != len(numaIDs) {
+			return nil, fmt.Errorf("numa cell %d: found %d distances, expected %d", numaID, len(values), len(numaIDs))
+		}
+		counters := make(PerNUMACounters)
+		for idx, value := range values {
+			dist, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("numa cell %d: cannot parse distance %q: %w", numaID, value, err)
+			}
+			counters[numaIDs[idx]] = dist
+		}
+		distances[numaID] = counters
+	}
+	return distances, nil
+}
+
+// SortedIDs returns the NUMA cell IDs of the counters in ascending order
+func (pnc PerNUMACounters) SortedIDs() []int {
+	var numaIDs []int
+	for numaID := range pnc {
+		numaIDs = append(numaIDs, numaID)
+	}
+	sort.Ints(numaIDs)
+	return numaIDs
+}
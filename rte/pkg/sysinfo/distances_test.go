@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetNUMADistances(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		online    string
+		distances map[string]string
+		expected  NUMADistances
+		expectErr bool
+	}{
+		{
+			name:   "single cell",
+			online: "0\n",
+			distances: map[string]string{
+				"node0": "10\n",
+			},
+			expected: NUMADistances{
+				0: {0: 10},
+			},
+		},
+		{
+			name:   "four cells",
+			online: "0-3\n",
+			distances: map[string]string{
+				"node0": "10 21 31 41\n",
+				"node1": "21 10 41 31\n",
+				"node2": "31 41 10 21\n",
+				"node3": "41 31 21 10\n",
+			},
+			expected: NUMADistances{
+				0: {0: 10, 1: 21, 2: 31, 3: 41},
+				1: {0: 21, 1: 10, 2: 41, 3: 31},
+				2: {0: 31, 1: 41, 2: 10, 3: 21},
+				3: {0: 41, 1: 31, 2: 21, 3: 10},
+			},
+		},
+		{
+			name:   "offline cell",
+			online: "0,2\n",
+			distances: map[string]string{
+				"node0": "10 32\n",
+				"node2": "32 10\n",
+			},
+			expected: NUMADistances{
+				0: {0: 10, 2: 32},
+				2: {0: 32, 2: 10},
+			},
+		},
+		{
+			name:   "missing distances",
+			online: "0-1\n",
+			distances: map[string]string{
+				"node0": "10 21\n",
+				"node1": "21\n",
+			},
+			expectErr: true,
+		},
+		{
+			name:   "malformed distances",
+			online: "0-1\n",
+			distances: map[string]string{
+				"node0": "10 foo\n",
+				"node1": "21 10\n",
+			},
+			expectErr: true,
+		},
+		{
+			name:      "missing cell",
+			online:    "0-1\n",
+			distances: map[string]string{},
+			expectErr: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sysfsRoot := t.TempDir()
+			nodesDir := filepath.Join(sysfsRoot, SysDevicesNodes)
+			writeSysfsFile(t, filepath.Join(nodesDir, "online"), testCase.online)
+			for node, distances := range testCase.distances {
+				writeSysfsFile(t, filepath.Join(nodesDir, node, "distance"), distances)
+			}
+
+			got, err := GetNUMADistances(sysfsRoot)
+			if testCase.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, testCase.expected) {
+				t.Errorf("got %v, want %v", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetNUMADistancesMissingSysfs(t *testing.T) {
+	_, err := GetNUMADistances(t.TempDir())
+	if err == nil {
+		t.Errorf("expected error reading an empty sysfs")
+	}
+}
+
+func writeSysfsFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("cannot create %q: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("cannot write %q: %v", path, err)
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/pci"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// relative to the sysfs root
+	SysBusPCIDevices = "bus/pci/devices"
+)
+
+// the device selector terms, see DeviceSelectorFromString
+const (
+	selectorVendor    = "vendor"
+	selectorDevice    = "device"
+	selectorClass     = "class"
+	selectorDriver    = "driver"
+	selectorAddress   = "addr"
+	selectorPFAddress = "pfaddr"
+	selectorPF        = "pf"
+	selectorVF        = "vf"
+)
+
+// PCIDevice is a PCI device along with its SR-IOV relationships
+type PCIDevice struct {
+	*pci.Device
+	// the PCI address of the physical function, set only for the virtual functions
+	PhysFn string
+	// the number of enabled virtual functions, set only for the physical functions
+	NumVFs int
+}
+
+func (dev PCIDevice) IsVF() bool {
+	return dev.PhysFn != ""
+}
+
+// ClassCode returns the class, subclass and programming interface IDs of the device as a single hex string
+func (dev PCIDevice) ClassCode() string {
+	code := ""
+	if dev.Class != nil {
+		code += dev.Class.ID
+	}
+	if dev.Subclass != nil {
+		code += dev.Subclass.ID
+	}
+	if dev.ProgrammingInterface != nil {
+		code += dev.ProgrammingInterface.ID
+	}
+	return strings.ToLower(code)
+}
+
+// DeviceSelector selects the PCI devices to map to a resource. The empty criteria match all the devices.
+// Each criterion can hold more values, and it's satisfied if any of them matches.
+type DeviceSelector struct {
+	// the ResourceMapping key which the selector was parsed from
+	Key          string
+	ResourceName string
+
+	Vendors []string
+	Devices []string
+	// class codes prefixes, like "02" for the network controllers or "0200" for the ethernet controllers
+	Classes []string
+	// drivers the devices are bound to
+	Drivers []string
+	// globs matching the PCI addresses, like "0000:3b:*"
+	Addresses []string
+	// globs matching the PCI addresses of the physical functions, implies VFOnly
+	PFAddresses []string
+	PFOnly      bool
+	VFOnly      bool
+}
+
+// DeviceSelectorFromString parses the ResourceMapping keys, which can be either the legacy 'vendor' or 'vendor:device'
+// keys, or '+'-separated terms among:
+// - 'vendor:ID[|ID...]' and 'device:ID[|ID...]'
+// - 'class:CODE[|CODE...]': prefix of the class code, made of the class, subclass and programming interface IDs
+// - 'driver:NAME[|NAME...]': the driver the device is bound to
+// - 'addr:GLOB[|GLOB...]': the PCI address of the device
+// - 'pfaddr:GLOB[|GLOB...]': the PCI address of the physical function, selects only virtual functions
+// - 'pf' or 'vf': selects only physical functions or only virtual functions
+// example: 'vendor:8086+driver:vfio-pci+pfaddr:0000:3b:00.*'
+func DeviceSelectorFromString(key, resourceName string) (DeviceSelector, error) {
+	ds := DeviceSelector{
+		Key:          key,
+		ResourceName: resourceName,
+	}
+	terms := strings.Split(key, "+")
+	if len(terms) == 1 && !isSelectorTerm(terms[0]) {
+		// legacy key
+		items := strings.SplitN(key, ":", 2)
+		ds.Vendors = []string{strings.ToLower(items[0])}
+		if len(items) == 2 {
+			ds.Devices = []string{strings.ToLower(items[1])}
+		}
+		return ds, nil
+	}
+
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == selectorPF {
+			ds.PFOnly = true
+			continue
+		}
+		if term == selectorVF {
+			ds.VFOnly = true
+			continue
+		}
+		items := strings.SplitN(term, ":", 2)
+		if len(items) != 2 || items[1] == "" {
+			return ds, fmt.Errorf("malformed term %q", term)
+		}
+		values := strings.Split(items[1], "|")
+		switch items[0] {
+		case selectorVendor:
+			ds.Vendors = append(ds.Vendors, toLower(values)...)
+		case selectorDevice:
+			ds.Devices = append(ds.Devices, toLower(values)...)
+		case selectorClass:
+			ds.Classes = append(ds.Classes, toLower(values)...)
+		case selectorDriver:
+			ds.Drivers = append(ds.Drivers, values...)
+		case selectorAddress:
+			ds.Addresses = append(ds.Addresses, toLower(values)...)
+		case selectorPFAddress:
+			ds.PFAddresses = append(ds.PFAddresses, toLower(values)...)
+			ds.VFOnly = true
+		default:
+			return ds, fmt.Errorf("unknown term %q", term)
+		}
+	}
+	for _, glob := range append(ds.Addresses, ds.PFAddresses...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return ds, fmt.Errorf("malformed address glob %q: %w", glob, err)
+		}
+	}
+	if ds.PFOnly && ds.VFOnly {
+		return ds, fmt.Errorf("cannot select only physical and only virtual functions")
+	}
+	return ds, nil
+}
+
+// DeviceSelectorsFromResourceMapping parses the ResourceMapping keys, skipping the malformed ones,
+// and sorts the selectors by precedence: the more criteria, the higher precedence, then by key.
+func DeviceSelectorsFromResourceMapping(resourceMap map[string]string) []DeviceSelector {
+	var selectors []DeviceSelector
+	for key, resourceName := range resourceMap {
+		ds, err := DeviceSelectorFromString(key, resourceName)
+		if err != nil {
+			klog.Warningf("malformed resource mapping key %q: %v - skipped", key, err)
+			continue
+		}
+		selectors = append(selectors, ds)
+	}
+	sort.SliceStable(selectors, func(i, j int) bool {
+		ci, cj := selectors[i].criteria(), selectors[j].criteria()
+		if ci != cj {
+			return ci > cj
+		}
+		return selectors[i].Key < selectors[j].Key
+	})
+	return selectors
+}
+
+func (ds DeviceSelector) Match(dev PCIDevice) bool {
+	if ds.PFOnly && dev.IsVF() {
+		return false
+	}
+	if ds.VFOnly && !dev.IsVF() {
+		return false
+	}
+	if len(ds.Vendors) > 0 && (dev.Vendor == nil || !containsString(ds.Vendors, strings.ToLower(dev.Vendor.ID))) {
+		return false
+	}
+	if len(ds.Devices) > 0 && (dev.Product == nil || !containsString(ds.Devices, strings.ToLower(dev.Product.ID))) {
+		return false
+	}
+	if len(ds.Classes) > 0 && !matchPrefix(ds.Classes, dev.ClassCode()) {
+		return false
+	}
+	if len(ds.Drivers) > 0 && !containsString(ds.Drivers, dev.Driver) {
+		return false
+	}
+	if len(ds.Addresses) > 0 && !matchGlob(ds.Addresses, strings.ToLower(dev.Address)) {
+		return false
+	}
+	if len(ds.PFAddresses) > 0 && !matchGlob(ds.PFAddresses, strings.ToLower(dev.PhysFn)) {
+		return false
+	}
+	return true
+}
+
+// criteria returns the number of criteria of the selector
+func (ds DeviceSelector) criteria() int {
+	count := 0
+	for _, values := range [][]string{ds.Vendors, ds.Devices, ds.Classes, ds.Drivers, ds.Addresses, ds.PFAddresses} {
+		if len(values) > 0 {
+			count++
+		}
+	}
+	if ds.PFOnly || ds.VFOnly {
+		count++
+	}
+	return count
+}
+
+// GetPCIDevicesFromSysfs adds the SR-IOV relationships, read from the sysfs mounted at sysfsRoot, to the devices
+func GetPCIDevicesFromSysfs(sysfsRoot string, devices []*pci.Device) ([]PCIDevice, error) {
+	var ret []PCIDevice
+	for _, device := range devices {
+		devDir := filepath.Join(sysfsRoot, SysBusPCIDevices, device.Address)
+		dev := PCIDevice{
+			Device: device,
+		}
+
+		physFn, err := os.Readlink(filepath.Join(devDir, "physfn"))
+		if err == nil {
+			dev.PhysFn = filepath.Base(physFn)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(devDir, "sriov_numvfs"))
+		if err == nil {
+			dev.NumVFs, err = strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				return nil, fmt.Errorf("device %s: cannot parse the number of virtual functions: %w", device.Address, err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		ret = append(ret, dev)
+	}
+	return ret, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPrefix(prefixes []string, s string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(globs []string, s string) bool {
+	for _, glob := range globs {
+		// the globs are validated when parsed
+		if ok, _ := path.Match(glob, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toLower(values []string) []string {
+	ret := make([]string, 0, len(values))
+	for _, value := range values {
+		ret = append(ret, strings.ToLower(value))
+	}
+	return ret
+}
+
+func isSelectorTerm(term string) bool {
+	term = strings.TrimSpace(term)
+	if term == selectorPF || term == selectorVF {
+		return true
+	}
+	items := strings.SplitN(term, ":", 2)
+	switch items[0] {
+	case selectorVendor, selectorDevice, selectorClass, selectorDriver, selectorAddress, selectorPFAddress:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jaypipes/ghw/pkg/pci"
+	"github.com/jaypipes/pcidb"
+)
+
+func TestDeviceSelectorFromString(t *testing.T) {
+	var testCases = []struct {
+		key       string
+		expected  DeviceSelector
+		expectErr bool
+	}{
+		{
+			key: "8086",
+			expected: DeviceSelector{
+				Vendors: []string{"8086"},
+			},
+		},
+		{
+			key: "8086:154C",
+			expected: DeviceSelector{
+				Vendors: []string{"8086"},
+				Devices: []string{"154c"},
+			},
+		},
+		{
+			key: "vendor:8086+device:154c|10ed+driver:vfio-pci",
+			expected: DeviceSelector{
+				Vendors: []string{"8086"},
+				Devices: []string{"154c", "10ed"},
+				Drivers: []string{"vfio-pci"},
+			},
+		},
+		{
+			key: "class:0200+pf",
+			expected: DeviceSelector{
+				Classes: []string{"0200"},
+				PFOnly:  true,
+			},
+		},
+		{
+			key: "pfaddr:0000:3b:00.*+addr:0000:3b:0[2-3].*",
+			expected: DeviceSelector{
+				Addresses:   []string{"0000:3b:0[2-3].*"},
+				PFAddresses: []string{"0000:3b:00.*"},
+				VFOnly:      true,
+			},
+		},
+		{
+			key:       "vendor:8086+foo:bar",
+			expectErr: true,
+		},
+		{
+			key:       "driver:",
+			expectErr: true,
+		},
+		{
+			key:       "addr:0000:3b:[",
+			expectErr: true,
+		},
+		{
+			key:       "pfaddr:0000:3b:00.0+pf",
+			expectErr: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.key, func(t *testing.T) {
+			got, err := DeviceSelectorFromString(testCase.key, "res")
+			if testCase.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testCase.expected.Key = testCase.key
+			testCase.expected.ResourceName = "res"
+			if !reflect.DeepEqual(got, testCase.expected) {
+				t.Errorf("got %+v, want %+v", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestResourceNameForSRIOVDevices(t *testing.T) {
+	pf := sriovPCIDevice("1572", "0000:3b:00.0", "i40e", "", 2)
+	vfNetdev := sriovPCIDevice("154c", "0000:3b:02.0", "iavf", "0000:3b:00.0", 0)
+	vfDPDK := sriovPCIDevice("154c", "0000:3b:02.1", "vfio-pci", "0000:3b:00.0", 0)
+	vfOtherPF := sriovPCIDevice("154c", "0000:5e:02.0", "iavf", "0000:5e:00.0", 0)
+	nvme := namedPCIDevice("144d", "a808")
+	nvme.Address = "0000:01:00.0"
+	nvme.Class = &pcidb.Class{ID: "01"}
+	nvme.Subclass = &pcidb.Subclass{ID: "08"}
+	nvme.ProgrammingInterface = &pcidb.ProgrammingInterface{ID: "02"}
+
+	// mirrors rte/doc/config/examples/sriovdp-config.json
+	resMap := map[string]string{
+		"vendor:8086+device:154c|10ed+driver:i40evf|ixgbevf|iavf": "intel_sriov_netdevice",
+		"vendor:8086+device:154c|10ed+driver:vfio-pci":            "intel_sriov_dpdk",
+		"vf+pfaddr:0000:5e:00.*":                                  "intel_sriov_other",
+		"vendor:8086+pf":                                          "intel_pf",
+		"class:0108":                                              "nvme",
+	}
+	selectors := DeviceSelectorsFromResourceMapping(resMap)
+
+	var testCases = []struct {
+		name     string
+		dev      PCIDevice
+		expected string
+	}{
+		{"physical function", pf, "intel_pf"},
+		{"virtual function netdevice", vfNetdev, "intel_sriov_netdevice"},
+		{"virtual function dpdk", vfDPDK, "intel_sriov_dpdk"},
+		// more criteria take precedence
+		{"virtual function by physical function", vfOtherPF, "intel_sriov_netdevice"},
+		{"class", nvme, "nvme"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, _ := ResourceNameForDevice(testCase.dev, selectors)
+			if got != testCase.expected {
+				t.Errorf("got %q, want %q", got, testCase.expected)
+			}
+		})
+	}
+
+	selectors = DeviceSelectorsFromResourceMapping(map[string]string{
+		"vf+pfaddr:0000:5e:00.*+driver:iavf": "intel_sriov_other",
+		"8086:154c":                          "intel_vfs",
+	})
+	if got, _ := ResourceNameForDevice(vfOtherPF, selectors); got != "intel_sriov_other" {
+		t.Errorf("got %q, want %q", got, "intel_sriov_other")
+	}
+	if got, _ := ResourceNameForDevice(vfNetdev, selectors); got != "intel_vfs" {
+		t.Errorf("got %q, want %q", got, "intel_vfs")
+	}
+}
+
+func TestGetPCIDevicesFromSysfs(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	devsDir := filepath.Join(sysfsRoot, SysBusPCIDevices)
+	writeSysfsFile(t, filepath.Join(devsDir, "0000:3b:00.0", "sriov_numvfs"), "2\n")
+	writeSysfsFile(t, filepath.Join(devsDir, "0000:3b:00.1", "sriov_numvfs"), "0\n")
+	for _, vf := range []string{"0000:3b:02.0", "0000:3b:02.1"} {
+		if err := os.MkdirAll(filepath.Join(devsDir, vf), 0755); err != nil {
+			t.Fatalf("cannot create %s: %v", vf, err)
+		}
+		if err := os.Symlink("../0000:3b:00.0", filepath.Join(devsDir, vf, "physfn")); err != nil {
+			t.Fatalf("cannot link the physical function of %s: %v", vf, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(devsDir, "0000:00:1f.0"), 0755); err != nil {
+		t.Fatalf("cannot create 0000:00:1f.0: %v", err)
+	}
+
+	var devices []*pci.Device
+	for _, address := range []string{"0000:3b:00.0", "0000:3b:00.1", "0000:3b:02.0", "0000:3b:02.1", "0000:00:1f.0"} {
+		devices = append(devices, &pci.Device{Address: address})
+	}
+	got, err := GetPCIDevicesFromSysfs(sysfsRoot, devices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []PCIDevice{
+		{Device: devices[0], NumVFs: 2},
+		{Device: devices[1]},
+		{Device: devices[2], PhysFn: "0000:3b:00.0"},
+		{Device: devices[3], PhysFn: "0000:3b:00.0"},
+		{Device: devices[4]},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, want %+v", got, expected)
+	}
+}
+
+func sriovPCIDevice(productID, address, driver, physFn string, numVFs int) PCIDevice {
+	dev := fakePCIDevice("8086", productID, address, 0)
+	dev.Driver = driver
+	dev.Class = &pcidb.Class{ID: "02"}
+	dev.Subclass = &pcidb.Subclass{ID: "00"}
+	dev.PhysFn = physFn
+	dev.NumVFs = numVFs
+	return dev
+}
+
+func withPhysFn(dev PCIDevice, physFn string) PCIDevice {
+	dev.PhysFn = physFn
+	return dev
+}
+
+func withNumVFs(dev PCIDevice, numVFs int) PCIDevice {
+	dev.NumVFs = numVFs
+	return dev
+}
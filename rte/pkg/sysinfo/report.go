@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	ReportFormatJSON = "json"
+	ReportFormatYAML = "yaml"
+)
+
+// Report is the machine readable representation of the SysInfo. All the lists are sorted,
+// so the reports of machines with the same hardware and configuration are identical.
+type Report struct {
+	CPUs      CPUsReport       `json:"cpus"`
+	NUMACells []NUMACellReport `json:"numaCells"`
+}
+
+// CPUsReport holds the CPU lists in the kernel cpu list format
+type CPUsReport struct {
+	Online      string `json:"online"`
+	Reserved    string `json:"reserved"`
+	Allocatable string `json:"allocatable"`
+	Isolated    string `json:"isolated"`
+	NoHZFull    string `json:"nohzFull"`
+	Offline     string `json:"offline"`
+}
+
+// NUMACellReport holds the memory and the devices of a NUMA cell. The devices without
+// NUMA affinity are reported in the cell with ID -1, which has no memory.
+type NUMACellReport struct {
+	ID        int              `json:"id"`
+	Memory    []MemoryReport   `json:"memory,omitempty"`
+	Resources []ResourceReport `json:"resources,omitempty"`
+}
+
+// MemoryReport holds the allocatable amount, in bytes, of a memory type
+type MemoryReport struct {
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ResourceReport holds the IDs of the devices mapped to a resource
+type ResourceReport struct {
+	Name    string   `json:"name"`
+	Devices []string `json:"devices"`
+}
+
+func (si SysInfo) Report() Report {
+	cells := make(map[int]*NUMACellReport)
+	cellFor := func(numaID int) *NUMACellReport {
+		cell, ok := cells[numaID]
+		if !ok {
+			cell = &NUMACellReport{ID: numaID}
+			cells[numaID] = cell
+		}
+		return cell
+	}
+
+	for memoryType, counters := range si.Memory {
+		for numaID, amount := range counters {
+			cell := cellFor(numaID)
+			cell.Memory = append(cell.Memory, MemoryReport{Type: memoryType, Bytes: amount})
+		}
+	}
+	for resourceName, numaDevs := range si.Resources {
+		for numaID, devs := range numaDevs {
+			devices := append([]string{}, devs...)
+			sort.Strings(devices)
+			cell := cellFor(numaID)
+			cell.Resources = append(cell.Resources, ResourceReport{Name: resourceName, Devices: devices})
+		}
+	}
+
+	rep := Report{
+		CPUs: CPUsReport{
+			Online:      si.OnlineCPUs.String(),
+			Reserved:    si.ReservedCPUs.String(),
+			Allocatable: si.CPUs.String(),
+			Isolated:    si.CPUTuning.Isolated.String(),
+			NoHZFull:    si.CPUTuning.NoHZFull.String(),
+			Offline:     si.CPUTuning.Offline.String(),
+		},
+		NUMACells: []NUMACellReport{},
+	}
+	for _, cell := range cells {
+		sort.Slice(cell.Memory, func(i, j int) bool {
+			return cell.Memory[i].Type < cell.Memory[j].Type
+		})
+		sort.Slice(cell.Resources, func(i, j int) bool {
+			return cell.Resources[i].Name < cell.Resources[j].Name
+		})
+		rep.NUMACells = append(rep.NUMACells, *cell)
+	}
+	sort.Slice(rep.NUMACells, func(i, j int) bool {
+		return rep.NUMACells[i].ID < rep.NUMACells[j].ID
+	})
+	return rep
+}
+
+// Render serializes the report in the given format, json or yaml
+func (rep Report) Render(format string) ([]byte, error) {
+	switch format {
+	case ReportFormatJSON:
+		return json.MarshalIndent(rep, "", "  ")
+	case ReportFormatYAML:
+		return yaml.Marshal(rep)
+	default:
+		return nil, fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+// IsValidReportFormat tells if the format is supported by Report.Render
+func IsValidReportFormat(format string) bool {
+	return format == ReportFormatJSON || format == ReportFormatYAML
+}
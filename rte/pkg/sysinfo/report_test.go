@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+func makeReportSysInfo() SysInfo {
+	return SysInfo{
+		OnlineCPUs:   cpuset.NewCPUSet(0, 1, 2, 3),
+		ReservedCPUs: cpuset.NewCPUSet(0),
+		CPUs:         cpuset.NewCPUSet(1, 2, 3),
+		CPUTuning: CPUTuning{
+			Isolated: cpuset.NewCPUSet(3),
+			NoHZFull: cpuset.NewCPUSet(),
+			Offline:  cpuset.NewCPUSet(),
+		},
+		Resources: map[string]PerNUMADevices{
+			"intel_nics": {
+				1:  []string{"0000:3b:00.1", "0000:3b:00.0"},
+				-1: []string{"0000:00:02.0"},
+			},
+			"gpus": {
+				0: []string{"0000:af:00.0"},
+			},
+		},
+		Memory: map[string]PerNUMACounters{
+			"memory": {
+				1: 8589934592,
+				0: 4294967296,
+			},
+			"hugepages-2Mi": {
+				0: 8388608,
+			},
+		},
+	}
+}
+
+func TestReportRenderJSON(t *testing.T) {
+	expected := `{
+  "cpus": {
+    "online": "0-3",
+    "reserved": "0",
+    "allocatable": "1-3",
+    "isolated": "3",
+    "nohzFull": "",
+    "offline": ""
+  },
+  "numaCells": [
+    {
+      "id": -1,
+      "resources": [
+        {
+          "name": "intel_nics",
+          "devices": [
+            "0000:00:02.0"
+          ]
+        }
+      ]
+    },
+    {
+      "id": 0,
+      "memory": [
+        {
+          "type": "hugepages-2Mi",
+          "bytes": 8388608
+        },
+        {
+          "type": "memory",
+          "bytes": 4294967296
+        }
+      ],
+      "resources": [
+        {
+          "name": "gpus",
+          "devices": [
+            "0000:af:00.0"
+          ]
+        }
+      ]
+    },
+    {
+      "id": 1,
+      "memory": [
+        {
+          "type": "memory",
+          "bytes": 8589934592
+        }
+      ],
+      "resources": [
+        {
+          "name": "intel_nics",
+          "devices": [
+            "0000:3b:00.0",
+            "0000:3b:00.1"
+          ]
+        }
+      ]
+    }
+  ]
+}`
+	// the maps iteration order is random, so render a few times to catch unstable output
+	for i := 0; i < 10; i++ {
+		data, err := makeReportSysInfo().Report().Render(ReportFormatJSON)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(data) != expected {
+			t.Fatalf("unexpected report:\n%s", string(data))
+		}
+	}
+}
+
+func TestReportRenderYAML(t *testing.T) {
+	expected := `cpus:
+  allocatable: 1-3
+  isolated: "3"
+  nohzFull: ""
+  offline: ""
+  online: 0-3
+  reserved: "0"
+numaCells:
+- id: 0
+  memory:
+  - bytes: 2097152
+    type: memory
+`
+	si := SysInfo{
+		OnlineCPUs:   cpuset.NewCPUSet(0, 1, 2, 3),
+		ReservedCPUs: cpuset.NewCPUSet(0),
+		CPUs:         cpuset.NewCPUSet(1, 2, 3),
+		CPUTuning: CPUTuning{
+			Isolated: cpuset.NewCPUSet(3),
+		},
+		Memory: map[string]PerNUMACounters{
+			"memory": {0: 2097152},
+		},
+	}
+	data, err := si.Report().Render(ReportFormatYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("unexpected report:\n%s", string(data))
+	}
+}
+
+func TestReportRenderUnsupported(t *testing.T) {
+	if _, err := makeReportSysInfo().Report().Render("xml"); err == nil {
+		t.Errorf("expected failure rendering an unsupported format")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/snapshot"
+
+	"k8s.io/klog/v2"
+)
+
+// the content NewSysinfo reads besides what ghw reads, in the format of snapshot.CopyFilesInto
+var snapshotExtraContent = []string{
+	// GetOnlineCPUs and GetCPUTuning
+	"/sys/devices/system/cpu/online",
+	"/sys/devices/system/cpu/offline",
+	"/sys/devices/system/cpu/isolated",
+	"/sys/devices/system/cpu/nohz_full",
+	"/proc/cmdline",
+	// GetPCIDevicesFromSysfs
+	"/sys/bus/pci/devices/*/physfn",
+	"/sys/bus/pci/devices/*/sriov_numvfs",
+	// the PCI IDs database, which ghw needs to identify the devices
+	"/usr/share/hwdata/pci.ids*",
+	"/usr/share/misc/pci.ids*",
+}
+
+// SnapshotContent returns the glob patterns of the host files a snapshot includes.
+// The PCI content depends on the devices of the host, so it's discovered when called.
+func SnapshotContent() []string {
+	fileSpecs := snapshot.ExpectedCloneStaticContent()
+	// must precede the extra content, which reaches the PCI devices through their links
+	fileSpecs = append(fileSpecs, snapshot.ExpectedClonePCIContent()...)
+	return append(fileSpecs, snapshotExtraContent...)
+}
+
+// CaptureSnapshot packs into the snapshotPath tarball the subset of the host sysfs, mounted at sysfsRoot,
+// and procfs NewSysinfo reads, so the system information can be computed again offline with ReplaySnapshot.
+// The snapshot always stores the sysfs under DefaultSysfsRoot. ghw discovers the PCI devices to capture
+// from DefaultSysfsRoot, which lists the same devices as any other sysfs mount.
+func CaptureSnapshot(snapshotPath, sysfsRoot string) error {
+	scratchDir, err := ioutil.TempDir("", "rte-snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	err = captureSnapshotInto(scratchDir, SnapshotContent(), sysfsRoot)
+	if err != nil {
+		return err
+	}
+	return snapshot.PackFrom(snapshotPath, scratchDir)
+}
+
+// captureSnapshotInto copies the fileSpecs, which refer to the sysfs as DefaultSysfsRoot, reading the sysfs
+// from sysfsRoot. The sysfs tree is moved to DefaultSysfsRoot once copied, so the links within it stay valid.
+func captureSnapshotInto(scratchDir string, fileSpecs []string, sysfsRoot string) error {
+	sysfsRoot = filepath.Clean(sysfsRoot)
+	if sysfsRoot == DefaultSysfsRoot {
+		return copyReadableFilesInto(fileSpecs, scratchDir)
+	}
+
+	specs := make([]string, 0, len(fileSpecs))
+	for _, fileSpec := range fileSpecs {
+		if rel, err := filepath.Rel(DefaultSysfsRoot, fileSpec); err == nil && !strings.HasPrefix(rel, "..") {
+			fileSpec = filepath.Join(sysfsRoot, rel)
+		}
+		specs = append(specs, fileSpec)
+	}
+	err := copyReadableFilesInto(specs, scratchDir)
+	if err != nil {
+		return err
+	}
+
+	copiedDir := filepath.Join(scratchDir, sysfsRoot)
+	if _, err := os.Stat(copiedDir); err != nil {
+		// nothing copied from the sysfs
+		return nil
+	}
+	err = os.Rename(copiedDir, filepath.Join(scratchDir, DefaultSysfsRoot))
+	if err != nil {
+		return err
+	}
+	// drop the now empty parents of the sysfs root
+	for dir := filepath.Dir(copiedDir); dir != scratchDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// copyReadableFilesInto is like snapshot.CopyFilesInto, but skips the files which cannot be read,
+// like the write-only sysfs attributes some kernels add next to the ones the snapshot needs.
+func copyReadableFilesInto(fileSpecs []string, destDir string) error {
+	for _, fileSpec := range fileSpecs {
+		paths, err := filepath.Glob(fileSpec)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if !isReadable(path) {
+				klog.V(2).Infof("snapshot: cannot read %q, skipped", path)
+				continue
+			}
+			err = snapshot.CopyFilesInto([]string{path}, destDir, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isReadable(path string) bool {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	// the links are copied as links, and the directories are never read
+	if fi.Mode()&os.ModeSymlink != 0 || fi.IsDir() {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return !errors.Is(err, os.ErrPermission)
+	}
+	f.Close()
+	return true
+}
+
+// ReplaySnapshot unpacks the snapshotPath tarball created by CaptureSnapshot and returns the Handle
+// to read the system information from it. The returned function removes the unpacked tree.
+func ReplaySnapshot(snapshotPath string) (Handle, func() error, error) {
+	root, err := snapshot.Unpack(snapshotPath)
+	if err != nil {
+		if root != "" {
+			snapshot.Cleanup(root)
+		}
+		return Handle{}, nil, err
+	}
+	return Handle{Root: root}, func() error { return snapshot.Cleanup(root) }, nil
+}
@@ -0,0 +1,283 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysinfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jaypipes/ghw/pkg/snapshot"
+)
+
+const fakePCIIDs = `8086  Intel Corporation
+	158b  Ethernet Controller XXV710 for 25GbE SFP28
+	154c  Ethernet Virtual Function 700 Series
+C 02  Network controller
+	00  Ethernet controller
+`
+
+// makeFakeHost lays out in root a host with 2 NUMA cells, 2 cpus and 2Mi hugepages on each cell,
+// and an SR-IOV NIC on the cell 1, whose physical function has a single virtual function.
+func makeFakeHost(t *testing.T, root string) {
+	writeSysfsFile(t, filepath.Join(root, "proc", "cmdline"), "BOOT_IMAGE=/vmlinuz isolcpus=managed_irq,3\n")
+	writeSysfsFile(t, filepath.Join(root, "proc", "meminfo"), "MemTotal:       8388608 kB\n")
+
+	cpusDir := filepath.Join(root, "sys", "devices", "system", "cpu")
+	writeSysfsFile(t, filepath.Join(cpusDir, "online"), "0-3\n")
+	writeSysfsFile(t, filepath.Join(cpusDir, "offline"), "\n")
+
+	writeSysfsFile(t, filepath.Join(root, "sys", "devices", "system", "memory", "block_size_bytes"), "8000000\n")
+
+	nodesDir := filepath.Join(root, "sys", "devices", "system", "node")
+	writeSysfsFile(t, filepath.Join(nodesDir, "online"), "0-1\n")
+	writeSysfsFile(t, filepath.Join(nodesDir, "possible"), "0-1\n")
+	for numaID := 0; numaID < 2; numaID++ {
+		nodeDir := filepath.Join(nodesDir, fmt.Sprintf("node%d", numaID))
+		writeSysfsFile(t, filepath.Join(nodeDir, "distance"), []string{"10 21\n", "21 10\n"}[numaID])
+		writeSysfsFile(t, filepath.Join(nodeDir, "meminfo"), fmt.Sprintf("Node %d MemTotal:       4194304 kB\n", numaID))
+		writeSysfsFile(t, filepath.Join(nodeDir, fmt.Sprintf("memory%d", numaID), "online"), "1\n")
+		writeSysfsFile(t, filepath.Join(nodeDir, fmt.Sprintf("memory%d", numaID), "state"), "online\n")
+		writeSysfsFile(t, filepath.Join(nodeDir, "hugepages", "hugepages-2048kB", "nr_hugepages"), "4\n")
+		for _, cpuID := range []int{numaID * 2, numaID*2 + 1} {
+			topoDir := filepath.Join(nodeDir, fmt.Sprintf("cpu%d", cpuID), "topology")
+			writeSysfsFile(t, filepath.Join(topoDir, "core_id"), fmt.Sprintf("%d\n", cpuID))
+			writeSysfsFile(t, filepath.Join(topoDir, "physical_package_id"), fmt.Sprintf("%d\n", numaID))
+			writeSysfsFile(t, filepath.Join(topoDir, "thread_siblings_list"), fmt.Sprintf("%d\n", cpuID))
+		}
+	}
+
+	busDir := filepath.Join(root, "sys", "bus", "pci", "devices")
+	devsDir := filepath.Join(root, "sys", "devices", "pci0000:3a")
+	devs := []struct {
+		address  string
+		product  string
+		physFn   string
+		sriovVFs string
+	}{
+		{address: "0000:3b:00.0", product: "158B", sriovVFs: "1\n"},
+		{address: "0000:3b:02.0", product: "154C", physFn: "0000:3b:00.0"},
+	}
+	for _, dev := range devs {
+		devDir := filepath.Join(devsDir, dev.address)
+		writeSysfsFile(t, filepath.Join(devDir, "modalias"), fmt.Sprintf("pci:v00008086d0000%ssv00008086sd00000000bc02sc00i00\n", dev.product))
+		writeSysfsFile(t, filepath.Join(devDir, "numa_node"), "1\n")
+		if dev.sriovVFs != "" {
+			writeSysfsFile(t, filepath.Join(devDir, "sriov_numvfs"), dev.sriovVFs)
+		}
+		if dev.physFn != "" {
+			makeSymlink(t, filepath.Join("..", dev.physFn), filepath.Join(devDir, "physfn"))
+		}
+		makeSymlink(t, filepath.Join("..", "..", "..", "devices", "pci0000:3a", dev.address), filepath.Join(busDir, dev.address))
+	}
+
+	writeSysfsFile(t, filepath.Join(root, "usr", "share", "hwdata", "pci.ids"), fakePCIIDs)
+}
+
+func makeSymlink(t *testing.T, target, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("cannot create %q: %v", filepath.Dir(path), err)
+	}
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatalf("cannot link %q: %v", path, err)
+	}
+}
+
+func TestReplaySnapshot(t *testing.T) {
+	hostRoot := t.TempDir()
+	makeFakeHost(t, hostRoot)
+	snapshotPath := filepath.Join(t.TempDir(), "host.tgz")
+	if err := snapshot.PackFrom(snapshotPath, hostRoot); err != nil {
+		t.Fatalf("cannot pack the snapshot: %v", err)
+	}
+
+	hnd, cleanup, err := ReplaySnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("cannot replay the snapshot: %v", err)
+	}
+	defer cleanup()
+
+	conf := Config{
+		ReservedCPUs: "0",
+		ResourceMapping: map[string]string{
+			"vendor:8086+vf": "intel_sriov_vf",
+			"vendor:8086+pf": "intel_sriov_pf",
+		},
+		ReservedMemory: ReservedMemory{
+			"hugepages-2Mi": PerNUMACounters{0: 2 * 2 * 1024 * 1024},
+		},
+	}
+	info, err := NewSysinfoFromHandle(conf, hnd)
+	if err != nil {
+		t.Fatalf("cannot compute the system information: %v", err)
+	}
+
+	if got := info.OnlineCPUs.String(); got != "0-3" {
+		t.Errorf("unexpected online cpus %q", got)
+	}
+	if got := info.CPUs.String(); got != "1-3" {
+		t.Errorf("unexpected allocatable cpus %q", got)
+	}
+	if got := info.CPUTuning.Isolated.String(); got != "3" {
+		t.Errorf("unexpected isolated cpus %q", got)
+	}
+
+	// the physical function has an enabled virtual function, so it's not allocatable
+	expectedResources := map[string]PerNUMADevices{
+		"intel_sriov_vf": {1: []string{"0000:3b:02.0"}},
+	}
+	if !reflect.DeepEqual(info.Resources, expectedResources) {
+		t.Errorf("unexpected resources %v", info.Resources)
+	}
+
+	expectedHugepages := PerNUMACounters{
+		0: 2 * 2 * 1024 * 1024,
+		1: 4 * 2 * 1024 * 1024,
+	}
+	if got := info.Memory["hugepages-2Mi"]; !reflect.DeepEqual(got, expectedHugepages) {
+		t.Errorf("unexpected hugepages %v", got)
+	}
+	expectedMemory := PerNUMACounters{
+		0: 4 * 1024 * 1024 * 1024,
+		1: 4 * 1024 * 1024 * 1024,
+	}
+	if got := info.Memory["memory"]; !reflect.DeepEqual(got, expectedMemory) {
+		t.Errorf("unexpected memory %v", got)
+	}
+}
+
+func TestNewSysinfoFromHandleSysfs(t *testing.T) {
+	hostRoot := t.TempDir()
+	makeFakeHost(t, hostRoot)
+	if err := os.Rename(filepath.Join(hostRoot, "sys"), filepath.Join(hostRoot, "host-sys")); err != nil {
+		t.Fatalf("cannot move the sysfs: %v", err)
+	}
+
+	conf := Config{
+		ReservedCPUs: "0",
+		ResourceMapping: map[string]string{
+			"vendor:8086+vf": "intel_sriov_vf",
+		},
+	}
+	info, err := NewSysinfoFromHandle(conf, Handle{Root: hostRoot, Sysfs: "/host-sys"})
+	if err != nil {
+		t.Fatalf("cannot compute the system information: %v", err)
+	}
+
+	if got := info.CPUs.String(); got != "1-3" {
+		t.Errorf("unexpected allocatable cpus %q", got)
+	}
+	if got := info.CPUTuning.Isolated.String(); got != "3" {
+		t.Errorf("unexpected isolated cpus %q", got)
+	}
+	expectedResources := map[string]PerNUMADevices{
+		"intel_sriov_vf": {1: []string{"0000:3b:02.0"}},
+	}
+	if !reflect.DeepEqual(info.Resources, expectedResources) {
+		t.Errorf("unexpected resources %v", info.Resources)
+	}
+	expectedHugepages := PerNUMACounters{
+		0: 4 * 2 * 1024 * 1024,
+		1: 4 * 2 * 1024 * 1024,
+	}
+	if got := info.Memory["hugepages-2Mi"]; !reflect.DeepEqual(got, expectedHugepages) {
+		t.Errorf("unexpected hugepages %v", got)
+	}
+	expectedMemory := PerNUMACounters{
+		0: 4 * 1024 * 1024 * 1024,
+		1: 4 * 1024 * 1024 * 1024,
+	}
+	if got := info.Memory["memory"]; !reflect.DeepEqual(got, expectedMemory) {
+		t.Errorf("unexpected memory %v", got)
+	}
+}
+
+func TestCaptureSnapshotIntoSysfs(t *testing.T) {
+	hostRoot := t.TempDir()
+	makeFakeHost(t, hostRoot)
+	sysfsRoot := filepath.Join(hostRoot, "host-sys")
+	if err := os.Rename(filepath.Join(hostRoot, "sys"), sysfsRoot); err != nil {
+		t.Fatalf("cannot move the sysfs: %v", err)
+	}
+
+	scratchDir := t.TempDir()
+	fileSpecs := []string{
+		"/sys/devices/system/cpu/online",
+		"/sys/devices/system/node/online",
+		"/sys/devices/system/node/node*/distance",
+	}
+	if err := captureSnapshotInto(scratchDir, fileSpecs, sysfsRoot); err != nil {
+		t.Fatalf("cannot capture the snapshot: %v", err)
+	}
+
+	entries, err := ioutil.ReadDir(scratchDir)
+	if err != nil {
+		t.Fatalf("cannot read the snapshot tree: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "sys" {
+		t.Errorf("unexpected snapshot tree content %v", entries)
+	}
+
+	hnd := Handle{Root: scratchDir}
+	cpus, err := hnd.GetOnlineCPUs()
+	if err != nil {
+		t.Fatalf("cannot read the snapshot online cpus: %v", err)
+	}
+	if got := cpus.String(); got != "0-3" {
+		t.Errorf("unexpected online cpus %q", got)
+	}
+	if _, err := GetNUMADistances(hnd.SysfsRoot()); err != nil {
+		t.Errorf("cannot read the snapshot numa distances: %v", err)
+	}
+}
+
+func TestReplaySnapshotMissing(t *testing.T) {
+	_, _, err := ReplaySnapshot(filepath.Join(t.TempDir(), "missing.tgz"))
+	if err == nil {
+		t.Errorf("expected failure replaying a missing snapshot")
+	}
+}
+
+func TestCaptureSnapshot(t *testing.T) {
+	expectedCPUs, err := GetOnlineCPUs()
+	if err != nil {
+		t.Skipf("cannot read the host online cpus: %v", err)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "host.tgz")
+	if err := CaptureSnapshot(snapshotPath, DefaultSysfsRoot); err != nil {
+		t.Fatalf("cannot capture the snapshot: %v", err)
+	}
+	hnd, cleanup, err := ReplaySnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("cannot replay the snapshot: %v", err)
+	}
+	defer cleanup()
+
+	cpus, err := hnd.GetOnlineCPUs()
+	if err != nil {
+		t.Fatalf("cannot read the snapshot online cpus: %v", err)
+	}
+	if !cpus.Equals(expectedCPUs) {
+		t.Errorf("snapshot online cpus %q differ from the host ones %q", cpus.String(), expectedCPUs.String())
+	}
+	if _, err := GetNUMADistances(hnd.SysfsRoot()); err != nil {
+		t.Errorf("cannot read the snapshot numa distances: %v", err)
+	}
+}
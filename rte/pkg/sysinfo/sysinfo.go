@@ -15,13 +15,16 @@ limitations under the License.
 package sysinfo
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/ghodss/yaml"
+	"github.com/jaypipes/ghw/pkg/option"
 	"github.com/jaypipes/ghw/pkg/pci"
 	"github.com/jaypipes/ghw/pkg/topology"
 
@@ -34,15 +37,82 @@ import (
 )
 
 const (
-	SysDevicesOnlineCPUs = "/sys/devices/system/cpu/online"
+	// relative to the sysfs root
+	SysDevicesOnlineCPUs = "devices/system/cpu/online"
 )
 
+// Handle tells where to read the system information from: the host root filesystem, or the
+// tree unpacked from a snapshot (see ReplaySnapshot). The empty Root means the host.
+// Sysfs is where the sysfs is mounted under Root, like the --sysfs option. The empty Sysfs means DefaultSysfsRoot.
+type Handle struct {
+	Root  string
+	Sysfs string
+}
+
+func (hnd Handle) SysfsRoot() string {
+	if hnd.Sysfs == "" {
+		return filepath.Join(hnd.Root, DefaultSysfsRoot)
+	}
+	return filepath.Join(hnd.Root, hnd.Sysfs)
+}
+
+func (hnd Handle) ProcfsRoot() string {
+	return filepath.Join(hnd.Root, DefaultProcfsRoot)
+}
+
+// ghwOptions makes ghw, and the PCI IDs database lookup, read from the Handle root and sysfs
+func (hnd Handle) ghwOptions() []*option.Option {
+	var opts []*option.Option
+	if hnd.Root != "" {
+		opts = append(opts, option.WithChroot(hnd.Root))
+	}
+	if hnd.Sysfs != "" {
+		opts = append(opts, option.WithPathOverrides(option.PathOverrides{
+			DefaultSysfsRoot: hnd.Sysfs,
+		}))
+	}
+	return opts
+}
+
 type Config struct {
 	ReservedCPUs string `json:"reservedCpus,omitempty"`
 	// vendor:device -> resourcename
 	ResourceMapping map[string]string `json:"resourceMapping,omitempty"`
-	// numa zone -> reserved amount
-	ReservedMemory map[int]int64 `json:"reservedMemory,omitempty"`
+	// memory type -> numa zone -> reserved amount
+	ReservedMemory ReservedMemory `json:"reservedMemory,omitempty"`
+}
+
+// ReservedMemory holds the amount of memory, in bytes, reserved for each memory type
+// ("memory", "hugepages-2Mi", "hugepages-1Gi"...) on each NUMA zone.
+type ReservedMemory map[string]PerNUMACounters
+
+// UnmarshalJSON also accepts the legacy format, which maps the NUMA zones to the amount of
+// plain memory reserved, to keep consuming the configurations rendered by older operators.
+func (rm *ReservedMemory) UnmarshalJSON(data []byte) error {
+	byType := make(map[string]PerNUMACounters)
+	err := json.Unmarshal(data, &byType)
+	if err == nil {
+		*rm = byType
+		return nil
+	}
+	byNUMA := make(PerNUMACounters)
+	if legacyErr := json.Unmarshal(data, &byNUMA); legacyErr != nil {
+		return err
+	}
+	*rm = ReservedMemory{
+		string(corev1.ResourceMemory): byNUMA,
+	}
+	return nil
+}
+
+// MarshalJSON emits the legacy format when only plain memory is reserved, so the exporters
+// which predate the reservation of the hugepages can consume the configurations too.
+func (rm ReservedMemory) MarshalJSON() ([]byte, error) {
+	byNUMA, ok := rm[string(corev1.ResourceMemory)]
+	if ok && len(rm) == 1 {
+		return json.Marshal(byNUMA)
+	}
+	return json.Marshal(map[string]PerNUMACounters(rm))
 }
 
 func (cfg Config) ToYAML() ([]byte, error) {
@@ -79,21 +149,30 @@ func ResourceMappingToString(rmap map[string]string) string {
 	return strings.Join(items, ",")
 }
 
-func ReservedMemoryFromString(s string) map[int]int64 {
-	// comma-separated 'numaID=amount'")
-	rmap := make(map[int]int64)
+func ReservedMemoryFromString(s string) ReservedMemory {
+	// comma-separated 'numaID[:memoryType]=amount', memoryType defaults to "memory"
+	rmap := make(ReservedMemory)
 	for _, keyvalue := range strings.Split(strings.TrimSpace(s), ",") {
 		if len(keyvalue) == 0 {
 			continue
 		}
 		items := strings.SplitN(keyvalue, "=", 2)
 		if len(items) != 2 {
-			klog.Warningf("malformed resource mapping item %q, skipped", keyvalue)
+			klog.Warningf("malformed reserved memory item %q, skipped", keyvalue)
 			continue
 		}
-		numaID, err := strconv.Atoi(strings.TrimSpace(items[0]))
+		memoryType := string(corev1.ResourceMemory)
+		keys := strings.SplitN(items[0], ":", 2)
+		if len(keys) == 2 {
+			memoryType = strings.TrimSpace(keys[1])
+		}
+		if memoryType != string(corev1.ResourceMemory) && !strings.HasPrefix(memoryType, corev1.ResourceHugePagesPrefix) {
+			klog.Warningf("unsupported memory type %q - skipped", memoryType)
+			continue
+		}
+		numaID, err := strconv.Atoi(strings.TrimSpace(keys[0]))
 		if err != nil {
-			klog.Warningf("cannot parse NUMA identifier %q: %v - skipped", items[0], err)
+			klog.Warningf("cannot parse NUMA identifier %q: %v - skipped", keys[0], err)
 			continue
 		}
 
@@ -104,29 +183,41 @@ func ReservedMemoryFromString(s string) map[int]int64 {
 		}
 		val, ok := res.AsInt64()
 		if !ok {
-			klog.Warningf("NUMA memory amount %q representation error: %v - skipped", items[1], err)
+			klog.Warningf("NUMA memory amount %q cannot be represented as int64 - skipped", items[1])
 			continue
 		}
-		rmap[numaID] = val
+		counters, ok := rmap[memoryType]
+		if !ok {
+			counters = make(PerNUMACounters)
+		}
+		counters[numaID] = val
+		rmap[memoryType] = counters
 	}
 	return rmap
 }
 
-func ReservedMemoryToString(rmap map[int]int64) string {
-	var keys []int
-	for key := range rmap {
-		keys = append(keys, key)
+func ReservedMemoryToString(rmap ReservedMemory) string {
+	var memoryTypes []string
+	for memoryType := range rmap {
+		memoryTypes = append(memoryTypes, memoryType)
 	}
-	sort.Ints(keys)
+	sort.Strings(memoryTypes)
 	var items []string
-	for _, key := range keys {
-		items = append(items, fmt.Sprintf("%d=%d", key, rmap[key]))
+	for _, memoryType := range memoryTypes {
+		var keys []int
+		for key := range rmap[memoryType] {
+			keys = append(keys, key)
+		}
+		sort.Ints(keys)
+		for _, key := range keys {
+			items = append(items, fmt.Sprintf("%d:%s=%d", key, memoryType, rmap[memoryType][key]))
+		}
 	}
 	return strings.Join(items, ",")
 }
 
 func (cfg Config) IsEmpty() bool {
-	return cfg.ReservedCPUs == "" && len(cfg.ResourceMapping) == 0
+	return cfg.ReservedCPUs == "" && len(cfg.ResourceMapping) == 0 && len(cfg.ReservedMemory) == 0
 }
 
 func (cfg Config) ToYAMLString() string {
@@ -140,11 +231,19 @@ func (cfg Config) ToYAMLString() string {
 // NUMA Cell -> deviceIDs
 type PerNUMADevices map[int][]string
 
+// the NUMA cell of the devices without NUMA affinity
+const NoNUMAAffinity = -1
+
 // NUMA Cell -> counter
 type PerNUMACounters map[int]int64
 
 type SysInfo struct {
+	OnlineCPUs   cpuset.CPUSet
+	ReservedCPUs cpuset.CPUSet
+	// the allocatable CPUs: the online CPUs but the reserved ones
 	CPUs cpuset.CPUSet
+	// reported for auditing purposes only, the tuned CPUs don't change the allocatable CPUs
+	CPUTuning CPUTuning
 	// resource name -> devices
 	Resources map[string]PerNUMADevices
 	// memory type -> counters
@@ -184,6 +283,7 @@ func FormatSize(v int64) string {
 func (si SysInfo) String() string {
 	b := strings.Builder{}
 	fmt.Fprintf(&b, "cpus: allocatable %q\n", si.CPUs.String())
+	b.WriteString(si.CPUTuning.String())
 	for memoryType, numaMem := range si.Memory {
 		fmt.Fprintf(&b, "%s:\n", memoryType)
 		for numaNode, amount := range numaMem {
@@ -200,10 +300,25 @@ func (si SysInfo) String() string {
 }
 
 func NewSysinfo(conf Config) (SysInfo, error) {
+	return NewSysinfoFromHandle(conf, Handle{})
+}
+
+// NewSysinfoFromHandle computes the system information reading from the root filesystem of the Handle
+func NewSysinfoFromHandle(conf Config, hnd Handle) (SysInfo, error) {
 	var err error
 	var sysinfo SysInfo
 
-	sysinfo.CPUs, err = GetCPUResources(conf.ReservedCPUs, GetOnlineCPUs)
+	sysinfo.ReservedCPUs, err = cpuset.Parse(conf.ReservedCPUs)
+	if err != nil {
+		return sysinfo, err
+	}
+	sysinfo.OnlineCPUs, err = hnd.GetOnlineCPUs()
+	if err != nil {
+		return sysinfo, err
+	}
+	sysinfo.CPUs, err = GetCPUResources(conf.ReservedCPUs, func() (cpuset.CPUSet, error) {
+		return sysinfo.OnlineCPUs, nil
+	})
 	if err != nil {
 		return sysinfo, err
 	}
@@ -211,12 +326,18 @@ func NewSysinfo(conf Config) (SysInfo, error) {
 		return sysinfo, fmt.Errorf("no allocatable cpus")
 	}
 
-	sysinfo.Resources, err = GetPCIResources(conf.ResourceMapping, GetPCIDevices)
+	// informational only, so not fatal
+	sysinfo.CPUTuning, err = GetCPUTuning(hnd.SysfsRoot(), hnd.ProcfsRoot())
+	if err != nil {
+		klog.Warningf("cpus: failed to detect the tuned cpus: %v", err)
+	}
+
+	sysinfo.Resources, err = GetPCIResources(conf.ResourceMapping, hnd.GetPCIDevices)
 	if err != nil {
 		return sysinfo, err
 	}
 
-	sysinfo.Memory, err = GetMemoryResources(conf.ReservedMemory, GetAvailableMemory)
+	sysinfo.Memory, err = GetMemoryResources(conf.ReservedMemory, hnd.GetAvailableMemory)
 	if err != nil {
 		return sysinfo, err
 	}
@@ -239,15 +360,23 @@ func GetCPUResources(resCPUs string, getCPUs func() (cpuset.CPUSet, error)) (cpu
 	return cpus.Difference(reservedCPUs), nil
 }
 
-func GetPCIResources(resourceMap map[string]string, getPCIs func() ([]*pci.Device, error)) (map[string]PerNUMADevices, error) {
+// GetPCIResources maps the PCI devices to the resources using the ResourceMapping.
+// Like the SR-IOV device plugin does, the physical functions with enabled virtual functions are never mapped.
+func GetPCIResources(resourceMap map[string]string, getPCIs func() ([]PCIDevice, error)) (map[string]PerNUMADevices, error) {
 	numaResources := make(map[string]PerNUMADevices)
 	devices, err := getPCIs()
 	if err != nil {
 		return numaResources, err
 	}
 
+	selectors := DeviceSelectorsFromResourceMapping(resourceMap)
 	for _, dev := range devices {
-		resourceName, ok := ResourceNameForDevice(dev, resourceMap)
+		if dev.NumVFs > 0 {
+			klog.V(2).Infof("devs: %s has %d virtual functions, skipped", dev.Address, dev.NumVFs)
+			continue
+		}
+
+		resourceName, ok := ResourceNameForDevice(dev, selectors)
 		if !ok {
 			continue
 		}
@@ -257,7 +386,7 @@ func GetPCIResources(resourceMap map[string]string, getPCIs func() ([]*pci.Devic
 			numaDevs = make(PerNUMADevices)
 		}
 
-		nodeID := -1
+		nodeID := NoNUMAAffinity
 		if dev.Node != nil {
 			nodeID = dev.Node.ID
 		}
@@ -268,8 +397,9 @@ func GetPCIResources(resourceMap map[string]string, getPCIs func() ([]*pci.Devic
 	return numaResources, nil
 }
 
-// TODO: support hugepages reservation
-func GetMemoryResources(reservedMemory map[int]int64, getAvailableMemory func() ([]*topology.Node, []*rtesysinfo.Hugepages, error)) (map[string]PerNUMACounters, error) {
+// GetMemoryResources returns the amount of memory, in bytes, available for each memory type on each NUMA cell,
+// minus the reserved memory.
+func GetMemoryResources(reservedMemory ReservedMemory, getAvailableMemory func() ([]*topology.Node, []*rtesysinfo.Hugepages, error)) (map[string]PerNUMACounters, error) {
 	numaMemory := make(map[string]PerNUMACounters)
 	nodes, hugepages, err := getAvailableMemory()
 	if err != nil {
@@ -280,16 +410,7 @@ func GetMemoryResources(reservedMemory map[int]int64, getAvailableMemory func()
 	for _, node := range nodes {
 		counters[node.ID] += node.Memory.TotalUsableBytes
 	}
-	memCounters := make(PerNUMACounters)
-	for numaID, amount := range counters {
-		reserved := reservedMemory[numaID]
-		if reserved > amount {
-			// TODO log
-			memCounters[numaID] = 0
-		}
-		memCounters[numaID] = amount - reserved
-	}
-	numaMemory[string(corev1.ResourceMemory)] = memCounters
+	numaMemory[string(corev1.ResourceMemory)] = counters
 
 	for _, hp := range hugepages {
 		name := rtesysinfo.HugepageResourceNameFromSize(hp.SizeKB)
@@ -297,28 +418,47 @@ func GetMemoryResources(reservedMemory map[int]int64, getAvailableMemory func()
 		if !ok {
 			hpCounters = make(PerNUMACounters)
 		}
-		hpCounters[hp.NodeID] += int64(hp.Total)
+		hpCounters[hp.NodeID] += int64(hp.Total) * int64(hp.SizeKB) * 1024
 		numaMemory[name] = hpCounters
 	}
 
+	for memoryType, memCounters := range numaMemory {
+		numaMemory[memoryType] = subtractReservedMemory(memoryType, memCounters, reservedMemory[memoryType])
+	}
 	return numaMemory, nil
 }
 
-func ResourceNameForDevice(dev *pci.Device, resourceMap map[string]string) (string, bool) {
-	devID := fmt.Sprintf("%s:%s", dev.Vendor.ID, dev.Product.ID)
-	if resourceName, ok := resourceMap[devID]; ok {
-		klog.Infof("devs: resource for %s is %q", devID, resourceName)
-		return resourceName, true
+func subtractReservedMemory(memoryType string, counters, reserved PerNUMACounters) PerNUMACounters {
+	ret := make(PerNUMACounters)
+	for numaID, amount := range counters {
+		res := reserved[numaID]
+		if res > amount {
+			klog.Warningf("%s: reserved %s exceeds the available %s on numa cell %d, reporting none", memoryType, FormatSize(res), FormatSize(amount), numaID)
+			ret[numaID] = 0
+			continue
+		}
+		ret[numaID] = amount - res
 	}
-	if resourceName, ok := resourceMap[dev.Vendor.ID]; ok {
-		klog.Infof("devs: resource for %s is %q", dev.Vendor.ID, resourceName)
-		return resourceName, true
+	return ret
+}
+
+// ResourceNameForDevice returns the resource of the first selector, in the given order, matching the device
+func ResourceNameForDevice(dev PCIDevice, selectors []DeviceSelector) (string, bool) {
+	for _, ds := range selectors {
+		if ds.Match(dev) {
+			klog.Infof("devs: resource for %s is %q (from %q)", dev.Address, ds.ResourceName, ds.Key)
+			return ds.ResourceName, true
+		}
 	}
 	return "", false
 }
 
 func GetOnlineCPUs() (cpuset.CPUSet, error) {
-	data, err := ioutil.ReadFile(SysDevicesOnlineCPUs)
+	return Handle{}.GetOnlineCPUs()
+}
+
+func (hnd Handle) GetOnlineCPUs() (cpuset.CPUSet, error) {
+	data, err := ioutil.ReadFile(filepath.Join(hnd.SysfsRoot(), SysDevicesOnlineCPUs))
 	if err != nil {
 		return cpuset.CPUSet{}, err
 	}
@@ -326,22 +466,77 @@ func GetOnlineCPUs() (cpuset.CPUSet, error) {
 	return cpuset.Parse(cpus)
 }
 
-func GetPCIDevices() ([]*pci.Device, error) {
-	info, err := pci.New()
+func GetPCIDevices() ([]PCIDevice, error) {
+	return Handle{}.GetPCIDevices()
+}
+
+func (hnd Handle) GetPCIDevices() ([]PCIDevice, error) {
+	info, err := pci.New(hnd.ghwOptions()...)
 	if err != nil {
 		return nil, err
 	}
-	return info.Devices, nil
+	return GetPCIDevicesFromSysfs(hnd.SysfsRoot(), info.Devices)
 }
 
 func GetAvailableMemory() ([]*topology.Node, []*rtesysinfo.Hugepages, error) {
-	hugepages, err := rtesysinfo.GetHugepages(rtesysinfo.Handle{})
+	return Handle{}.GetAvailableMemory()
+}
+
+func (hnd Handle) GetAvailableMemory() ([]*topology.Node, []*rtesysinfo.Hugepages, error) {
+	hugepages, err := GetHugepages(hnd.SysfsRoot())
 	if err != nil {
 		return nil, nil, err
 	}
-	info, err := topology.New()
+	info, err := topology.New(hnd.ghwOptions()...)
 	if err != nil {
 		return nil, nil, err
 	}
 	return info.Nodes, hugepages, nil
 }
+
+// GetHugepages reads the hugepages of each NUMA cell from the sysfs mounted at sysfsRoot.
+// rtesysinfo.GetHugepages can read only the sysfs mounted at /sys under its root.
+func GetHugepages(sysfsRoot string) ([]*rtesysinfo.Hugepages, error) {
+	nodesDir := filepath.Join(sysfsRoot, SysDevicesNodes)
+	entries, err := ioutil.ReadDir(nodesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var hugepages []*rtesysinfo.Hugepages
+	for _, entry := range entries {
+		numaID, ok := parseIndexedName(entry.Name(), "node")
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		hpDir := filepath.Join(nodesDir, entry.Name(), "hugepages")
+		hpEntries, err := ioutil.ReadDir(hpDir)
+		if err != nil {
+			klog.Warningf("cannot find the hugepages on numa cell %d: %v", numaID, err)
+			continue
+		}
+		for _, hpEntry := range hpEntries {
+			var sizeKB int
+			if _, err := fmt.Sscanf(hpEntry.Name(), "hugepages-%dkB", &sizeKB); err != nil {
+				klog.Warningf("malformed hugepages entry %q", hpEntry.Name())
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(hpDir, hpEntry.Name(), "nr_hugepages"))
+			if err != nil {
+				klog.Warningf("cannot read the hugepages from %q: %v", hpEntry.Name(), err)
+				continue
+			}
+			total, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				klog.Warningf("cannot parse the hugepages from %q: %v", hpEntry.Name(), err)
+				continue
+			}
+			hugepages = append(hugepages, &rtesysinfo.Hugepages{
+				NodeID: numaID,
+				SizeKB: sizeKB,
+				Total:  total,
+			})
+		}
+	}
+	return hugepages, nil
+}
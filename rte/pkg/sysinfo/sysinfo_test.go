@@ -18,6 +18,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/ghodss/yaml"
 	"github.com/jaypipes/ghw/pkg/memory"
 	"github.com/jaypipes/ghw/pkg/pci"
 	"github.com/jaypipes/ghw/pkg/topology"
@@ -84,7 +85,7 @@ func TestResourceMappingFromString(t *testing.T) {
 func TestReservedMemoryFromString(t *testing.T) {
 	var testCases = []struct {
 		data     string
-		expected map[int]int64
+		expected ReservedMemory
 	}{
 		{
 			data:     "",
@@ -92,29 +93,60 @@ func TestReservedMemoryFromString(t *testing.T) {
 		},
 		{
 			data: "0=16Mi",
-			expected: map[int]int64{
-				0: 16 * 1024 * 1024,
+			expected: ReservedMemory{
+				"memory": {
+					0: 16 * 1024 * 1024,
+				},
 			},
 		},
 		{
 			data: "0=16Mi,,,",
-			expected: map[int]int64{
-				0: 16 * 1024 * 1024,
+			expected: ReservedMemory{
+				"memory": {
+					0: 16 * 1024 * 1024,
+				},
 			},
 		},
 		{
 			data: "0=16Gi,2=8Gi",
-			expected: map[int]int64{
-				0: 16 * 1024 * 1024 * 1024,
-				2: 8 * 1024 * 1024 * 1024,
+			expected: ReservedMemory{
+				"memory": {
+					0: 16 * 1024 * 1024 * 1024,
+					2: 8 * 1024 * 1024 * 1024,
+				},
 			},
 		},
 		{
 			data: "0=16Gi,1=8192Mi,3=1Gi",
-			expected: map[int]int64{
-				0: 16 * 1024 * 1024 * 1024,
-				1: 8 * 1024 * 1024 * 1024,
-				3: 1024 * 1024 * 1024,
+			expected: ReservedMemory{
+				"memory": {
+					0: 16 * 1024 * 1024 * 1024,
+					1: 8 * 1024 * 1024 * 1024,
+					3: 1024 * 1024 * 1024,
+				},
+			},
+		},
+
+		{
+			data: "0:memory=16Gi,0:hugepages-1Gi=2Gi,1:hugepages-2Mi=512Mi",
+			expected: ReservedMemory{
+				"memory": {
+					0: 16 * 1024 * 1024 * 1024,
+				},
+				"hugepages-1Gi": {
+					0: 2 * 1024 * 1024 * 1024,
+				},
+				"hugepages-2Mi": {
+					1: 512 * 1024 * 1024,
+				},
+			},
+		},
+		{
+			data: "0=1Gi,1:foobar=1Gi,x:hugepages-1Gi=1Gi",
+			expected: ReservedMemory{
+				"memory": {
+					0: 1024 * 1024 * 1024,
+				},
 			},
 		},
 	}
@@ -167,14 +199,14 @@ func TestGetCPUResources(t *testing.T) {
 func TestGetPCIResources(t *testing.T) {
 	var testCases = []struct {
 		name     string
-		devs     []*pci.Device
+		devs     []PCIDevice
 		resMap   map[string]string
 		expected map[string]PerNUMADevices
 	}{
 		{"no devs", nil, map[string]string{"8086:1520": "intel_nics"}, map[string]PerNUMADevices{}},
 		{
 			"devs no numa",
-			[]*pci.Device{
+			[]PCIDevice{
 				fakePCIDevice("8086", "1520", "0000:00:02.0", -1),
 				fakePCIDevice("8086", "1520", "0000:00:02.1", -1),
 			},
@@ -187,7 +219,7 @@ func TestGetPCIResources(t *testing.T) {
 		},
 		{
 			"devs single numa",
-			[]*pci.Device{
+			[]PCIDevice{
 				fakePCIDevice("8086", "1520", "0000:00:02.0", 0),
 				fakePCIDevice("8086", "1520", "0000:00:02.1", 0),
 			},
@@ -200,7 +232,7 @@ func TestGetPCIResources(t *testing.T) {
 		},
 		{
 			"devs multi numa",
-			[]*pci.Device{
+			[]PCIDevice{
 				fakePCIDevice("8086", "1520", "0000:00:02.0", 0),
 				fakePCIDevice("8086", "1520", "0000:00:03.0", 1),
 			},
@@ -212,10 +244,26 @@ func TestGetPCIResources(t *testing.T) {
 				},
 			},
 		},
+		{
+			"physical functions with virtual functions",
+			[]PCIDevice{
+				withNumVFs(fakePCIDevice("8086", "1520", "0000:00:02.0", 0), 2),
+				withPhysFn(fakePCIDevice("8086", "1520", "0000:00:02.1", 0), "0000:00:02.0"),
+				withPhysFn(fakePCIDevice("8086", "1520", "0000:00:02.2", 0), "0000:00:02.0"),
+				fakePCIDevice("8086", "1520", "0000:00:03.0", 1),
+			},
+			map[string]string{"8086:1520": "intel_nics"},
+			map[string]PerNUMADevices{
+				"intel_nics": map[int][]string{
+					0: {"0000:00:02.1", "0000:00:02.2"},
+					1: {"0000:00:03.0"},
+				},
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			got, err := GetPCIResources(testCase.resMap, func() ([]*pci.Device, error) { return testCase.devs, nil })
+			got, err := GetPCIResources(testCase.resMap, func() ([]PCIDevice, error) { return testCase.devs, nil })
 			if err != nil {
 				t.Errorf("unexpected error: %v", err)
 			}
@@ -226,12 +274,108 @@ func TestGetPCIResources(t *testing.T) {
 	}
 }
 
+func TestReservedMemoryUnmarshal(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		data     string
+		expected ReservedMemory
+	}{
+		{
+			name: "legacy",
+			data: "reservedMemory:\n  0: 1073741824\n  1: 2147483648\n",
+			expected: ReservedMemory{
+				"memory": {
+					0: 1024 * 1024 * 1024,
+					1: 2 * 1024 * 1024 * 1024,
+				},
+			},
+		},
+		{
+			name: "by memory type",
+			data: "reservedMemory:\n  memory:\n    0: 1073741824\n  hugepages-1Gi:\n    1: 2147483648\n",
+			expected: ReservedMemory{
+				"memory": {
+					0: 1024 * 1024 * 1024,
+				},
+				"hugepages-1Gi": {
+					1: 2 * 1024 * 1024 * 1024,
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			conf := Config{}
+			if err := yaml.Unmarshal([]byte(testCase.data), &conf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(conf.ReservedMemory, testCase.expected) {
+				t.Errorf("got %v, want %v", conf.ReservedMemory, testCase.expected)
+			}
+
+			data, err := conf.ToYAML()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			roundTrip := Config{}
+			if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(roundTrip.ReservedMemory, testCase.expected) {
+				t.Errorf("round trip got %v, want %v", roundTrip.ReservedMemory, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestReservedMemoryMarshal(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		rmap     ReservedMemory
+		expected string
+	}{
+		{
+			name: "memory only",
+			rmap: ReservedMemory{
+				"memory": {
+					0: 1024 * 1024 * 1024,
+					1: 2 * 1024 * 1024 * 1024,
+				},
+			},
+			expected: "reservedMemory:\n  \"0\": 1073741824\n  \"1\": 2147483648\n",
+		},
+		{
+			name: "with hugepages",
+			rmap: ReservedMemory{
+				"memory": {
+					0: 1024 * 1024 * 1024,
+				},
+				"hugepages-1Gi": {
+					1: 2 * 1024 * 1024 * 1024,
+				},
+			},
+			expected: "reservedMemory:\n  hugepages-1Gi:\n    \"1\": 2147483648\n  memory:\n    \"0\": 1073741824\n",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data, err := Config{ReservedMemory: testCase.rmap}.ToYAML()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != testCase.expected {
+				t.Errorf("got %q, want %q", string(data), testCase.expected)
+			}
+		})
+	}
+}
+
 func TestGetMemoryResources(t *testing.T) {
 	var testCases = []struct {
 		name      string
 		nodes     []*topology.Node
 		hugepages []*rtesysinfo.Hugepages
-		resMem    map[int]int64
+		resMem    ReservedMemory
 		expected  map[string]PerNUMACounters
 	}{
 		{
@@ -326,8 +470,8 @@ func TestGetMemoryResources(t *testing.T) {
 					1: 32 * 1024 * 1024 * 1024,
 				},
 				"hugepages-1Gi": {
-					0: 2,
-					1: 8,
+					0: 2 * 1024 * 1024 * 1024,
+					1: 8 * 1024 * 1024 * 1024,
 				},
 			},
 		},
@@ -376,12 +520,104 @@ func TestGetMemoryResources(t *testing.T) {
 					1: 32 * 1024 * 1024 * 1024,
 				},
 				"hugepages-1Gi": {
-					0: 2,
-					1: 8,
+					0: 2 * 1024 * 1024 * 1024,
+					1: 8 * 1024 * 1024 * 1024,
+				},
+				"hugepages-2Mi": {
+					0: 6 * 2 * 1024 * 1024,
+					1: 16 * 2 * 1024 * 1024,
+				},
+			},
+		},
+		{
+			"reserved memory and hugepages",
+			[]*topology.Node{
+				{
+					ID: 0,
+					Memory: &memory.Area{
+						TotalUsableBytes: 32 * 1024 * 1024 * 1024,
+					},
+				},
+				{
+					ID: 1,
+					Memory: &memory.Area{
+						TotalUsableBytes: 32 * 1024 * 1024 * 1024,
+					},
+				},
+			},
+			[]*rtesysinfo.Hugepages{
+				{
+					NodeID: 0,
+					SizeKB: 1 * 1024 * 1024,
+					Total:  2,
+				},
+				{
+					NodeID: 1,
+					SizeKB: 1 * 1024 * 1024,
+					Total:  8,
+				},
+				{
+					NodeID: 0,
+					SizeKB: 2 * 1024,
+					Total:  6,
+				},
+			},
+			ReservedMemory{
+				"memory": {
+					0: 1024 * 1024 * 1024,
+				},
+				"hugepages-1Gi": {
+					1: 2 * 1024 * 1024 * 1024,
+				},
+				"hugepages-2Mi": {
+					0: 2 * 1024 * 1024,
+				},
+			},
+			map[string]PerNUMACounters{
+				"memory": {
+					0: 31 * 1024 * 1024 * 1024,
+					1: 32 * 1024 * 1024 * 1024,
+				},
+				"hugepages-1Gi": {
+					0: 2 * 1024 * 1024 * 1024,
+					1: 6 * 1024 * 1024 * 1024,
 				},
 				"hugepages-2Mi": {
-					0: 6,
-					1: 16,
+					0: 5 * 2 * 1024 * 1024,
+				},
+			},
+		},
+		{
+			"reservation exceeding the available memory",
+			[]*topology.Node{
+				{
+					ID: 0,
+					Memory: &memory.Area{
+						TotalUsableBytes: 8 * 1024 * 1024 * 1024,
+					},
+				},
+			},
+			[]*rtesysinfo.Hugepages{
+				{
+					NodeID: 0,
+					SizeKB: 1 * 1024 * 1024,
+					Total:  2,
+				},
+			},
+			ReservedMemory{
+				"memory": {
+					0: 16 * 1024 * 1024 * 1024,
+				},
+				"hugepages-1Gi": {
+					0: 4 * 1024 * 1024 * 1024,
+				},
+			},
+			map[string]PerNUMACounters{
+				"memory": {
+					0: 0,
+				},
+				"hugepages-1Gi": {
+					0: 0,
 				},
 			},
 		},
@@ -404,7 +640,7 @@ func TestGetMemoryResources(t *testing.T) {
 func TestResourceNameForDevice(t *testing.T) {
 	var testCases = []struct {
 		name     string
-		dev      *pci.Device
+		dev      PCIDevice
 		resMap   map[string]string
 		expected string
 	}{
@@ -417,7 +653,7 @@ func TestResourceNameForDevice(t *testing.T) {
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			got, _ := ResourceNameForDevice(testCase.dev, testCase.resMap)
+			got, _ := ResourceNameForDevice(testCase.dev, DeviceSelectorsFromResourceMapping(testCase.resMap))
 			if got != testCase.expected {
 				t.Errorf("got %q, want %q", got, testCase.expected)
 			}
@@ -457,18 +693,20 @@ func TestFormatSize(t *testing.T) {
 	}
 }
 
-func namedPCIDevice(vendorID, productID string) *pci.Device {
-	return &pci.Device{
-		Vendor: &pcidb.Vendor{
-			ID: vendorID,
-		},
-		Product: &pcidb.Product{
-			ID: productID,
+func namedPCIDevice(vendorID, productID string) PCIDevice {
+	return PCIDevice{
+		Device: &pci.Device{
+			Vendor: &pcidb.Vendor{
+				ID: vendorID,
+			},
+			Product: &pcidb.Product{
+				ID: productID,
+			},
 		},
 	}
 }
 
-func fakePCIDevice(vendorID, productID, address string, numaNode int) *pci.Device {
+func fakePCIDevice(vendorID, productID, address string, numaNode int) PCIDevice {
 	dev := namedPCIDevice(vendorID, productID)
 	dev.Address = address
 	if numaNode != -1 {
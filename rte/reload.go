@@ -0,0 +1,89 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"reflect"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/config"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
+)
+
+// configReloader applies the changes of the configuration file to the running exporter
+type configReloader struct {
+	args localArgs
+	cli  *podrescompat.ReloadableClient
+	conf config.Config
+	// exit terminates the process, when the changes cannot be applied while running
+	exit func()
+	// onReload, if set, gets the effective configuration once reloaded
+	onReload func(config.Config)
+}
+
+func newConfigReloader(args localArgs, cli *podrescompat.ReloadableClient) (*configReloader, error) {
+	conf, err := config.ReadConfig(args.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return &configReloader{
+		args: args,
+		cli:  cli,
+		conf: conf,
+		exit: func() { os.Exit(0) },
+	}, nil
+}
+
+// Reload reads again the configuration file, and applies the sysinfo config and mode, and the exclude list.
+// The topology manager settings are consumed once at startup, so if they change the process exits,
+// expecting the supervisor to restart it.
+// If the configuration cannot be read, the current one is kept.
+func (rl *configReloader) Reload() error {
+	conf, err := config.ReadConfig(rl.args.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	if conf.TopologyManagerPolicy != rl.conf.TopologyManagerPolicy || conf.TopologyManagerScope != rl.conf.TopologyManagerScope {
+		klog.Infof("configuration file %q changed the topology manager settings: exit", rl.args.ConfigPath)
+		rl.exit()
+		return nil
+	}
+
+	if reflect.DeepEqual(conf, rl.conf) {
+		klog.V(2).Infof("configuration file %q unchanged", rl.args.ConfigPath)
+		return nil
+	}
+
+	mode, err := podrescompat.ModeFromString(conf.ResourcesMode)
+	if err != nil {
+		return err
+	}
+	sysConf := overrideSysConf(conf.Resources, rl.args.SysConfOverrides)
+	rl.cli.Update(sysConf, mode, conf.ExcludeList)
+	rl.conf = conf
+	if rl.onReload != nil {
+		effective := conf
+		effective.Resources = sysConf
+		// the topology manager settings cannot change while running
+		effective.TopologyManagerPolicy = rl.args.Config.TopologyManagerPolicy
+		effective.TopologyManagerScope = rl.args.Config.TopologyManagerScope
+		rl.onReload(effective)
+	}
+	klog.Infof("configuration file %q reloaded", rl.args.ConfigPath)
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+type fakeLister struct{}
+
+func (fl fakeLister) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return &podresourcesapi.ListPodResourcesResponse{}, nil
+}
+
+func (fl fakeLister) GetAllocatableResources(ctx context.Context, in *podresourcesapi.AllocatableResourcesRequest, opts ...grpc.CallOption) (*podresourcesapi.AllocatableResourcesResponse, error) {
+	return &podresourcesapi.AllocatableResourcesResponse{
+		CpuIds: []int64{0, 1},
+		Devices: []*podresourcesapi.ContainerDevices{
+			{
+				ResourceName: "intel_nics",
+				DeviceIds:    []string{"0000:00:02.0"},
+			},
+		},
+	}, nil
+}
+
+func TestConfigReloader(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("cannot write the configuration: %v", err)
+		}
+	}
+	countDevices := func(cli *podrescompat.ReloadableClient) int {
+		resp, err := cli.GetAllocatableResources(context.TODO(), &podresourcesapi.AllocatableResourcesRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return len(resp.GetDevices())
+	}
+
+	writeConfig("topologyManagerPolicy: single-numa-node\n")
+	args := localArgs{
+		ConfigPath: configPath,
+	}
+	cli := podrescompat.NewReloadableClientFromLister(fakeLister{}, "node-0", sysinfo.Handle{}, sysinfo.Config{}, podrescompat.ModeFallback, nil)
+	rl, err := newConfigReloader(args, cli)
+	if err != nil {
+		t.Fatalf("cannot create the reloader: %v", err)
+	}
+	exited := false
+	rl.exit = func() { exited = true }
+
+	writeConfig("topologyManagerPolicy: single-numa-node\nexcludeList:\n  node-0: [intel_nics]\n")
+	if err := rl.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if exited {
+		t.Fatalf("unexpected exit reloading the exclude list")
+	}
+	if got := countDevices(cli); got != 0 {
+		t.Errorf("expected the devices to be excluded, got %d", got)
+	}
+
+	writeConfig("this: [is: not, yaml")
+	if err := rl.Reload(); err == nil {
+		t.Errorf("expected failure reloading a broken configuration")
+	}
+	if got := countDevices(cli); got != 0 {
+		t.Errorf("expected the previous configuration to be kept, got %d devices", got)
+	}
+
+	writeConfig("topologyManagerPolicy: best-effort\n")
+	if err := rl.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !exited {
+		t.Errorf("expected exit on topology manager policy change")
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/podrescompat"
+	"github.com/openshift-kni/numaresources-operator/rte/pkg/sysinfo"
+)
+
+// replaySysinfo computes the system information from the snapshot, and writes to w as JSON
+// the allocatable resources the exporter would report on the host the snapshot was captured on.
+func replaySysinfo(w io.Writer, snapshotPath string, conf sysinfo.Config) error {
+	hnd, cleanup, err := sysinfo.ReplaySnapshot(snapshotPath)
+	if err != nil {
+		return fmt.Errorf("cannot unpack the snapshot %q: %w", snapshotPath, err)
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			klog.Warningf("cannot remove the unpacked snapshot %q: %v", hnd.Root, err)
+		}
+	}()
+
+	sysInfo, err := sysinfo.NewSysinfoFromHandle(conf, hnd)
+	if err != nil {
+		return fmt.Errorf("failed to query system info from the snapshot %q: %w", snapshotPath, err)
+	}
+	klog.Infof("\n%s", sysInfo)
+
+	resp := podrescompat.MakeAllocatableResourcesResponseFromSysInfo(sysInfo)
+	data, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}